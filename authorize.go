@@ -0,0 +1,50 @@
+package cooperate
+
+import (
+	"errors"
+	"fmt"
+)
+
+// ErrUnauthorized indicates that a session may not edit a document at all.
+var ErrUnauthorized = errors.New("unauthorized")
+
+type (
+	// An AuthorizerOf decides whether a session may commit an operation to a
+	// document. The operation has been transformed against history, so it
+	// applies to the server's current copy of the document, and ranges of
+	// the document can be checked against it directly.
+	AuthorizerOf[A any] interface {
+		// Authorize returns nil if session may commit op to document, or
+		// otherwise the reason it may not, such as ErrUnauthorized.
+		Authorize(session, document string, op OperationOf[A]) error
+	}
+
+	// AuthorizerFuncOf is an AuthorizerOf implemented by a function.
+	AuthorizerFuncOf[A any] func(session, document string, op OperationOf[A]) error
+
+	// Authorizer is an AuthorizerOf untyped actions.
+	Authorizer = AuthorizerOf[Action]
+
+	// AuthorizerFunc is an AuthorizerFuncOf untyped actions.
+	AuthorizerFunc = AuthorizerFuncOf[Action]
+)
+
+func (f AuthorizerFuncOf[A]) Authorize(session, document string, op OperationOf[A]) error {
+	return f(session, document, op)
+}
+
+// An AuthorizationError is returned by a server whose Authorizer rejects an
+// operation. Err is the reason given by the Authorizer.
+type AuthorizationError struct {
+	Session  string
+	Document string
+	Err      error
+}
+
+func (e *AuthorizationError) Error() string {
+	return fmt.Sprintf("session %q may not edit document %q: %s", e.Session, e.Document, e.Err)
+}
+
+func (e *AuthorizationError) Unwrap() error {
+	return e.Err
+}
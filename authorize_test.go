@@ -0,0 +1,57 @@
+package cooperate_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestServer_Authorizer(t *testing.T) {
+
+	var authorized []string
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.DedupMemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		ID:                 "notes",
+		Authorizer: cooperate.AuthorizerFunc(func(session, document string, op cooperate.Operation) error {
+			authorized = append(authorized, session+"@"+document)
+			if session == "guest" {
+				return cooperate.ErrUnauthorized
+			}
+			return nil
+		}),
+	}
+
+	if err := s.ApplyAs("ada", 0, cooperate.Operation{text.InsertAction("foo")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	err := s.ApplyAs("guest", 1, cooperate.Operation{text.RetainAction(3), text.InsertAction("bar")})
+	var authErr *cooperate.AuthorizationError
+	if !errors.As(err, &authErr) || authErr.Session != "guest" || authErr.Document != "notes" || !errors.Is(err, cooperate.ErrUnauthorized) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	sub := cooperate.Submission{Session: "guest", ClientID: "phone", Seq: 1, Root: 1, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("bar")}}
+	if _, err := s.Submit(sub); !errors.Is(err, cooperate.ErrUnauthorized) {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	// rejected operations are neither applied nor committed
+	if doc := s.Document.(*text.TextDocument); doc.String() != "foo" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+	if seqno := s.History.SequenceNumber(); seqno != 1 {
+		t.Errorf("unexpected sequence number: expected 1 but got %d", seqno)
+	}
+
+	if expected := []string{"ada@notes", "guest@notes", "guest@notes"}; len(authorized) != len(expected) {
+		t.Errorf("unexpected authorizations: expected %v but got %v", expected, authorized)
+	}
+
+}
@@ -0,0 +1,139 @@
+// Package bytes implements collaborative editing of binary data. It mirrors
+// package text, but operates on []byte without assuming any encoding: every
+// length is measured in bytes.
+package bytes
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/runlength"
+)
+
+type (
+	// A BytesHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the binary operations: retain, insert and
+	// delete.
+	BytesHandler struct {
+		// Priority decides the order of concurrent insertions at the same
+		// location.
+		Priority cooperate.Priority
+	}
+
+	// RetainAction moves the cursor forward the given number of bytes.
+	RetainAction int
+
+	// InsertAction inserts the given bytes at the current location.
+	InsertAction []byte
+
+	// DeleteAction asserts that the given bytes immediately follow the
+	// cursor, and then removes them.
+	DeleteAction []byte
+)
+
+func (a RetainAction) GoString() string { return fmt.Sprintf("R(%d)", a) }
+func (a InsertAction) GoString() string { return fmt.Sprintf("I(%x)", []byte(a)) }
+func (a DeleteAction) GoString() string { return fmt.Sprintf("D(%x)", []byte(a)) }
+
+// Expand inflates a such that it affects only one byte.
+func (bh BytesHandler) Expand(a cooperate.Action) []cooperate.Action {
+	var actions []cooperate.Action
+	switch a := a.(type) {
+	case RetainAction:
+		for i := 0; i < int(a); i++ {
+			actions = append(actions, RetainAction(1))
+		}
+	case InsertAction:
+		for i := range a {
+			actions = append(actions, a[i:i+1:i+1])
+		}
+	case DeleteAction:
+		for i := range a {
+			actions = append(actions, a[i:i+1:i+1])
+		}
+	}
+	return actions
+}
+
+// Reduce combines two actions of identical type into a single action with the
+// same effect. Neither a nor b is modified.
+func (bh BytesHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	switch a := a.(type) {
+	case RetainAction:
+		if b, ok := b.(RetainAction); ok {
+			return a + b, true
+		}
+	case InsertAction:
+		if b, ok := b.(InsertAction); ok {
+			return InsertAction(concat(a, b)), true
+		}
+	case DeleteAction:
+		if b, ok := b.(DeleteAction); ok {
+			return DeleteAction(concat(a, b)), true
+		}
+	}
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (bh BytesHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+	return runlength.Compose[cooperate.Action](bh, a, b)
+}
+
+// Transform implements cooperate.Transformer for the binary operations
+// defined in this package.
+//
+// This implementation favors b; that is, if a and b both act on the
+// same byte, the effect is as though b's intended change was applied first.
+// The order of concurrent insertions at the same location is instead decided
+// by bh.Priority.
+func (bh BytesHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+	return runlength.Transform[cooperate.Action](bh, a, b, bh.Priority == cooperate.FavorA)
+}
+
+// Kind implements runlength.Alphabet.
+func (bh BytesHandler) Kind(a cooperate.Action) runlength.Kind {
+	switch a.(type) {
+	case RetainAction:
+		return runlength.Retain
+	case InsertAction:
+		return runlength.Insert
+	case DeleteAction:
+		return runlength.Delete
+	}
+	return runlength.Unknown
+}
+
+// Retain implements runlength.Alphabet.
+func (bh BytesHandler) Retain(n int) cooperate.Action {
+	return RetainAction(n)
+}
+
+// Matches implements runlength.Alphabet.
+func (bh BytesHandler) Matches(insert, del cooperate.Action) bool {
+	return string(insert.(InsertAction)) == string(del.(DeleteAction))
+}
+
+// Lengths calculates the lengths of the document op expects to be applied to
+// and the length of that document after applying op, in bytes.
+func Lengths(op cooperate.Operation) (pre, post int) {
+	for _, a := range []cooperate.Action(op) {
+		switch a := a.(type) {
+		case RetainAction:
+			pre += int(a)
+			post += int(a)
+		case InsertAction:
+			post += len(a)
+		case DeleteAction:
+			pre += len(a)
+		}
+	}
+	return
+}
+
+// concat returns a new slice holding a followed by b.
+func concat(a, b []byte) []byte {
+	c := make([]byte, 0, len(a)+len(b))
+	return append(append(c, a...), b...)
+}
@@ -0,0 +1,155 @@
+package bytes
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestReduce(t *testing.T) {
+
+	var bh BytesHandler
+
+	a := InsertAction{0x01, 0x02}
+	if action, ok := bh.Reduce(a[:1], InsertAction{0x03}); !ok {
+		t.Errorf("expected inserts to be mergeable")
+	} else if !reflect.DeepEqual(action, InsertAction{0x01, 0x03}) {
+		t.Errorf("unexpected reduction: got '%#v'", action)
+	}
+
+	// reducing must not overwrite the backing array of its arguments
+	if !reflect.DeepEqual(a, InsertAction{0x01, 0x02}) {
+		t.Errorf("reduce modified its argument: got '%#v'", a)
+	}
+
+	if _, ok := bh.Reduce(InsertAction{0x01}, DeleteAction{0x01}); ok {
+		t.Errorf("expected insert and delete not to be mergeable")
+	}
+
+}
+
+func TestCompose(t *testing.T) {
+
+	var bh BytesHandler
+
+	a := cooperate.Expand(bh, cooperate.Operation{RetainAction(1), InsertAction{0xaa, 0xbb}, RetainAction(1)})
+	b := cooperate.Expand(bh, cooperate.Operation{RetainAction(2), DeleteAction{0xbb}, DeleteAction{0xff}})
+
+	expected := cooperate.Operation{RetainAction(1), InsertAction{0xaa}, DeleteAction{0xff}}
+
+	if sum, err := bh.Compose(cooperate.NewOperationIterator(a), cooperate.NewOperationIterator(b)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(sum, expected) {
+		t.Errorf("unexpected composition: expected '%#v' but got '%#v'", expected, sum)
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	initial := []byte{0x00, 0xff, 0x80}
+
+	cases := []struct {
+		Priority       cooperate.Priority
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       []byte
+	}{
+		{
+			A:        cooperate.Operation{RetainAction(1), InsertAction{0xc0}, RetainAction(2)},
+			B:        cooperate.Operation{RetainAction(1), InsertAction{0xc1}, RetainAction(2)},
+			APrime:   cooperate.Operation{RetainAction(2), InsertAction{0xc0}, RetainAction(2)},
+			BPrime:   cooperate.Operation{RetainAction(1), InsertAction{0xc1}, RetainAction(3)},
+			Expected: []byte{0x00, 0xc1, 0xc0, 0xff, 0x80},
+		},
+		{
+			Priority: cooperate.FavorA,
+			A:        cooperate.Operation{RetainAction(1), InsertAction{0xc0}, RetainAction(2)},
+			B:        cooperate.Operation{RetainAction(1), InsertAction{0xc1}, RetainAction(2)},
+			APrime:   cooperate.Operation{RetainAction(1), InsertAction{0xc0}, RetainAction(3)},
+			BPrime:   cooperate.Operation{RetainAction(2), InsertAction{0xc1}, RetainAction(2)},
+			Expected: []byte{0x00, 0xc0, 0xc1, 0xff, 0x80},
+		},
+		{
+			A:        cooperate.Operation{RetainAction(1), DeleteAction{0xff}, RetainAction(1)},
+			B:        cooperate.Operation{RetainAction(1), DeleteAction{0xff, 0x80}},
+			APrime:   cooperate.Operation{RetainAction(1)},
+			BPrime:   cooperate.Operation{RetainAction(1), DeleteAction{0x80}},
+			Expected: []byte{0x00},
+		},
+	}
+
+	for i, c := range cases {
+
+		bh := BytesHandler{Priority: c.Priority}
+		aPrime, bPrime, err := bh.Transform(
+			cooperate.NewOperationIterator(cooperate.Expand(bh, c.A)),
+			cooperate.NewOperationIterator(cooperate.Expand(bh, c.B)))
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewBytesDocument(initial), NewBytesDocument(initial)
+		for _, op := range []cooperate.Operation{c.A, bPrime} {
+			if err := ab.Apply(op); err != nil {
+				t.Errorf("[case %d] unexpected error applying %#v: %s", i, op, err)
+			}
+		}
+		for _, op := range []cooperate.Operation{c.B, aPrime} {
+			if err := ba.Apply(op); err != nil {
+				t.Errorf("[case %d] unexpected error applying %#v: %s", i, op, err)
+			}
+		}
+		if !reflect.DeepEqual(ab.Bytes(), c.Expected) || !reflect.DeepEqual(ba.Bytes(), c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%x' but got '%x' and '%x'", i, c.Expected, ab.Bytes(), ba.Bytes())
+		}
+	}
+
+}
+
+var bytesConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return NewBytesDocument([]byte{0x00, 0xff, 0x80, 0x7f}[:r.Intn(5)])
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return BytesHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		b := doc.(*BytesDocument).Bytes()
+		var op cooperate.Operation
+		for cur := 0; cur < len(b); {
+			n := 1 + r.Intn(len(b)-cur)
+			switch r.Intn(3) {
+			case 0:
+				op = append(op, RetainAction(n))
+			case 1:
+				op = append(op, DeleteAction(append([]byte(nil), b[cur:cur+n]...)))
+			case 2:
+				op = append(op, InsertAction{byte(r.Intn(256))}, RetainAction(n))
+			}
+			cur += n
+		}
+		if len(op) == 0 || r.Intn(3) == 0 {
+			op = append(op, InsertAction{byte(r.Intn(256))})
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		return string(a.(*BytesDocument).Bytes()) == string(b.(*BytesDocument).Bytes())
+	},
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, bytesConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, bytesConfig, 200)
+}
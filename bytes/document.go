@@ -0,0 +1,67 @@
+package bytes
+
+import (
+	"github.com/tylerchr/cooperate"
+)
+
+// A BytesDocument is a byte slice that implements the cooperate.Document
+// interface.
+type BytesDocument struct {
+	contents []byte
+}
+
+// NewBytesDocument initializes a BytesDocument with a copy of initial.
+func NewBytesDocument(initial []byte) *BytesDocument {
+	return &BytesDocument{
+		contents: append([]byte(nil), initial...),
+	}
+}
+
+// Bytes returns the current contents of the document. The returned slice
+// must not be modified.
+func (bd *BytesDocument) Bytes() []byte {
+	return bd.contents
+}
+
+// Len returns the length of the document in bytes.
+func (bd *BytesDocument) Len() int {
+	return len(bd.contents)
+}
+
+// Apply performs op against the BytesDocument. If op does not apply cleanly,
+// the document is left unchanged.
+func (bd *BytesDocument) Apply(op cooperate.Operation) error {
+
+	// verify that operation will apply cleanly to document
+	pre, post := Lengths(op)
+	if len(bd.contents) != pre {
+		return cooperate.ErrDocumentSizeMismatch
+	}
+
+	contents := make([]byte, 0, post)
+	var cursor int
+
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			contents = append(contents, bd.contents[cursor:cursor+int(a)]...)
+			cursor += int(a)
+
+		case InsertAction:
+			contents = append(contents, a...)
+
+		case DeleteAction:
+			if string(bd.contents[cursor:cursor+len(a)]) != string(a) {
+				return cooperate.ErrDeleteMismatch
+			}
+			cursor += len(a)
+
+		default:
+			return cooperate.ErrUnknownAction
+		}
+	}
+
+	bd.contents = contents
+	return nil
+
+}
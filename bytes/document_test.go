@@ -0,0 +1,73 @@
+package bytes
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestBytesDocument(t *testing.T) {
+
+	cases := []struct {
+		ExistingContents []byte
+		Operation        cooperate.Operation
+		ExpectedError    error
+		ExpectedContents []byte
+	}{
+		{
+			ExistingContents: nil,
+			Operation: cooperate.Operation([]cooperate.Action{
+				InsertAction{0xff, 0x00},
+			}),
+			ExpectedContents: []byte{0xff, 0x00},
+		},
+		{
+			ExistingContents: []byte{0xc3, 0x28},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				InsertAction{0x80},
+				RetainAction(1),
+			}),
+			ExpectedContents: []byte{0xc3, 0x80, 0x28},
+		},
+		{
+			ExistingContents: []byte{0x01, 0xfe, 0x02},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				DeleteAction{0xfe},
+				RetainAction(1),
+			}),
+			ExpectedContents: []byte{0x01, 0x02},
+		},
+		{
+			ExistingContents: []byte{0x01, 0xfe},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				DeleteAction{0xff},
+			}),
+			ExpectedError:    cooperate.ErrDeleteMismatch,
+			ExpectedContents: []byte{0x01, 0xfe},
+		},
+		{
+			ExistingContents: []byte{0x01},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(2),
+			}),
+			ExpectedError:    cooperate.ErrDocumentSizeMismatch,
+			ExpectedContents: []byte{0x01},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewBytesDocument(c.ExistingContents)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Bytes(), c.ExpectedContents) {
+			t.Errorf("[case %d] unexpected contents: expected '%x' but got '%x'", i, c.ExpectedContents, doc.Bytes())
+		}
+	}
+
+}
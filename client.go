@@ -1,90 +1,229 @@
 package cooperate
 
-import "fmt"
-
-// A Client serves as the editing entity in the OT paradigm. It maintains
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNothingInFlight indicates that a client was acknowledged while it
+	// had no operation in-flight.
+	ErrNothingInFlight = errors.New("no operation in flight")
+
+	// ErrRevisionMismatch indicates that an operation received by a client
+	// does not follow the revision its document is at.
+	ErrRevisionMismatch = errors.New("revision mismatch")
+)
+
+// A ClientOf serves as the editing entity in the OT paradigm. It maintains
 // its own independent state and proposes updates to some OT server.
-type Client struct {
-	Document Document
+type ClientOf[A any] struct {
+	Document DocumentOf[A]
+
+	// Revision is the revision of the server's document that Document
+	// reflects, apart from the effects of InFlight and Buffer.
+	Revision int
+
+	// Seq numbers the InFlight operation among the client's submissions,
+	// for use as the Seq of a SubmissionOf. It increases each time an
+	// operation is put in flight.
+	Seq int
+
+	// InFlight is the operation proposed to the server and not yet
+	// acknowledged, or nil if there is none. It is empty rather than nil if
+	// concurrent operations left nothing of it, as when two clients delete
+	// the same text, since the server still acknowledges it.
+	InFlight OperationOf[A]
 
-	InFlight Operation
-	Buffer   Operation
+	// Buffer composes the local operations made while InFlight awaits its
+	// acknowledgement.
+	Buffer OperationOf[A]
 
 	// these implement the core OT operations
-	ExpandReducer
-	ComposeTransformer
+	ExpandReducer      ExpandReducerOf[A]
+	ComposeTransformer ComposeTransformerOf[A]
+
+	// Store, if set, persists the client's state after every change, using
+	// Snapshotter to encode Document.
+	Store       ClientStoreOf[A]
+	Snapshotter SnapshotterOf[A]
+
+	// snapshot is the Snapshot last saved, and changes are the operations
+	// applied to Document since.
+	snapshot []byte
+	changes  []OperationOf[A]
+}
+
+// A Client is a ClientOf untyped actions.
+type Client = ClientOf[Action]
+
+// Expand, Reduce, Compose and Transform call the client's ExpandReducer and
+// ComposeTransformer. Before operations were generic these were embedded, and
+// the methods remain so that a ClientOf still implements ExpandReducerOf and
+// ComposeTransformerOf.
+func (c *ClientOf[A]) Expand(a A) []A {
+	return c.ExpandReducer.Expand(a)
+}
+
+func (c *ClientOf[A]) Reduce(a, b A) (A, bool) {
+	return c.ExpandReducer.Reduce(a, b)
+}
+
+func (c *ClientOf[A]) Compose(a, b *OperationIteratorOf[A]) (OperationOf[A], error) {
+	return c.ComposeTransformer.Compose(a, b)
+}
+
+func (c *ClientOf[A]) Transform(a, b *OperationIteratorOf[A]) (aa, bb OperationOf[A], err error) {
+	return c.ComposeTransformer.Transform(a, b)
 }
 
 // ApplyLocal applies an operation that this client produced. If no pending
 // operations exist, this operation is immediately proposed; otherwise it is
 // composed into the buffer and held for future proposal.
-func (c *Client) ApplyLocal(op Operation) error {
+//
+// If ApplyLocal returns a *SaveError, op has been applied all the same.
+// Any other error leaves the client unchanged.
+func (c *ClientOf[A]) ApplyLocal(op OperationOf[A]) error {
 
-	// apply the transformation to the document
-	if err := c.Document.Apply(op); err != nil {
+	if err := c.checkStore(); err != nil {
 		return err
 	}
 
+	inFlight, buffer, seq := c.InFlight, c.Buffer, c.Seq
+
 	switch {
-	case c.InFlight == nil:
-		c.InFlight = op
-		fmt.Printf("[sent op] %#v\n", c.InFlight)
+	case inFlight == nil:
+		inFlight = op
+		seq++
+		fmt.Printf("[sent op] %#v\n", inFlight)
 
-	case c.InFlight != nil && c.Buffer == nil:
-		c.Buffer = op
-		fmt.Printf("[set buffer] %#v\n", c.Buffer)
+	case inFlight != nil && buffer == nil:
+		buffer = op
+		fmt.Printf("[set buffer] %#v\n", buffer)
 
-	case c.InFlight != nil && c.Buffer != nil:
-		composedOp, err := c.ComposeTransformer.Compose(NewOperationIterator(Expand(c.ExpandReducer, c.Buffer)), NewOperationIterator(Expand(c.ExpandReducer, op)))
+	case inFlight != nil && buffer != nil:
+		composedOp, err := c.ComposeTransformer.Compose(NewOperationIterator(Expand(c.ExpandReducer, buffer)), NewOperationIterator(Expand(c.ExpandReducer, op)))
 		if err != nil {
 			return err
 		}
-		c.Buffer = Reduce(c.ExpandReducer, composedOp)
-		fmt.Printf("[composed into buffer] %#v\n", c.Buffer)
+		buffer = Reduce(c.ExpandReducer, composedOp)
+		fmt.Printf("[composed into buffer] %#v\n", buffer)
+	}
+
+	// apply the transformation to the document
+	if err := c.Document.Apply(op); err != nil {
+		return err
 	}
+	c.InFlight, c.Buffer, c.Seq = inFlight, buffer, seq
 
-	return nil
+	return c.save(op)
 
 }
 
 // ApplyReceived transforms an Operation received from the server for local
 // application and adapts InFlight and Buffer accordingly.
-func (c *Client) ApplyReceived(op Operation) error {
+//
+// If ApplyReceived returns a *SaveError, op has been applied all the same.
+// Any other error leaves the client unchanged.
+func (c *ClientOf[A]) ApplyReceived(op OperationOf[A]) error {
 
-	// TODO(tylerchr): We are currently assuming that an inflight and buffer exist. What to do if one/both don't?
+	if err := c.checkStore(); err != nil {
+		return err
+	}
+
+	inFlight, buffer := c.InFlight, c.Buffer
 
 	// transform op against inflight --> this is our new inflight + temp state
 	// transform temp state against buffer --> this is our new buffer
 
-	if_aa, if_bb, err := c.Transform(NewOperationIterator(Expand(c.ExpandReducer, c.InFlight)), NewOperationIterator(Expand(c.ExpandReducer, op)))
-	// fmt.Printf(">> %#v %#v %s\n", if_aa, if_bb, err)
-	if err != nil {
-		return err
+	// an empty InFlight operation is still in flight, but has no effect for
+	// op to be transformed against
+	if len(inFlight) > 0 {
+		if_aa, if_bb, err := c.ComposeTransformer.Transform(NewOperationIterator(Expand(c.ExpandReducer, inFlight)), NewOperationIterator(Expand(c.ExpandReducer, op)))
+		if err != nil {
+			return err
+		}
+
+		// a' is our new InFlight operation, which remains in flight even if
+		// nothing is left of it
+		inFlight = if_aa
+		if inFlight == nil {
+			inFlight = OperationOf[A]{}
+		}
+		fmt.Printf("[inflight] %#v\n", inFlight)
+
+		// b' is now useful for transforming the buffer
+		op = if_bb
+	}
+
+	if buffer != nil {
+		buf_aa, buf_bb, err := c.ComposeTransformer.Transform(NewOperationIterator(Expand(c.ExpandReducer, buffer)), NewOperationIterator(Expand(c.ExpandReducer, op)))
+		if err != nil {
+			return err
+		}
+
+		// buf_aa is our new Buffer operation
+		buffer = buf_aa
+		fmt.Printf("[buffer] %#v\n", buffer)
+
+		// buf_bb is the operation we should apply to our document
+		op = buf_bb
 	}
 
-	// a' is our new InFlight operation
-	c.InFlight = if_aa
-	fmt.Printf("[inflight] %#v\n", c.InFlight)
+	fmt.Printf("[apply] %#v\n", op)
 
-	// b' is now useful for transforming the buffer
-	buf_aa, buf_bb, err := c.Transform(NewOperationIterator(Expand(c.ExpandReducer, c.Buffer)), NewOperationIterator(Expand(c.ExpandReducer, if_bb)))
-	if err != nil {
+	if err := c.Document.Apply(op); err != nil {
 		return err
 	}
+	c.InFlight, c.Buffer = inFlight, buffer
+	c.Revision++
 
-	// buf_aa is our new Buffer operation
-	c.Buffer = buf_aa
-	fmt.Printf("[buffer] %#v\n", c.Buffer)
+	fmt.Printf("[document] %#v\n", c.Document)
+
+	return c.save(op)
 
-	// buf_bb is the operation we should apply to our document
-	fmt.Printf("[apply] %#v\n", buf_bb)
+}
 
-	if err := c.Document.Apply(buf_bb); err != nil {
+// Acknowledge records that the server has committed the InFlight operation,
+// and moves the buffer in-flight. The new InFlight operation, if any, should
+// then be proposed to the server, rooted at Revision.
+//
+// If Acknowledge returns a *SaveError, the acknowledgement has been recorded
+// all the same. Any other error leaves the client unchanged.
+func (c *ClientOf[A]) Acknowledge() error {
+
+	if c.InFlight == nil {
+		return ErrNothingInFlight
+	}
+	if err := c.checkStore(); err != nil {
 		return err
 	}
 
-	fmt.Printf("[document] %#v\n", c.Document)
+	c.InFlight, c.Buffer = c.Buffer, nil
+	c.Revision++
+
+	if c.InFlight != nil {
+		c.Seq++
+	}
+
+	return c.save(nil)
+
+}
+
+// Catchup applies an operation streamed by ServerOf.Resync, which produced
+// revision. If own is set, the operation is the client's InFlight operation,
+// and is acknowledged rather than applied. Operations must be caught up in
+// order, starting from the one following Revision.
+func (c *ClientOf[A]) Catchup(revision int, op OperationOf[A], own bool) error {
+
+	if revision != c.Revision+1 {
+		return ErrRevisionMismatch
+	}
+
+	if own {
+		return c.Acknowledge()
+	}
 
-	return nil
+	return c.ApplyReceived(op)
 
 }
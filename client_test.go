@@ -1,10 +1,12 @@
 package cooperate_test
 
 import (
+	"math/rand"
 	"reflect"
 	"testing"
 
 	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
 	"github.com/tylerchr/cooperate/text"
 )
 
@@ -164,3 +166,251 @@ func TestClient_ApplyReceived(t *testing.T) {
 	}
 
 }
+
+func TestClient_Priority(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument("xy"),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument("xy"),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{Priority: text.FavorA},
+	}
+
+	// the client inserts at the same place as an operation that the server
+	// commits before the client's arrives
+	mine := cooperate.Operation{text.RetainAction(1), text.InsertAction("c"), text.RetainAction(1)}
+	theirs := cooperate.Operation{text.RetainAction(1), text.InsertAction("s"), text.RetainAction(1)}
+
+	if err := client.ApplyLocal(mine); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := s.Apply(0, theirs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Apply(0, mine); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.ApplyReceived(theirs); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	server, local := s.Document.(*text.TextDocument).String(), client.Document.(*text.TextDocument).String()
+	if server != "xcsy" || local != server {
+		t.Errorf("documents diverged: server has %q and client has %q", server, local)
+	}
+
+}
+
+func TestClient_ApplyReceivedIdle(t *testing.T) {
+
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument("red"),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	if err := client.ApplyReceived(cooperate.Operation{text.InsertAction("green"), text.RetainAction(3)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if doc := client.Document.(*text.TextDocument).String(); doc != "greenred" {
+		t.Errorf("unexpected document: expected 'greenred' but got '%s'", doc)
+	}
+
+	if client.Revision != 1 {
+		t.Errorf("unexpected revision: expected 1 but got %d", client.Revision)
+	}
+
+}
+
+func TestClient_Resync(t *testing.T) {
+
+	cases := []struct {
+		// Committed is whether the client's in-flight operation reached the
+		// server before the client disconnected
+		Committed bool
+	}{
+		{Committed: true},
+		{Committed: false},
+	}
+
+	for i, c := range cases {
+
+		s := &cooperate.Server{
+			Document:           text.NewTextDocument("ab"),
+			History:            &cooperate.DedupMemoryHistory{},
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+		}
+
+		client := &cooperate.Client{
+			Document:           text.NewTextDocument("ab"),
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+		}
+
+		inFlight := cooperate.Submission{ClientID: "alice", Seq: 1, Root: 0, Operation: cooperate.Operation{text.RetainAction(2), text.InsertAction("c")}}
+		client.ApplyLocal(inFlight.Operation)
+		client.ApplyLocal(cooperate.Operation{text.RetainAction(3), text.InsertAction("d")})
+
+		// while the client is disconnected, another client edits the
+		// document, perhaps after the in-flight operation is committed
+		if c.Committed {
+			if _, err := s.Submit(inFlight); err != nil {
+				t.Fatalf("[case %d] unexpected error: %s", i, err)
+			}
+		}
+		if _, err := s.Submit(cooperate.Submission{ClientID: "bob", Seq: 1, Root: 0, Operation: cooperate.Operation{text.InsertAction("x"), text.RetainAction(2)}}); err != nil {
+			t.Fatalf("[case %d] unexpected error: %s", i, err)
+		}
+
+		// on reconnecting, the client catches up and then proposes whatever
+		// remains in flight
+		if err := s.Resync(client.Revision, "alice", client.Seq, client.Catchup); err != nil {
+			t.Fatalf("[case %d] unexpected error: %s", i, err)
+		}
+
+		for client.InFlight != nil {
+			if _, err := s.Submit(cooperate.Submission{ClientID: "alice", Seq: client.Seq, Root: client.Revision, Operation: client.InFlight}); err != nil {
+				t.Fatalf("[case %d] unexpected error: %s", i, err)
+			}
+			if err := client.Acknowledge(); err != nil {
+				t.Fatalf("[case %d] unexpected error: %s", i, err)
+			}
+		}
+
+		server, local := s.Document.(*text.TextDocument).String(), client.Document.(*text.TextDocument).String()
+		if server != "xabcd" || local != server {
+			t.Errorf("[case %d] documents diverged: server has %q and client has %q", i, server, local)
+		}
+
+		if seqno := s.History.SequenceNumber(); client.Revision != seqno {
+			t.Errorf("[case %d] unexpected revision: expected %d but got %d", i, seqno, client.Revision)
+		}
+	}
+
+}
+
+func TestClient_Catchup(t *testing.T) {
+
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument(""),
+		Revision:           2,
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	if err := client.Catchup(4, cooperate.Operation{text.InsertAction("a")}, false); err != cooperate.ErrRevisionMismatch {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", cooperate.ErrRevisionMismatch, err)
+	}
+
+	if err := client.Catchup(3, nil, true); err != cooperate.ErrNothingInFlight {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", cooperate.ErrNothingInFlight, err)
+	}
+
+}
+
+func TestClient_IdenticalDeletes(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument("xy"),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument("xy"),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{Priority: text.FavorA},
+	}
+
+	// the client deletes what another client has just deleted, and then edits
+	// again while waiting for its delete to be acknowledged
+	del := cooperate.Operation{text.DeleteAction("xy")}
+
+	if err := client.ApplyLocal(del); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Apply(0, del); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := s.Apply(0, del); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.ApplyReceived(del); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.ApplyLocal(cooperate.Operation{text.InsertAction("z")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// nothing is left of the in-flight delete, but it is still acknowledged
+	if client.InFlight == nil || len(client.InFlight) != 0 {
+		t.Errorf("unexpected in-flight operation: %#v", client.InFlight)
+	}
+	if err := client.Acknowledge(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if client.Revision != 2 || client.Seq != 2 {
+		t.Errorf("unexpected revision and seq: expected 2 and 2 but got %d and %d", client.Revision, client.Seq)
+	}
+
+	if err := s.Apply(client.Revision, client.InFlight); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.Acknowledge(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	server, local := s.Document.(*text.TextDocument).String(), client.Document.(*text.TextDocument).String()
+	if server != "z" || local != server {
+		t.Errorf("documents diverged: server has %q and client has %q", server, local)
+	}
+
+}
+
+func TestClient_Simulation(t *testing.T) {
+	ottest.Converge(t, textConfig, 200)
+}
+
+var textConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return text.NewTextDocument("hello"[:r.Intn(6)])
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return text.TextHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		return randomOperation(r, doc.(*text.TextDocument).String())
+	},
+}
+
+// randomOperation returns an operation on doc that retains, deletes and
+// inserts text at random.
+func randomOperation(r *rand.Rand, doc string) cooperate.Operation {
+	var op cooperate.Operation
+	for cur := 0; cur < len(doc); {
+		n := 1 + r.Intn(len(doc)-cur)
+		switch r.Intn(3) {
+		case 0:
+			op = append(op, text.RetainAction(n))
+		case 1:
+			op = append(op, text.DeleteAction(doc[cur:cur+n]))
+		case 2:
+			op = append(op, text.InsertAction(string(rune('a'+r.Intn(26)))), text.RetainAction(n))
+		}
+		cur += n
+	}
+	if len(op) == 0 || r.Intn(3) == 0 {
+		op = append(op, text.InsertAction(string(rune('A'+r.Intn(26)))))
+	}
+	return op
+}
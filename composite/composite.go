@@ -0,0 +1,171 @@
+// Package composite implements documents made of named child documents of
+// possibly different types, such as a text title, a rich text body and a
+// map of metadata.
+//
+// A composite operation is a list of ChildActions, each of which carries an
+// operation for one child. Composition and transformation are delegated to
+// the handler of each child, so that edits to different children never
+// conflict.
+package composite
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrUnknownChild indicates that an action names a child for which there is
+// no document or handler.
+var ErrUnknownChild = errors.New("unknown child")
+
+type (
+	// A Handler implements the OT operations for the documents of one child.
+	Handler interface {
+		cooperate.ComposeTransformer
+		cooperate.ExpandReducer
+	}
+
+	// A CompositeHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for composite operations by delegating to the
+	// Handler of each child.
+	CompositeHandler struct {
+		Children map[string]Handler
+	}
+
+	// ChildAction applies Op to the child called Name.
+	ChildAction struct {
+		Name string
+		Op   cooperate.Operation
+	}
+)
+
+func (a ChildAction) GoString() string {
+	return fmt.Sprintf("C(%s, %#v)", a.Name, a.Op)
+}
+
+// Expand returns a unchanged; the operations of each child are expanded as
+// needed by Compose and Transform.
+func (ch CompositeHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce never combines actions, since operations on the same child can be
+// combined only by composing them, which may fail.
+func (ch CompositeHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b. The composed operation
+// contains at most one ChildAction per child, ordered by name.
+func (ch CompositeHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	ops, err := ch.gather(nil, a)
+	if err != nil {
+		return nil, err
+	}
+	if ops, err = ch.gather(ops, b); err != nil {
+		return nil, err
+	}
+
+	return sorted(ops), nil
+
+}
+
+// Transform implements cooperate.Transformer for composite operations.
+// Operations on different children are independent, and operations on the
+// same child are transformed by that child's Handler.
+func (ch CompositeHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	aOps, err := ch.gather(nil, a)
+	if err != nil {
+		return nil, nil, err
+	}
+	bOps, err := ch.gather(nil, b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for name, aOp := range aOps {
+		bOp, ok := bOps[name]
+		if !ok {
+			continue
+		}
+		h := ch.Children[name]
+		aPrime, bPrime, err := h.Transform(
+			cooperate.NewOperationIterator(cooperate.Expand(h, aOp)),
+			cooperate.NewOperationIterator(cooperate.Expand(h, bOp)),
+		)
+		if err != nil {
+			return nil, nil, err
+		}
+		aOps[name] = cooperate.Reduce(h, aPrime)
+		bOps[name] = cooperate.Reduce(h, bPrime)
+	}
+
+	return sorted(aOps), sorted(bOps), nil
+
+}
+
+// gather composes the ChildActions of oit onto ops, returning the operation
+// for each child.
+func (ch CompositeHandler) gather(ops map[string]cooperate.Operation, oit *cooperate.OperationIterator) (map[string]cooperate.Operation, error) {
+
+	if ops == nil {
+		ops = make(map[string]cooperate.Operation)
+	}
+
+	for oit.More() {
+
+		a, ok := oit.Consume().(ChildAction)
+		if !ok {
+			return nil, cooperate.ErrUnknownAction
+		}
+
+		h, ok := ch.Children[a.Name]
+		if !ok {
+			return nil, ErrUnknownChild
+		}
+
+		prev, ok := ops[a.Name]
+		if !ok {
+			ops[a.Name] = a.Op
+			continue
+		}
+
+		composed, err := h.Compose(
+			cooperate.NewOperationIterator(cooperate.Expand(h, prev)),
+			cooperate.NewOperationIterator(cooperate.Expand(h, a.Op)),
+		)
+		if err != nil {
+			return nil, err
+		}
+		ops[a.Name] = cooperate.Reduce(h, composed)
+
+	}
+
+	return ops, nil
+
+}
+
+// sorted returns the operations of ops as ChildActions ordered by name,
+// omitting children whose operations are empty.
+func sorted(ops map[string]cooperate.Operation) cooperate.Operation {
+
+	names := make([]string, 0, len(ops))
+	for name, op := range ops {
+		if len(op) > 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var actions []cooperate.Action
+	for _, name := range names {
+		actions = append(actions, ChildAction{Name: name, Op: ops[name]})
+	}
+	return cooperate.Operation(actions)
+
+}
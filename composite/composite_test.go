@@ -0,0 +1,125 @@
+package composite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/kv"
+	"github.com/tylerchr/cooperate/rich"
+	"github.com/tylerchr/cooperate/text"
+)
+
+var handler = CompositeHandler{
+	Children: map[string]Handler{
+		"title":    text.TextHandler{},
+		"body":     rich.RichTextHandler{},
+		"metadata": kv.KVHandler{},
+	},
+}
+
+func TestCompose(t *testing.T) {
+
+	a := cooperate.NewOperationIterator(cooperate.Operation{
+		ChildAction{Name: "title", Op: cooperate.Operation{text.InsertAction("a"), text.RetainAction(1)}},
+		ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "k", Value: 1}}},
+	})
+	b := cooperate.NewOperationIterator(cooperate.Operation{
+		ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("b")}},
+	})
+
+	expected := cooperate.Operation{
+		ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "k", Value: 1}}},
+		ChildAction{Name: "title", Op: cooperate.Operation{text.InsertAction("a"), text.RetainAction(1), text.InsertAction("b")}},
+	}
+
+	if sum, err := handler.Compose(a, b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(sum, expected) {
+		t.Errorf("unexpected composition: expected '%#v' but got '%#v'", expected, sum)
+	}
+
+	unknown := cooperate.NewOperationIterator(cooperate.Operation{ChildAction{Name: "missing"}})
+	if _, err := handler.Compose(unknown, cooperate.NewOperationIterator(cooperate.Operation(nil))); err != ErrUnknownChild {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", ErrUnknownChild, err)
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	a := cooperate.Operation{
+		ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("a")}},
+		ChildAction{Name: "body", Op: cooperate.Operation{rich.RetainAction{N: 3, Attributes: rich.Attributes{rich.Bold: true}}}},
+	}
+	b := cooperate.Operation{
+		ChildAction{Name: "title", Op: cooperate.Operation{text.InsertAction("b"), text.RetainAction(2)}},
+		ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "k", Value: 1}}},
+	}
+
+	aPrime, bPrime, err := handler.Transform(cooperate.NewOperationIterator(a), cooperate.NewOperationIterator(b))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expectedA := cooperate.Operation{
+		ChildAction{Name: "body", Op: cooperate.Operation{rich.RetainAction{N: 3, Attributes: rich.Attributes{rich.Bold: true}}}},
+		ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(3), text.InsertAction("a")}},
+	}
+	expectedB := cooperate.Operation{
+		ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "k", Value: 1}}},
+		ChildAction{Name: "title", Op: cooperate.Operation{text.InsertAction("b"), text.RetainAction(3)}},
+	}
+
+	if !reflect.DeepEqual(aPrime, expectedA) || !reflect.DeepEqual(bPrime, expectedB) {
+		t.Errorf("unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", expectedA, expectedB, aPrime, bPrime)
+	}
+
+}
+
+func TestServer(t *testing.T) {
+
+	title := text.NewTextDocument("hi")
+	body := rich.NewRichTextDocument(rich.Run{Text: "abc"})
+	metadata := kv.NewKVDocument(nil)
+
+	s := &cooperate.Server{
+		Document: NewCompositeDocument(map[string]cooperate.Document{
+			"title":    title,
+			"body":     body,
+			"metadata": metadata,
+		}),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      handler,
+		ComposeTransformer: handler,
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("!")}}}},
+		{Root: 0, Operation: cooperate.Operation{
+			ChildAction{Name: "title", Op: cooperate.Operation{text.InsertAction("oh "), text.RetainAction(2)}},
+			ChildAction{Name: "body", Op: cooperate.Operation{rich.RetainAction{N: 1, Attributes: rich.Attributes{rich.Italic: true}}, rich.RetainAction{N: 2}}},
+		}},
+		{Root: 1, Operation: cooperate.Operation{ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "author", Value: "ada"}}}}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if expected := "oh hi!"; title.String() != expected {
+		t.Errorf("unexpected title: expected %q but got %q", expected, title.String())
+	}
+	if expected := []rich.Run{{Text: "a", Attributes: rich.Attributes{rich.Italic: true}}, {Text: "bc"}}; !reflect.DeepEqual(body.Runs(), expected) {
+		t.Errorf("unexpected body: expected '%v' but got '%v'", expected, body.Runs())
+	}
+	if expected := (kv.KVDocument{"author": "ada"}); !reflect.DeepEqual(metadata, expected) {
+		t.Errorf("unexpected metadata: expected '%v' but got '%v'", expected, metadata)
+	}
+
+}
@@ -0,0 +1,56 @@
+package composite
+
+import (
+	"github.com/tylerchr/cooperate"
+)
+
+// A CompositeDocument is a set of named child documents that implements the
+// cooperate.Document interface.
+type CompositeDocument struct {
+	children map[string]cooperate.Document
+}
+
+// NewCompositeDocument initializes a CompositeDocument with the given
+// children. The set of children is fixed; their contents change only through
+// Apply.
+func NewCompositeDocument(children map[string]cooperate.Document) *CompositeDocument {
+	cd := &CompositeDocument{children: make(map[string]cooperate.Document, len(children))}
+	for name, doc := range children {
+		cd.children[name] = doc
+	}
+	return cd
+}
+
+// Child returns the child document called name, or nil if none exists.
+func (cd *CompositeDocument) Child(name string) cooperate.Document {
+	return cd.children[name]
+}
+
+// Apply performs op against the CompositeDocument by applying the operation
+// of each ChildAction to its child.
+//
+// Every action is checked before any is applied, but since children are
+// applied one at a time, an error from a child may leave the children
+// before it changed.
+func (cd *CompositeDocument) Apply(op cooperate.Operation) error {
+
+	for _, a := range op {
+		ca, ok := a.(ChildAction)
+		if !ok {
+			return cooperate.ErrUnknownAction
+		}
+		if _, ok := cd.children[ca.Name]; !ok {
+			return ErrUnknownChild
+		}
+	}
+
+	for _, a := range op {
+		ca := a.(ChildAction)
+		if err := cd.children[ca.Name].Apply(ca.Op); err != nil {
+			return err
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,57 @@
+package composite
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/kv"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestCompositeDocument(t *testing.T) {
+
+	cases := []struct {
+		Operation        cooperate.Operation
+		ExpectedError    error
+		ExpectedTitle    string
+		ExpectedMetadata kv.KVDocument
+	}{
+		{
+			Operation: cooperate.Operation{
+				ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(5), text.InsertAction("!")}},
+				ChildAction{Name: "metadata", Op: cooperate.Operation{kv.SetAction{Key: "author", Value: "ada"}}},
+			},
+			ExpectedTitle:    "hello!",
+			ExpectedMetadata: kv.KVDocument{"author": "ada"},
+		},
+		{
+			Operation: cooperate.Operation{
+				ChildAction{Name: "title", Op: cooperate.Operation{text.RetainAction(5), text.InsertAction("!")}},
+				ChildAction{Name: "missing", Op: cooperate.Operation{}},
+			},
+			ExpectedError:    ErrUnknownChild,
+			ExpectedTitle:    "hello",
+			ExpectedMetadata: kv.KVDocument{},
+		},
+		{
+			Operation:        cooperate.Operation{text.InsertAction("!")},
+			ExpectedError:    cooperate.ErrUnknownAction,
+			ExpectedTitle:    "hello",
+			ExpectedMetadata: kv.KVDocument{},
+		},
+	}
+
+	for i, c := range cases {
+
+		title, metadata := text.NewTextDocument("hello"), kv.NewKVDocument(nil)
+		doc := NewCompositeDocument(map[string]cooperate.Document{"title": title, "metadata": metadata})
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if title.String() != c.ExpectedTitle || !reflect.DeepEqual(metadata, c.ExpectedMetadata) {
+			t.Errorf("[case %d] unexpected document: expected (%q, %v) but got (%q, %v)", i, c.ExpectedTitle, c.ExpectedMetadata, title.String(), metadata)
+		}
+	}
+
+}
@@ -20,15 +20,15 @@ var (
 )
 
 type (
-	// A Document is data that may be collaboratively edited via
-	// a series of distributed Operations.
-	Document interface {
-		Apply(Operation) error
+	// A DocumentOf is data that may be collaboratively edited via a series of
+	// distributed operations whose actions are of type A.
+	DocumentOf[A any] interface {
+		Apply(OperationOf[A]) error
 	}
 
-	// An Operation is a list of component actions that together define one
-	// complete iteration through a document.
-	Operation []Action
+	// An OperationOf is a list of component actions of type A that together
+	// define one complete iteration through a document.
+	OperationOf[A any] []A
 
 	// // TODO(tylerchr): I think eventually we should use an "Operation" that looks more like this:
 	// Operation struct {
@@ -41,72 +41,101 @@ type (
 	// 	Actions []Action // the enumeration of actions that form the operation
 	// }
 
-	// An Action is something that can be performed as part of an operation.
-	Action interface{}
-
-	// A Composer provides an application-aware implementation of the OT compose
+	// A ComposerOf provides an application-aware implementation of the OT compose
 	// function. Mathematically, it's defined as
 	//
 	//   Compose(a, b) ≡ a ◦ b
 	//
 	// In other words, given two operations a and b, it is expected to produce a
 	// single operation c that produces the same effect as applying a then b.
-	Composer interface {
-		Compose(a, b *OperationIterator) (Operation, error)
+	ComposerOf[A any] interface {
+		Compose(a, b *OperationIteratorOf[A]) (OperationOf[A], error)
 	}
 
-	// A Transformer provides an application-aware implementation of the OT transform
+	// A TransformerOf provides an application-aware implementation of the OT transform
 	// function by producing the operational inverses of a and b. Mathematically:
 	//
 	//   transform(a, b) = (a', b') where a ◦ b' ≡ b ◦ a'
 	//
-	// Implementations of Transformer are expected to behave such that the above
+	// Implementations of TransformerOf are expected to behave such that the above
 	// equality holds.
-	Transformer interface {
-		Transform(a, b *OperationIterator) (aa, bb Operation, err error)
+	TransformerOf[A any] interface {
+		Transform(a, b *OperationIteratorOf[A]) (aa, bb OperationOf[A], err error)
 	}
 
-	// A ComposeTransformer implements the two core OT functions.
-	ComposeTransformer interface {
-		Composer
-		Transformer
+	// A ComposeTransformerOf implements the two core OT functions.
+	ComposeTransformerOf[A any] interface {
+		ComposerOf[A]
+		TransformerOf[A]
 	}
 
-	// An ExpandReducer converts an Operation to its most and least verbose forms,
+	// An ExpandReducerOf converts an operation to its most and least verbose forms,
 	// respectively. It may help simplify implementations of Compose and Transform.
-	ExpandReducer interface {
+	ExpandReducerOf[A any] interface {
 		// Expand inflates a single action to its atomic parts.
-		Expand(a Action) []Action
+		Expand(a A) []A
 
 		// Reduce merges actions a and b, or indicates that they are unmergable. The
 		// arguments are provided such that the action a happens before action b.
-		Reduce(a, b Action) (Action, bool)
+		Reduce(a, b A) (A, bool)
 	}
 )
 
-// An OperationIterator provides an interface to the actions within an Operation
-// that helps simplify Composer and Transformer implementations.
-type OperationIterator struct {
+// The untyped API is the generic API instantiated over Action, which permits
+// any value as an action. Packages that define a closed set of actions may
+// instead instantiate the generic API over their own action type to have
+// actions checked at compile time.
+type (
+	// An Action is something that can be performed as part of an operation.
+	Action interface{}
+
+	// A Document is data that may be collaboratively edited via
+	// a series of distributed Operations.
+	Document = DocumentOf[Action]
+
+	// An Operation is a list of component actions that together define one
+	// complete iteration through a document.
+	Operation = OperationOf[Action]
+
+	// A Composer is a ComposerOf untyped actions.
+	Composer = ComposerOf[Action]
+
+	// A Transformer is a TransformerOf untyped actions.
+	Transformer = TransformerOf[Action]
+
+	// A ComposeTransformer is a ComposeTransformerOf untyped actions.
+	ComposeTransformer = ComposeTransformerOf[Action]
+
+	// An ExpandReducer is an ExpandReducerOf untyped actions.
+	ExpandReducer = ExpandReducerOf[Action]
+
+	// An OperationIterator is an OperationIteratorOf untyped actions.
+	OperationIterator = OperationIteratorOf[Action]
+)
+
+// An OperationIteratorOf provides an interface to the actions within an
+// operation that helps simplify Composer and Transformer implementations.
+type OperationIteratorOf[A any] struct {
 	Cursor  int
-	Actions []Action
+	Actions []A
 }
 
-func NewOperationIterator(op Operation) *OperationIterator {
-	return &OperationIterator{Actions: []Action(op)}
+func NewOperationIterator[A any](op OperationOf[A]) *OperationIteratorOf[A] {
+	return &OperationIteratorOf[A]{Actions: []A(op)}
 }
 
 // More indicates whether any unconsumed actions remain.
-func (oit *OperationIterator) More() bool {
+func (oit *OperationIteratorOf[A]) More() bool {
 	return oit.Len() > 0
 }
 
 // Len reports the number of unconsumed actions remaining.
-func (oit *OperationIterator) Len() int {
+func (oit *OperationIteratorOf[A]) Len() int {
 	return len(oit.Actions) - oit.Cursor
 }
 
 // Peek returns the foremost action. It panics if none remain.
-func (oit *OperationIterator) Peek() Action {
+func (oit *OperationIteratorOf[A]) Peek() A {
 	if oit.Cursor >= len(oit.Actions) {
 		panic(errors.New("no actions remain in iterator"))
 	}
@@ -114,7 +143,9 @@ func (oit *OperationIterator) Peek() Action {
 }
 
 // PeekType returns the reflect.Type of the foremost action, or nil if none remain.
-func (oit *OperationIterator) PeekType() reflect.Type {
+//
+// A type switch on Peek is considerably faster and should be preferred.
+func (oit *OperationIteratorOf[A]) PeekType() reflect.Type {
 	if oit.More() {
 		return reflect.TypeOf(oit.Peek())
 	}
@@ -123,7 +154,7 @@ func (oit *OperationIterator) PeekType() reflect.Type {
 
 // Consume advances the iterator over foremost action and returns it. It panics if
 // no actions remain.
-func (oit *OperationIterator) Consume() Action {
+func (oit *OperationIteratorOf[A]) Consume() A {
 	if oit.Cursor >= len(oit.Actions) {
 		panic(errors.New("no actions remain in iterator"))
 	}
@@ -133,19 +164,19 @@ func (oit *OperationIterator) Consume() Action {
 
 // Expand inflates op such that each action affects only one element. For
 // example, a RetainAction(6) becomes six consecutive RetainAction(1).
-func Expand(er ExpandReducer, op Operation) Operation {
-	var actions []Action
-	for _, a := range []Action(op) {
+func Expand[A any](er ExpandReducerOf[A], op OperationOf[A]) OperationOf[A] {
+	var actions []A
+	for _, a := range []A(op) {
 		actions = append(actions, er.Expand(a)...)
 	}
-	return Operation(actions)
+	return OperationOf[A](actions)
 }
 
 // Reduce collapses op into the minumum possible number of actions that have
 // an identical effect.
-func Reduce(er ExpandReducer, op Operation) Operation {
-	var actions []Action
-	for _, a := range []Action(op) {
+func Reduce[A any](er ExpandReducerOf[A], op OperationOf[A]) OperationOf[A] {
+	var actions []A
+	for _, a := range []A(op) {
 		if len(actions) == 0 {
 			actions = append(actions, a)
 		} else if reduced, ok := er.Reduce(actions[len(actions)-1], a); ok {
@@ -154,5 +185,51 @@ func Reduce(er ExpandReducer, op Operation) Operation {
 			actions = append(actions, a)
 		}
 	}
+	return OperationOf[A](actions)
+}
+
+// ToUntyped converts op to an untyped Operation.
+func ToUntyped[A any](op OperationOf[A]) Operation {
+	if op == nil {
+		return nil
+	}
+	actions := make([]Action, len(op))
+	for i, a := range op {
+		actions[i] = a
+	}
 	return Operation(actions)
 }
+
+// FromUntyped converts op to an OperationOf[A]. It returns ErrUnknownAction
+// if any action in op is not an A.
+func FromUntyped[A any](op Operation) (OperationOf[A], error) {
+	if op == nil {
+		return nil, nil
+	}
+	actions := make([]A, len(op))
+	for i, a := range op {
+		typed, ok := a.(A)
+		if !ok {
+			return nil, ErrUnknownAction
+		}
+		actions[i] = typed
+	}
+	return OperationOf[A](actions), nil
+}
+
+// UntypedDocument adapts a DocumentOf[A] to the untyped Document interface.
+func UntypedDocument[A any](doc DocumentOf[A]) Document {
+	return untypedDocument[A]{doc}
+}
+
+type untypedDocument[A any] struct {
+	doc DocumentOf[A]
+}
+
+func (ud untypedDocument[A]) Apply(op Operation) error {
+	typed, err := FromUntyped[A](op)
+	if err != nil {
+		return err
+	}
+	return ud.doc.Apply(typed)
+}
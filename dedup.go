@@ -0,0 +1,146 @@
+package cooperate
+
+import "errors"
+
+var (
+	// ErrStaleSubmission indicates that a submission is older than the last
+	// one committed for its client, so that its ack is no longer known.
+	ErrStaleSubmission = errors.New("stale submission")
+
+	// ErrDedupUnsupported indicates that a server's history cannot record
+	// which submissions it has committed.
+	ErrDedupUnsupported = errors.New("history does not support deduplication")
+)
+
+type (
+	// A SubmissionOf is an operation sent to a server by a client. Seq
+	// numbers the operations of each client, increasing with every new
+	// operation, so that a client that resends an operation after
+	// reconnecting reuses its Seq. Session identifies the user of the client
+	// to the server's Authorizer.
+	SubmissionOf[A any] struct {
+		Session   string
+		ClientID  string
+		Seq       int
+		Root      int
+		Operation OperationOf[A]
+	}
+
+	// An Ack acknowledges a submission. Revision is the sequence number the
+	// submitted operation produced, and Duplicate reports whether it had
+	// already been committed by an earlier submission.
+	Ack struct {
+		Revision  int
+		Duplicate bool
+	}
+
+	// DedupHistoryOf is a HistoryOf that also records the last submission
+	// committed for each client, so that a server can recognize an
+	// operation that is resent.
+	DedupHistoryOf[A any] interface {
+		HistoryOf[A]
+
+		// Committed returns the seqno of the operation committed for
+		// submission seq of clientID, and whether there is one. It returns
+		// ErrStaleSubmission if seq precedes the last submission committed
+		// for clientID.
+		Committed(clientID string, seq int) (seqno int, ok bool, err error)
+
+		// StoreSubmission is like Store, but also records op as the last
+		// submission committed for clientID, atomically.
+		StoreSubmission(clientID string, seq int, op OperationOf[A]) (seqno int, err error)
+	}
+
+	// SnapshotDedupHistoryOf is a DedupHistoryOf that can also store
+	// snapshots of the document along with submissions.
+	SnapshotDedupHistoryOf[A any] interface {
+		DedupHistoryOf[A]
+		SnapshotHistoryOf[A]
+
+		// StoreSubmissionSnapshot is like StoreSubmission, but also stores
+		// snapshot as StoreSnapshot does, atomically.
+		StoreSubmissionSnapshot(clientID string, seq int, op OperationOf[A], snapshot []byte) (seqno int, err error)
+	}
+
+	// DedupMemoryHistoryOf is a MemoryHistoryOf that implements
+	// DedupHistoryOf.
+	DedupMemoryHistoryOf[A any] struct {
+		MemoryHistoryOf[A]
+		clients map[string]submitted
+	}
+
+	// Submission is a SubmissionOf untyped actions.
+	Submission = SubmissionOf[Action]
+
+	// DedupHistory is a DedupHistoryOf untyped actions.
+	DedupHistory = DedupHistoryOf[Action]
+
+	// SnapshotDedupHistory is a SnapshotDedupHistoryOf untyped actions.
+	SnapshotDedupHistory = SnapshotDedupHistoryOf[Action]
+
+	// DedupMemoryHistory is a DedupMemoryHistoryOf untyped actions.
+	DedupMemoryHistory = DedupMemoryHistoryOf[Action]
+)
+
+// submitted is the last submission committed for a client.
+type submitted struct {
+	seq, seqno int
+}
+
+func (mh *DedupMemoryHistoryOf[A]) Committed(clientID string, seq int) (int, bool, error) {
+	last, ok := mh.clients[clientID]
+	switch {
+	case !ok || seq > last.seq:
+		return 0, false, nil
+	case seq < last.seq:
+		return 0, false, ErrStaleSubmission
+	}
+	return last.seqno, true, nil
+}
+
+func (mh *DedupMemoryHistoryOf[A]) StoreSubmission(clientID string, seq int, op OperationOf[A]) (int, error) {
+	seqno, err := mh.Store(op)
+	if err != nil {
+		return 0, err
+	}
+	if mh.clients == nil {
+		mh.clients = make(map[string]submitted)
+	}
+	mh.clients[clientID] = submitted{seq: seq, seqno: seqno}
+	return seqno, nil
+}
+
+// Submit applies the operation of sub, unless it has already been committed,
+// in which case it returns the original ack with Duplicate set. The server's
+// History must implement DedupHistoryOf.
+func (s *ServerOf[A]) Submit(sub SubmissionOf[A]) (Ack, error) {
+
+	h, ok := s.History.(DedupHistoryOf[A])
+	if !ok {
+		return Ack{}, ErrDedupUnsupported
+	}
+
+	if seqno, ok, err := h.Committed(sub.ClientID, sub.Seq); err != nil {
+		if errors.Is(err, ErrStaleSubmission) {
+			s.Metrics.rejectedOp(err)
+		} else {
+			s.Metrics.historyFailed()
+		}
+		return Ack{}, err
+	} else if ok {
+		return Ack{Revision: seqno, Duplicate: true}, nil
+	}
+
+	seqno, err := s.apply(sub.Session, sub.Root, sub.Operation, func(op OperationOf[A], snapshot []byte) (int, error) {
+		if h, ok := h.(SnapshotDedupHistoryOf[A]); ok && snapshot != nil {
+			return h.StoreSubmissionSnapshot(sub.ClientID, sub.Seq, op, snapshot)
+		}
+		return h.StoreSubmission(sub.ClientID, sub.Seq, op)
+	})
+	if err != nil {
+		return Ack{}, err
+	}
+
+	return Ack{Revision: seqno}, nil
+
+}
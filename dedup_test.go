@@ -0,0 +1,77 @@
+package cooperate_test
+
+import (
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestServer_Submit(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.DedupMemoryHistory{},
+		ExpandReducer:      &text.TextHandler{},
+		ComposeTransformer: &text.TextHandler{},
+	}
+
+	subs := []struct {
+		Submission cooperate.Submission
+		Ack        cooperate.Ack
+		Error      error
+	}{
+		{
+			Submission: cooperate.Submission{ClientID: "alice", Seq: 1, Root: 0, Operation: cooperate.Operation{text.InsertAction("foo")}},
+			Ack:        cooperate.Ack{Revision: 1},
+		},
+		{
+			Submission: cooperate.Submission{ClientID: "bob", Seq: 1, Root: 0, Operation: cooperate.Operation{text.InsertAction("bar")}},
+			Ack:        cooperate.Ack{Revision: 2},
+		},
+		{
+			// alice reconnects and resends her operation
+			Submission: cooperate.Submission{ClientID: "alice", Seq: 1, Root: 0, Operation: cooperate.Operation{text.InsertAction("foo")}},
+			Ack:        cooperate.Ack{Revision: 1, Duplicate: true},
+		},
+		{
+			Submission: cooperate.Submission{ClientID: "alice", Seq: 2, Root: 2, Operation: cooperate.Operation{text.RetainAction(6), text.InsertAction("!")}},
+			Ack:        cooperate.Ack{Revision: 3},
+		},
+		{
+			Submission: cooperate.Submission{ClientID: "alice", Seq: 1, Root: 0, Operation: cooperate.Operation{text.InsertAction("foo")}},
+			Error:      cooperate.ErrStaleSubmission,
+		},
+	}
+
+	for i, c := range subs {
+		if ack, err := s.Submit(c.Submission); err != c.Error {
+			t.Errorf("[case %d] unexpected error state: expected '%v' but got '%v'", i, c.Error, err)
+		} else if ack != c.Ack {
+			t.Errorf("[case %d] unexpected ack: expected %+v but got %+v", i, c.Ack, ack)
+		}
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "barfoo!" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+	if seqno := s.History.SequenceNumber(); seqno != 3 {
+		t.Errorf("unexpected sequence number: expected 3 but got %d", seqno)
+	}
+
+}
+
+func TestServer_SubmitUnsupported(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document: text.NewTextDocument(""),
+		History:  &cooperate.MemoryHistory{},
+	}
+
+	sub := cooperate.Submission{ClientID: "alice", Seq: 1, Operation: cooperate.Operation{text.InsertAction("foo")}}
+	if _, err := s.Submit(sub); err != cooperate.ErrDedupUnsupported {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", cooperate.ErrDedupUnsupported, err)
+	}
+
+}
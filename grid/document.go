@@ -0,0 +1,155 @@
+package grid
+
+import (
+	"errors"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrOutOfRange indicates that an action addresses a row, column or cell
+// that does not exist in the document.
+var ErrOutOfRange = errors.New("out of range")
+
+// A GridDocument is a two-dimensional table of cells that implements the
+// cooperate.Document interface. Cells hold arbitrary values, or nil if empty.
+type GridDocument struct {
+	cells   [][]interface{}
+	columns int
+}
+
+// NewGridDocument initializes a GridDocument with a copy of cells. Rows
+// shorter than the longest row are padded with empty cells.
+func NewGridDocument(cells [][]interface{}) *GridDocument {
+	gd := &GridDocument{}
+	for _, row := range cells {
+		if len(row) > gd.columns {
+			gd.columns = len(row)
+		}
+	}
+	for _, row := range cells {
+		r := make([]interface{}, gd.columns)
+		copy(r, row)
+		gd.cells = append(gd.cells, r)
+	}
+	return gd
+}
+
+// Rows returns the number of rows in the document.
+func (gd *GridDocument) Rows() int {
+	return len(gd.cells)
+}
+
+// Columns returns the number of columns in the document.
+func (gd *GridDocument) Columns() int {
+	return gd.columns
+}
+
+// Cell returns the value of the cell at row and column.
+func (gd *GridDocument) Cell(row, column int) interface{} {
+	return gd.cells[row][column]
+}
+
+// Cells returns the current contents of the document, row by row. The
+// returned slices must not be modified.
+func (gd *GridDocument) Cells() [][]interface{} {
+	return gd.cells
+}
+
+// Apply performs op against the GridDocument. The actions of op are applied
+// in order; if any of them fails, the document is left unchanged.
+func (gd *GridDocument) Apply(op cooperate.Operation) error {
+
+	// check every action against the dimensions it will find before changing
+	// anything, so that a failure needn't be undone
+	rows, columns := len(gd.cells), gd.columns
+	for _, a := range op {
+		var err error
+		if rows, columns, err = resize(rows, columns, a); err != nil {
+			return err
+		}
+	}
+
+	for _, a := range op {
+		gd.apply(a)
+	}
+
+	return nil
+
+}
+
+// resize returns the dimensions of a document of the given dimensions after
+// a is applied to it, or an error if a cannot be applied.
+func resize(rows, columns int, a cooperate.Action) (int, int, error) {
+
+	switch a := a.(type) {
+
+	case InsertRowAction:
+		if int(a) < 0 || int(a) > rows {
+			return 0, 0, ErrOutOfRange
+		}
+		return rows + 1, columns, nil
+
+	case DeleteRowAction:
+		if int(a) < 0 || int(a) >= rows {
+			return 0, 0, ErrOutOfRange
+		}
+		return rows - 1, columns, nil
+
+	case InsertColumnAction:
+		if int(a) < 0 || int(a) > columns {
+			return 0, 0, ErrOutOfRange
+		}
+		return rows, columns + 1, nil
+
+	case DeleteColumnAction:
+		if int(a) < 0 || int(a) >= columns {
+			return 0, 0, ErrOutOfRange
+		}
+		return rows, columns - 1, nil
+
+	case SetCellAction:
+		if a.Row < 0 || a.Row >= rows || a.Column < 0 || a.Column >= columns {
+			return 0, 0, ErrOutOfRange
+		}
+		return rows, columns, nil
+
+	}
+
+	return 0, 0, cooperate.ErrUnknownAction
+
+}
+
+// apply performs a, which resize has checked, against the GridDocument.
+func (gd *GridDocument) apply(a cooperate.Action) {
+
+	switch a := a.(type) {
+
+	case InsertRowAction:
+		gd.cells = append(gd.cells, nil)
+		copy(gd.cells[a+1:], gd.cells[a:])
+		gd.cells[a] = make([]interface{}, gd.columns)
+
+	case DeleteRowAction:
+		gd.cells = append(gd.cells[:a], gd.cells[a+1:]...)
+
+	case InsertColumnAction:
+		for i, row := range gd.cells {
+			row = append(row, nil)
+			copy(row[a+1:], row[a:])
+			row[a] = nil
+			gd.cells[i] = row
+		}
+		gd.columns++
+
+	case DeleteColumnAction:
+		for i, row := range gd.cells {
+			gd.cells[i] = append(row[:a], row[a+1:]...)
+		}
+		gd.columns--
+
+	case SetCellAction:
+		gd.cells[a.Row][a.Column] = a.Value
+
+	}
+
+}
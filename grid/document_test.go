@@ -0,0 +1,69 @@
+package grid
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestGridDocument(t *testing.T) {
+
+	cases := []struct {
+		Existing      [][]interface{}
+		Operation     cooperate.Operation
+		ExpectedError error
+		Expected      [][]interface{}
+	}{
+		// ragged rows are padded
+		{
+			Existing: [][]interface{}{{1, 2}, {3}},
+			Expected: [][]interface{}{{1, 2}, {3, nil}},
+		},
+		{
+			Existing: [][]interface{}{{1, 2}, {3, 4}},
+			Operation: cooperate.Operation{
+				InsertRowAction(1),
+				InsertColumnAction(0),
+				SetCellAction{Row: 1, Column: 0, Value: "x"},
+			},
+			Expected: [][]interface{}{{nil, 1, 2}, {"x", nil, nil}, {nil, 3, 4}},
+		},
+		{
+			Existing:  [][]interface{}{{1, 2}, {3, 4}},
+			Operation: cooperate.Operation{DeleteRowAction(0), DeleteColumnAction(1)},
+			Expected:  [][]interface{}{{3}},
+		},
+		// a failed action leaves the document unchanged
+		{
+			Existing:      [][]interface{}{{1, 2}, {3, 4}},
+			Operation:     cooperate.Operation{DeleteRowAction(0), SetCellAction{Row: 1, Column: 0, Value: "x"}},
+			ExpectedError: ErrOutOfRange,
+			Expected:      [][]interface{}{{1, 2}, {3, 4}},
+		},
+		{
+			Existing:      [][]interface{}{{1, 2}, {3, 4}},
+			Operation:     cooperate.Operation{SetCellAction{Row: 0, Column: 0, Value: "x"}, InsertColumnAction(1), DeleteColumnAction(3)},
+			ExpectedError: ErrOutOfRange,
+			Expected:      [][]interface{}{{1, 2}, {3, 4}},
+		},
+		{
+			Existing:      [][]interface{}{{1}},
+			Operation:     cooperate.Operation{"bogus"},
+			ExpectedError: cooperate.ErrUnknownAction,
+			Expected:      [][]interface{}{{1}},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewGridDocument(c.Existing)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Cells(), c.Expected) {
+			t.Errorf("[case %d] unexpected document: expected '%v' but got '%v'", i, c.Expected, doc.Cells())
+		}
+	}
+
+}
@@ -0,0 +1,242 @@
+// Package grid implements collaborative editing of a two-dimensional table
+// of cells, such as a spreadsheet.
+//
+// An operation is a list of actions applied one after another. Rows and
+// columns are inserted empty and filled in with SetCellAction.
+package grid
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/json0"
+)
+
+type (
+	// A GridHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the grid actions defined in this package.
+	GridHandler struct {
+		// Priority decides whose row or column comes first when a and b
+		// insert at the same index, and whose value wins when both set the
+		// same cell.
+		Priority cooperate.Priority
+	}
+
+	// InsertRowAction inserts an empty row so that it ends up at the given
+	// index.
+	InsertRowAction int
+
+	// DeleteRowAction removes the row at the given index.
+	DeleteRowAction int
+
+	// InsertColumnAction inserts an empty column so that it ends up at the
+	// given index.
+	InsertColumnAction int
+
+	// DeleteColumnAction removes the column at the given index.
+	DeleteColumnAction int
+
+	// SetCellAction sets the cell at Row and Column to Value.
+	SetCellAction struct {
+		Row, Column int
+		Value       interface{}
+	}
+)
+
+func (a InsertRowAction) GoString() string    { return fmt.Sprintf("IR(%d)", a) }
+func (a DeleteRowAction) GoString() string    { return fmt.Sprintf("DR(%d)", a) }
+func (a InsertColumnAction) GoString() string { return fmt.Sprintf("IC(%d)", a) }
+func (a DeleteColumnAction) GoString() string { return fmt.Sprintf("DC(%d)", a) }
+
+func (a SetCellAction) GoString() string {
+	return fmt.Sprintf("S(%d, %d, %v)", a.Row, a.Column, a.Value)
+}
+
+// Expand returns a unchanged, since grid actions are already atomic.
+func (gh GridHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce merges consecutive sets of the same cell, of which only the later
+// one, b, has any effect.
+func (gh GridHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	as, aok := a.(SetCellAction)
+	bs, bok := b.(SetCellAction)
+	if !aok || !bok || as.Row != bs.Row || as.Column != bs.Column {
+		return nil, false
+	}
+	return b, true
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (gh GridHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var composed []cooperate.Action
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			next := it.Consume()
+			if !known(next) {
+				return nil, cooperate.ErrUnknownAction
+			}
+			composed, _ = gh.merge(composed, next)
+		}
+	}
+
+	return cooperate.Operation(composed), nil
+
+}
+
+// Transform implements cooperate.Transformer for the grid actions defined in
+// this package.
+//
+// Concurrent insertions and deletions of rows and columns shift the
+// coordinates of each other's actions, and sets of cells in a deleted row
+// or column are discarded. By default this implementation favors b:
+// concurrent insertions at the same index place b's row or column first, and
+// if both set the same cell, b's value wins. gh.Priority may favor a instead.
+func (gh GridHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	var aActions, bActions []cooperate.Action
+	for a.More() {
+		aActions = append(aActions, a.Consume())
+	}
+	for b.More() {
+		bActions = append(bActions, b.Consume())
+	}
+
+	// the left side wins conflicts
+	left, right := bActions, aActions
+	if gh.Priority == cooperate.FavorA {
+		left, right = aActions, bActions
+	}
+
+	for _, x := range append(append([]cooperate.Action(nil), left...), right...) {
+		if !known(x) {
+			return nil, nil, cooperate.ErrUnknownAction
+		}
+	}
+
+	leftPrime, rightPrime, err := json0.TransformSequences(left, right, transformAction, gh.merge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if gh.Priority == cooperate.FavorA {
+		return cooperate.Operation(leftPrime), cooperate.Operation(rightPrime), nil
+	}
+	return cooperate.Operation(rightPrime), cooperate.Operation(leftPrime), nil
+
+}
+
+func (gh GridHandler) merge(actions []cooperate.Action, a cooperate.Action) ([]cooperate.Action, error) {
+	if n := len(actions); n > 0 {
+		if reduced, ok := gh.Reduce(actions[n-1], a); ok {
+			actions[n-1] = reduced
+			return actions, nil
+		}
+	}
+	return append(actions, a), nil
+}
+
+// transformAction transforms c so that it applies after other. If c and
+// other conflict, c wins when isLeft.
+func transformAction(c, other cooperate.Action, isLeft bool) ([]cooperate.Action, error) {
+
+	switch c := c.(type) {
+
+	case InsertRowAction:
+		return []cooperate.Action{InsertRowAction(insertion(int(c), rowEffect(other), isLeft))}, nil
+
+	case DeleteRowAction:
+		if i, ok := index(int(c), rowEffect(other)); ok {
+			return []cooperate.Action{DeleteRowAction(i)}, nil
+		}
+
+	case InsertColumnAction:
+		return []cooperate.Action{InsertColumnAction(insertion(int(c), columnEffect(other), isLeft))}, nil
+
+	case DeleteColumnAction:
+		if i, ok := index(int(c), columnEffect(other)); ok {
+			return []cooperate.Action{DeleteColumnAction(i)}, nil
+		}
+
+	case SetCellAction:
+		if o, ok := other.(SetCellAction); ok && !isLeft && o.Row == c.Row && o.Column == c.Column {
+			return nil, nil
+		}
+		row, rok := index(c.Row, rowEffect(other))
+		column, cok := index(c.Column, columnEffect(other))
+		if rok && cok {
+			return []cooperate.Action{SetCellAction{Row: row, Column: column, Value: c.Value}}, nil
+		}
+
+	}
+
+	return nil, nil
+
+}
+
+// An effect describes how an action changes the indices along one axis: by
+// inserting at, or deleting, the index at.
+type effect struct {
+	at     int
+	insert bool
+	delete bool
+}
+
+func rowEffect(a cooperate.Action) effect {
+	switch a := a.(type) {
+	case InsertRowAction:
+		return effect{at: int(a), insert: true}
+	case DeleteRowAction:
+		return effect{at: int(a), delete: true}
+	}
+	return effect{}
+}
+
+func columnEffect(a cooperate.Action) effect {
+	switch a := a.(type) {
+	case InsertColumnAction:
+		return effect{at: int(a), insert: true}
+	case DeleteColumnAction:
+		return effect{at: int(a), delete: true}
+	}
+	return effect{}
+}
+
+// index returns where the row or column at i ends up after e, or false if
+// e deletes it.
+func index(i int, e effect) (int, bool) {
+	switch {
+	case e.insert && e.at <= i:
+		return i + 1, true
+	case e.delete && e.at == i:
+		return 0, false
+	case e.delete && e.at < i:
+		return i - 1, true
+	}
+	return i, true
+}
+
+// insertion returns where a row or column should be inserted after e to
+// have the effect of inserting it at i before. Insertions at the same index
+// as e are placed first if isLeft.
+func insertion(i int, e effect, isLeft bool) int {
+	switch {
+	case e.insert && (e.at < i || (e.at == i && !isLeft)):
+		return i + 1
+	case e.delete && e.at < i:
+		return i - 1
+	}
+	return i
+}
+
+func known(a cooperate.Action) bool {
+	switch a.(type) {
+	case InsertRowAction, DeleteRowAction, InsertColumnAction, DeleteColumnAction, SetCellAction:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package grid
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestCompose(t *testing.T) {
+
+	var gh GridHandler
+
+	a := cooperate.NewOperationIterator(cooperate.Operation{InsertRowAction(0), SetCellAction{Row: 0, Column: 0, Value: 1}})
+	b := cooperate.NewOperationIterator(cooperate.Operation{SetCellAction{Row: 0, Column: 0, Value: 2}, DeleteColumnAction(1)})
+
+	expected := cooperate.Operation{InsertRowAction(0), SetCellAction{Row: 0, Column: 0, Value: 2}, DeleteColumnAction(1)}
+
+	if sum, err := gh.Compose(a, b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(sum, expected) {
+		t.Errorf("unexpected composition: expected '%#v' but got '%#v'", expected, sum)
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	initial := [][]interface{}{{"a", "b"}, {"c", "d"}}
+
+	cases := []struct {
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       [][]interface{}
+	}{
+		// an inserted row shifts a concurrent set
+		{
+			A:        cooperate.Operation{SetCellAction{Row: 1, Column: 1, Value: "x"}},
+			B:        cooperate.Operation{InsertRowAction(0)},
+			APrime:   cooperate.Operation{SetCellAction{Row: 2, Column: 1, Value: "x"}},
+			BPrime:   cooperate.Operation{InsertRowAction(0)},
+			Expected: [][]interface{}{{nil, nil}, {"a", "b"}, {"c", "x"}},
+		},
+		// a set in a deleted column is discarded
+		{
+			A:        cooperate.Operation{SetCellAction{Row: 0, Column: 1, Value: "x"}, SetCellAction{Row: 1, Column: 0, Value: "y"}},
+			B:        cooperate.Operation{DeleteColumnAction(1)},
+			APrime:   cooperate.Operation{SetCellAction{Row: 1, Column: 0, Value: "y"}},
+			BPrime:   cooperate.Operation{DeleteColumnAction(1)},
+			Expected: [][]interface{}{{"a"}, {"y"}},
+		},
+		// concurrent insertions at the same index place b first
+		{
+			A:        cooperate.Operation{InsertColumnAction(1), SetCellAction{Row: 0, Column: 1, Value: "a"}},
+			B:        cooperate.Operation{InsertColumnAction(1), SetCellAction{Row: 0, Column: 1, Value: "b"}},
+			APrime:   cooperate.Operation{InsertColumnAction(2), SetCellAction{Row: 0, Column: 2, Value: "a"}},
+			BPrime:   cooperate.Operation{InsertColumnAction(1), SetCellAction{Row: 0, Column: 1, Value: "b"}},
+			Expected: [][]interface{}{{"a", "b", "a", "b"}, {"c", nil, nil, "d"}},
+		},
+		// concurrent deletions of the same row happen once
+		{
+			A:        cooperate.Operation{DeleteRowAction(0)},
+			B:        cooperate.Operation{DeleteRowAction(0)},
+			APrime:   nil,
+			BPrime:   nil,
+			Expected: [][]interface{}{{"c", "d"}},
+		},
+		// when both set the same cell, b wins
+		{
+			A:        cooperate.Operation{SetCellAction{Row: 0, Column: 0, Value: "x"}},
+			B:        cooperate.Operation{SetCellAction{Row: 0, Column: 0, Value: "y"}},
+			APrime:   nil,
+			BPrime:   cooperate.Operation{SetCellAction{Row: 0, Column: 0, Value: "y"}},
+			Expected: [][]interface{}{{"y", "b"}, {"c", "d"}},
+		},
+	}
+
+	for i, c := range cases {
+
+		aPrime, bPrime, err := GridHandler{}.Transform(cooperate.NewOperationIterator(c.A), cooperate.NewOperationIterator(c.B))
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewGridDocument(initial), NewGridDocument(initial)
+		for _, op := range []cooperate.Operation{c.A, bPrime} {
+			if err := ab.Apply(op); err != nil {
+				t.Errorf("[case %d] unexpected error applying %#v: %s", i, op, err)
+			}
+		}
+		for _, op := range []cooperate.Operation{c.B, aPrime} {
+			if err := ba.Apply(op); err != nil {
+				t.Errorf("[case %d] unexpected error applying %#v: %s", i, op, err)
+			}
+		}
+		if !reflect.DeepEqual(ab.Cells(), c.Expected) || !reflect.DeepEqual(ba.Cells(), c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%v' but got '%v' and '%v'", i, c.Expected, ab.Cells(), ba.Cells())
+		}
+	}
+
+}
+
+func TestServer(t *testing.T) {
+
+	doc := NewGridDocument([][]interface{}{{"a", "b"}})
+
+	s := &cooperate.Server{
+		Document:           doc,
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      GridHandler{},
+		ComposeTransformer: GridHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{InsertRowAction(1), SetCellAction{Row: 1, Column: 0, Value: "c"}}},
+		{Root: 0, Operation: cooperate.Operation{InsertColumnAction(0), SetCellAction{Row: 0, Column: 0, Value: "#"}}},
+		{Root: 1, Operation: cooperate.Operation{SetCellAction{Row: 1, Column: 1, Value: "d"}}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if expected := [][]interface{}{{"#", "a", "b"}, {nil, "c", "d"}}; !reflect.DeepEqual(doc.Cells(), expected) {
+		t.Errorf("unexpected document: expected '%v' but got '%v'", expected, doc.Cells())
+	}
+
+}
+
+var gridConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		cells := make([][]interface{}, r.Intn(3))
+		columns := r.Intn(3)
+		for i := range cells {
+			cells[i] = make([]interface{}, columns)
+		}
+		return NewGridDocument(cells)
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return GridHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		gd := doc.(*GridDocument)
+		rows, columns := gd.Rows(), gd.Columns()
+		var op cooperate.Operation
+		for n := 1 + r.Intn(3); len(op) < n; {
+			var a cooperate.Action
+			switch r.Intn(5) {
+			case 0:
+				a = InsertRowAction(r.Intn(rows + 1))
+			case 1:
+				a = InsertColumnAction(r.Intn(columns + 1))
+			case 2:
+				if rows > 0 {
+					a = DeleteRowAction(r.Intn(rows))
+				}
+			case 3:
+				if columns > 0 {
+					a = DeleteColumnAction(r.Intn(columns))
+				}
+			case 4:
+				if rows > 0 && columns > 0 {
+					a = SetCellAction{Row: r.Intn(rows), Column: r.Intn(columns), Value: r.Intn(100)}
+				}
+			}
+			if a != nil {
+				rows, columns, _ = resize(rows, columns, a)
+				op = append(op, a)
+			}
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		ga, gb := a.(*GridDocument), b.(*GridDocument)
+		if ga.Rows() != gb.Rows() || ga.Columns() != gb.Columns() {
+			return false
+		}
+		for i := 0; i < ga.Rows(); i++ {
+			for j := 0; j < ga.Columns(); j++ {
+				if ga.Cell(i, j) != gb.Cell(i, j) {
+					return false
+				}
+			}
+		}
+		return true
+	},
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, gridConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, gridConfig, 200)
+}
@@ -1,34 +1,101 @@
 package cooperate
 
+import (
+	"context"
+	"errors"
+)
+
+// ErrSeqnoOutOfRange indicates that a sequence number or range of sequence
+// numbers does not address operations in a history.
+var ErrSeqnoOutOfRange = errors.New("seqno out of range")
+
 type (
-	// History implements storage of a sequence of Operations.
-	History interface {
+	// HistoryOf implements storage of a sequence of operations.
+	HistoryOf[A any] interface {
 		// SequenceNumber returns the sequence number of the current state.
 		SequenceNumber() int
 
 		// Store appends an operation to the history, and returns its seqno.
-		Store(op Operation) (seqno int, err error)
+		Store(op OperationOf[A]) (seqno int, err error)
 
 		// Iterate traverses through all operations between startingSeqno and
 		// SequenceNumber() inclusive.
-		Iterate(startingSeqno int, cb func(seqno int, op Operation) error) error
+		Iterate(startingSeqno int, cb func(seqno int, op OperationOf[A]) error) error
+	}
+
+	// RangeHistoryOf is a HistoryOf that also supports random access and
+	// cancellable traversal of part of the history in either direction.
+	// Sequence numbers are those passed by Iterate.
+	RangeHistoryOf[A any] interface {
+		HistoryOf[A]
+
+		// Get returns the operation numbered seqno.
+		Get(ctx context.Context, seqno int) (OperationOf[A], error)
+
+		// Range traverses the operations numbered from from up to but not
+		// including to, in order. It stops with ctx.Err() if ctx is done.
+		Range(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error
+
+		// Reverse is like Range, but traverses the operations in reverse
+		// order, starting with the one numbered to-1.
+		Reverse(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error
 	}
 
-	// MemoryHistory is the simplest possible History implementation, storing
-	// a sequence of Operations in an in-memory slice.
-	MemoryHistory []Operation
+	// SnapshotHistoryOf is a HistoryOf that can also store snapshots of the
+	// document, encoded by a SnapshotterOf, so that loading the document
+	// need not replay its whole history.
+	SnapshotHistoryOf[A any] interface {
+		HistoryOf[A]
+
+		// StoreSnapshot is like Store, but also stores snapshot as the state
+		// of the document after op, atomically.
+		StoreSnapshot(op OperationOf[A], snapshot []byte) (seqno int, err error)
+	}
+
+	// A SequenceNumberReader is a history that may fail to read its
+	// sequence number, such as one kept in a database.
+	SequenceNumberReader interface {
+		// ReadSequenceNumber is like SequenceNumber, but returns the error
+		// if the sequence number cannot be read.
+		ReadSequenceNumber() (int, error)
+	}
+
+	// MemoryHistoryOf is the simplest possible HistoryOf implementation,
+	// storing a sequence of operations in an in-memory slice.
+	MemoryHistoryOf[A any] []OperationOf[A]
+
+	// History is a HistoryOf untyped actions.
+	History = HistoryOf[Action]
+
+	// MemoryHistory is a MemoryHistoryOf untyped actions.
+	MemoryHistory = MemoryHistoryOf[Action]
+
+	// RangeHistory is a RangeHistoryOf untyped actions.
+	RangeHistory = RangeHistoryOf[Action]
+
+	// SnapshotHistory is a SnapshotHistoryOf untyped actions.
+	SnapshotHistory = SnapshotHistoryOf[Action]
 )
 
-func (mh *MemoryHistory) SequenceNumber() int {
+// sequenceNumber returns the sequence number of h, along with the error
+// reading it if h is a SequenceNumberReader.
+func sequenceNumber[A any](h HistoryOf[A]) (int, error) {
+	if r, ok := h.(SequenceNumberReader); ok {
+		return r.ReadSequenceNumber()
+	}
+	return h.SequenceNumber(), nil
+}
+
+func (mh *MemoryHistoryOf[A]) SequenceNumber() int {
 	return len(*mh)
 }
 
-func (mh *MemoryHistory) Store(op Operation) (int, error) {
+func (mh *MemoryHistoryOf[A]) Store(op OperationOf[A]) (int, error) {
 	*mh = append(*mh, op)
 	return mh.SequenceNumber(), nil
 }
 
-func (mh *MemoryHistory) Iterate(startingSeqno int, cb func(seqno int, op Operation) error) error {
+func (mh *MemoryHistoryOf[A]) Iterate(startingSeqno int, cb func(seqno int, op OperationOf[A]) error) error {
 	for i := startingSeqno; i < len(*mh); i++ {
 		if err := cb(i, (*mh)[i]); err != nil {
 			return err
@@ -36,3 +103,119 @@ func (mh *MemoryHistory) Iterate(startingSeqno int, cb func(seqno int, op Operat
 	}
 	return nil
 }
+
+func (mh *MemoryHistoryOf[A]) Get(ctx context.Context, seqno int) (OperationOf[A], error) {
+	if seqno < 0 || seqno >= len(*mh) {
+		return nil, ErrSeqnoOutOfRange
+	}
+	return (*mh)[seqno], nil
+}
+
+func (mh *MemoryHistoryOf[A]) Range(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error {
+	if from < 0 || to < from || to > len(*mh) {
+		return ErrSeqnoOutOfRange
+	}
+	for i := from; i < to; i++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := cb(i, (*mh)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (mh *MemoryHistoryOf[A]) Reverse(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error {
+	if from < 0 || to < from || to > len(*mh) {
+		return ErrSeqnoOutOfRange
+	}
+	for i := to - 1; i >= from; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := cb(i, (*mh)[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NewRangeHistory returns h as a RangeHistoryOf. If h does not implement
+// RangeHistoryOf itself, the returned history implements it using Iterate,
+// which may be slow: Get and Range read the history from the first
+// operation they need to the last, and Reverse holds the operations of its
+// range in memory.
+func NewRangeHistory[A any](h HistoryOf[A]) RangeHistoryOf[A] {
+	if rh, ok := h.(RangeHistoryOf[A]); ok {
+		return rh
+	}
+	return rangeHistory[A]{h}
+}
+
+type rangeHistory[A any] struct {
+	HistoryOf[A]
+}
+
+// errStop ends an Iterate early.
+var errStop = errors.New("stop")
+
+func (rh rangeHistory[A]) Get(ctx context.Context, seqno int) (OperationOf[A], error) {
+	var op OperationOf[A]
+	err := rh.Range(ctx, seqno, seqno+1, func(_ int, o OperationOf[A]) error {
+		op = o
+		return nil
+	})
+	return op, err
+}
+
+func (rh rangeHistory[A]) Range(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error {
+
+	seqno, err := sequenceNumber[A](rh.HistoryOf)
+	if err != nil {
+		return err
+	}
+	if from < 0 || to < from || to > seqno {
+		return ErrSeqnoOutOfRange
+	}
+	if from == to {
+		return nil
+	}
+
+	err = rh.Iterate(from, func(seqno int, op OperationOf[A]) error {
+		if seqno >= to {
+			return errStop
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		return cb(seqno, op)
+	})
+	if err == errStop {
+		return nil
+	}
+	return err
+
+}
+
+func (rh rangeHistory[A]) Reverse(ctx context.Context, from, to int, cb func(seqno int, op OperationOf[A]) error) error {
+
+	var ops []OperationOf[A]
+	if err := rh.Range(ctx, from, to, func(seqno int, op OperationOf[A]) error {
+		ops = append(ops, op)
+		return nil
+	}); err != nil {
+		return err
+	}
+
+	for i := len(ops) - 1; i >= 0; i-- {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := cb(from+i, ops[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+
+}
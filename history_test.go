@@ -0,0 +1,98 @@
+package cooperate_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+// iterateOnly hides all but the methods of cooperate.History, so that
+// NewRangeHistory must adapt it.
+type iterateOnly struct {
+	cooperate.History
+}
+
+func TestRangeHistory(t *testing.T) {
+
+	mh := &cooperate.MemoryHistory{}
+	for _, s := range []string{"a", "b", "c", "d"} {
+		mh.Store(cooperate.Operation{text.InsertAction(s)})
+	}
+
+	histories := map[string]cooperate.RangeHistory{
+		"memory":  cooperate.NewRangeHistory[cooperate.Action](mh),
+		"adapter": cooperate.NewRangeHistory[cooperate.Action](iterateOnly{mh}),
+	}
+
+	cases := []struct {
+		From, To int
+		Reverse  bool
+		Expected []int
+		Error    error
+	}{
+		{From: 0, To: 4, Expected: []int{0, 1, 2, 3}},
+		{From: 1, To: 3, Expected: []int{1, 2}},
+		{From: 1, To: 3, Reverse: true, Expected: []int{2, 1}},
+		{From: 0, To: 4, Reverse: true, Expected: []int{3, 2, 1, 0}},
+		{From: 2, To: 2, Expected: nil},
+		{From: 2, To: 5, Error: cooperate.ErrSeqnoOutOfRange},
+		{From: 3, To: 2, Reverse: true, Error: cooperate.ErrSeqnoOutOfRange},
+	}
+
+	for name, h := range histories {
+
+		if _, ok := h.(*cooperate.MemoryHistory); ok != (name == "memory") {
+			t.Errorf("[%s] unexpected adaptation", name)
+		}
+
+		for i, c := range cases {
+
+			traverse := h.Range
+			if c.Reverse {
+				traverse = h.Reverse
+			}
+
+			var seqnos []int
+			err := traverse(context.Background(), c.From, c.To, func(seqno int, op cooperate.Operation) error {
+				if expected := (*mh)[seqno]; !reflect.DeepEqual(op, expected) {
+					t.Errorf("[%s case %d] unexpected operation %d: expected %#v but got %#v", name, i, seqno, expected, op)
+				}
+				seqnos = append(seqnos, seqno)
+				return nil
+			})
+			if err != c.Error {
+				t.Errorf("[%s case %d] unexpected error: expected '%v' but got '%v'", name, i, c.Error, err)
+			} else if !reflect.DeepEqual(seqnos, c.Expected) {
+				t.Errorf("[%s case %d] unexpected seqnos: expected %v but got %v", name, i, c.Expected, seqnos)
+			}
+		}
+
+		if op, err := h.Get(context.Background(), 2); err != nil || !reflect.DeepEqual(op, (*mh)[2]) {
+			t.Errorf("[%s] unexpected result from Get: %#v, %v", name, op, err)
+		}
+		if _, err := h.Get(context.Background(), 4); err != cooperate.ErrSeqnoOutOfRange {
+			t.Errorf("[%s] unexpected error: expected '%v' but got '%v'", name, cooperate.ErrSeqnoOutOfRange, err)
+		}
+
+		// a callback's error and cancellation both stop the traversal
+		stop := errors.New("stop")
+		ctx, cancel := context.WithCancel(context.Background())
+		var calls int
+		err := h.Range(ctx, 0, 4, func(seqno int, op cooperate.Operation) error {
+			calls++
+			cancel()
+			return nil
+		})
+		if err != context.Canceled || calls != 1 {
+			t.Errorf("[%s] unexpected result after cancellation: %d calls, %v", name, calls, err)
+		}
+		if err := h.Reverse(context.Background(), 0, 4, func(int, cooperate.Operation) error { return stop }); err != stop {
+			t.Errorf("[%s] unexpected error: expected '%v' but got '%v'", name, stop, err)
+		}
+	}
+
+}
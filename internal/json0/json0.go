@@ -0,0 +1,122 @@
+// Package json0 implements the parts of ShareDB's json0 transformation
+// shared by the json, tree and grid documents, whose operations are lists of
+// actions applied one after another and which embed text edits.
+package json0
+
+import (
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+// TransformSequences implements transform for operations whose actions are
+// applied one after another, like those of a json0 document, rather than as
+// a single pass over the document.
+//
+// transform must transform the single action c so that it applies after
+// other, and return its replacement actions. If c and other conflict, c
+// should win when isLeft. merge appends an action to a list of actions,
+// perhaps combining it with the last one; if nil, actions are simply
+// appended.
+//
+// It returns left and right transformed against each other, such that
+// applying left then right' is equivalent to applying right then left'.
+func TransformSequences[A any](left, right []A, transform func(c, other A, isLeft bool) ([]A, error), merge func(actions []A, a A) ([]A, error)) (leftPrime, rightPrime []A, err error) {
+
+	if merge == nil {
+		merge = func(actions []A, a A) ([]A, error) {
+			return append(actions, a), nil
+		}
+	}
+
+	mergeAll := func(actions []A, as []A) ([]A, error) {
+		for _, a := range as {
+			var err error
+			if actions, err = merge(actions, a); err != nil {
+				return nil, err
+			}
+		}
+		return actions, nil
+	}
+
+	for _, r := range right {
+
+		var newLeft []A
+		rc, hasR := r, true
+
+		for k := 0; k < len(left); k++ {
+
+			l, err := transform(left[k], rc, true)
+			if err != nil {
+				return nil, nil, err
+			}
+			if newLeft, err = mergeAll(newLeft, l); err != nil {
+				return nil, nil, err
+			}
+
+			next, err := transform(rc, left[k], false)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if len(next) == 1 {
+				rc = next[0]
+				continue
+			}
+
+			// r was either dropped, in which case the rest of left applies
+			// unchanged, or split, in which case the rest of left must be
+			// transformed against each of its parts
+			if len(next) == 0 {
+				if newLeft, err = mergeAll(newLeft, left[k+1:]); err != nil {
+					return nil, nil, err
+				}
+			} else {
+				l, r, err := TransformSequences(left[k+1:], next, transform, merge)
+				if err != nil {
+					return nil, nil, err
+				}
+				if newLeft, err = mergeAll(newLeft, l); err != nil {
+					return nil, nil, err
+				}
+				if rightPrime, err = mergeAll(rightPrime, r); err != nil {
+					return nil, nil, err
+				}
+			}
+
+			hasR = false
+			break
+
+		}
+
+		if hasR {
+			if rightPrime, err = merge(rightPrime, rc); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		left = newLeft
+
+	}
+
+	return left, rightPrime, nil
+
+}
+
+// ComposeText composes the text operations a and b embedded in a document.
+func ComposeText(a, b cooperate.Operation) (cooperate.Operation, error) {
+	var th text.TextHandler
+	return th.Compose(
+		cooperate.NewOperationIterator(cooperate.Expand(th, a)),
+		cooperate.NewOperationIterator(cooperate.Expand(th, b)),
+	)
+}
+
+// TransformText transforms the text operations a and b embedded in a
+// document, favoring b.
+func TransformText(a, b cooperate.Operation) (aa, bb cooperate.Operation, err error) {
+	var th text.TextHandler
+	return th.Transform(
+		cooperate.NewOperationIterator(cooperate.Expand(th, a)),
+		cooperate.NewOperationIterator(cooperate.Expand(th, b)),
+	)
+}
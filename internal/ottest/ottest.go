@@ -0,0 +1,249 @@
+// Package ottest checks the handlers of document types with random
+// operations: that transformed operations converge, and that clients and
+// a server exchanging them do too.
+package ottest
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// A Handler implements the core OT operations of a document type.
+type Handler[A any] interface {
+	cooperate.ExpandReducerOf[A]
+	cooperate.ComposeTransformerOf[A]
+}
+
+// A Config describes a document type to check.
+type Config[A any] struct {
+	// NewDocument returns a document to start from, which must be the same
+	// for sources of randomness in the same state.
+	NewDocument func(r *rand.Rand) cooperate.DocumentOf[A]
+
+	// Handler returns a handler favoring p.
+	Handler func(p cooperate.Priority) Handler[A]
+
+	// RandomOperation returns a random operation that applies to doc,
+	// without changing doc.
+	RandomOperation func(r *rand.Rand, doc cooperate.DocumentOf[A]) cooperate.OperationOf[A]
+
+	// Equal reports whether two documents are the same. It defaults to
+	// reflect.DeepEqual.
+	Equal func(a, b cooperate.DocumentOf[A]) bool
+}
+
+func (c Config[A]) equal(a, b cooperate.DocumentOf[A]) bool {
+	if c.Equal != nil {
+		return c.Equal(a, b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Transform checks n times, with either priority, that applying random
+// operations a then b' to a document has the same effect as applying b then
+// a', where a' and b' are a and b transformed against each other.
+func Transform[A any](t *testing.T, c Config[A], n int) {
+
+	t.Helper()
+
+	for seed := int64(0); seed < int64(n); seed++ {
+
+		r := rand.New(rand.NewSource(seed))
+		ab, ba := c.NewDocument(rand.New(rand.NewSource(seed))), c.NewDocument(r)
+
+		// reach a random state of the document
+		for i := r.Intn(4); i > 0; i-- {
+			op := c.RandomOperation(r, ab)
+			if err := ab.Apply(op); err != nil {
+				t.Fatalf("[seed %d] unexpected error applying %#v: %s", seed, op, err)
+			}
+			if err := ba.Apply(op); err != nil {
+				t.Fatalf("[seed %d] unexpected error applying %#v: %s", seed, op, err)
+			}
+		}
+
+		a, b := c.RandomOperation(r, ab), c.RandomOperation(r, ab)
+
+		p := cooperate.Priority(r.Intn(2))
+		h := c.Handler(p)
+		aPrime, bPrime, err := h.Transform(
+			cooperate.NewOperationIterator(cooperate.Expand[A](h, a)),
+			cooperate.NewOperationIterator(cooperate.Expand[A](h, b)))
+		if err != nil {
+			t.Fatalf("[seed %d] unexpected error transforming a:%#v and b:%#v: %s", seed, a, b, err)
+		}
+
+		for _, op := range []cooperate.OperationOf[A]{a, bPrime} {
+			if err := ab.Apply(op); err != nil {
+				t.Fatalf("[seed %d] unexpected error applying %#v after a:%#v and b:%#v: %s", seed, op, a, b, err)
+			}
+		}
+		for _, op := range []cooperate.OperationOf[A]{b, aPrime} {
+			if err := ba.Apply(op); err != nil {
+				t.Fatalf("[seed %d] unexpected error applying %#v after a:%#v and b:%#v: %s", seed, op, a, b, err)
+			}
+		}
+
+		if !c.equal(ab, ba) {
+			t.Fatalf("[seed %d] documents diverged transforming a:%#v and b:%#v with priority %d: %v then %v", seed, a, b, p, ab, ba)
+		}
+
+	}
+
+}
+
+// Converge has three clients edit a document at random, n times, exchanging
+// operations with a server over connections that deliver messages in order
+// but at random times, and checks that they all converge. The server favors
+// b and the clients favor a.
+func Converge[A any](t *testing.T, c Config[A], n int) {
+
+	t.Helper()
+
+	for seed := int64(0); seed < int64(n); seed++ {
+		if !converge(t, c, seed) {
+			t.Fatalf("[seed %d] failed", seed)
+		}
+	}
+
+}
+
+func converge[A any](t *testing.T, c Config[A], seed int64) bool {
+
+	t.Helper()
+
+	r := rand.New(rand.NewSource(seed))
+	newDocument := func() cooperate.DocumentOf[A] {
+		return c.NewDocument(rand.New(rand.NewSource(seed)))
+	}
+
+	s := &cooperate.ServerOf[A]{
+		Document:           newDocument(),
+		History:            &cooperate.MemoryHistoryOf[A]{},
+		ExpandReducer:      c.Handler(cooperate.FavorB),
+		ComposeTransformer: c.Handler(cooperate.FavorB),
+	}
+
+	// a message to a client is either an operation committed by another
+	// client, or an acknowledgement of its own
+	type message struct {
+		ack bool
+		op  cooperate.OperationOf[A]
+	}
+
+	type peer struct {
+		client *cooperate.ClientOf[A]
+		inbox  []message
+	}
+
+	type proposal struct {
+		from int
+		root int
+		op   cooperate.OperationOf[A]
+	}
+
+	var peers []*peer
+	for i := 0; i < 3; i++ {
+		peers = append(peers, &peer{client: &cooperate.ClientOf[A]{
+			Document:           newDocument(),
+			ExpandReducer:      c.Handler(cooperate.FavorA),
+			ComposeTransformer: c.Handler(cooperate.FavorA),
+		}})
+	}
+
+	var proposals []proposal
+	propose := func(from int) {
+		if c := peers[from].client; c.InFlight != nil {
+			proposals = append(proposals, proposal{from: from, root: c.Revision, op: c.InFlight})
+		}
+	}
+
+	commit := func() bool {
+		p := proposals[0]
+		proposals = proposals[1:]
+		if err := s.Apply(p.root, p.op); err != nil {
+			t.Errorf("unexpected error committing %#v: %s", p.op, err)
+			return false
+		}
+		var committed cooperate.OperationOf[A]
+		s.History.Iterate(s.History.SequenceNumber()-1, func(_ int, op cooperate.OperationOf[A]) error {
+			committed = op
+			return nil
+		})
+		for i, peer := range peers {
+			peer.inbox = append(peer.inbox, message{ack: i == p.from, op: committed})
+		}
+		return true
+	}
+
+	receive := func(i int) bool {
+		peer := peers[i]
+		msg := peer.inbox[0]
+		peer.inbox = peer.inbox[1:]
+		if msg.ack {
+			if err := peer.client.Acknowledge(); err != nil {
+				t.Errorf("unexpected error acknowledging client %d: %s", i, err)
+				return false
+			}
+			propose(i)
+		} else if err := peer.client.ApplyReceived(msg.op); err != nil {
+			t.Errorf("unexpected error applying %#v to client %d: %s", msg.op, i, err)
+			return false
+		}
+		return true
+	}
+
+	for step := 0; step < 60; step++ {
+		i := r.Intn(len(peers))
+		switch client := peers[i].client; r.Intn(3) {
+		case 0:
+			idle := client.InFlight == nil
+			op := c.RandomOperation(r, client.Document)
+			if err := client.ApplyLocal(op); err != nil {
+				t.Errorf("unexpected error applying %#v to client %d: %s", op, i, err)
+				return false
+			}
+			if idle {
+				propose(i)
+			}
+		case 1:
+			if len(proposals) > 0 && !commit() {
+				return false
+			}
+		case 2:
+			if len(peers[i].inbox) > 0 && !receive(i) {
+				return false
+			}
+		}
+	}
+
+	// deliver everything still in transit
+	for pending := true; pending; {
+		pending = false
+		for len(proposals) > 0 {
+			if !commit() {
+				return false
+			}
+		}
+		for i, peer := range peers {
+			for len(peer.inbox) > 0 {
+				if !receive(i) {
+					return false
+				}
+				pending = true
+			}
+		}
+	}
+
+	for i, peer := range peers {
+		if !c.equal(s.Document, peer.client.Document) {
+			t.Errorf("client %d diverged: server has %v and client has %v", i, s.Document, peer.client.Document)
+			return false
+		}
+	}
+	return true
+
+}
@@ -0,0 +1,204 @@
+// Package runlength implements Compose and Transform for sequence documents
+// whose operations are made of retains, inserts and deletes, such as the
+// text and bytes documents.
+//
+// The algorithms work on expanded operations, in which each action affects a
+// single element, so they need to know only the kind of each action.
+package runlength
+
+import (
+	"github.com/tylerchr/cooperate"
+)
+
+// A Kind identifies whether an action retains, inserts or deletes.
+type Kind int
+
+const (
+	Unknown Kind = iota
+	Retain
+	Insert
+	Delete
+)
+
+// An Alphabet describes the retain, insert and delete actions of a sequence
+// document.
+type Alphabet[A any] interface {
+	cooperate.ExpandReducerOf[A]
+
+	// Kind returns the kind of a, or Unknown if a is not one of the actions
+	// of the alphabet.
+	Kind(a A) Kind
+
+	// Retain returns an action that retains n elements.
+	Retain(n int) A
+
+	// Matches reports whether the insert action and the delete action act
+	// on the same contents.
+	Matches(insert, del A) bool
+}
+
+// peek returns the kind of the foremost action of oit, or Unknown if none
+// remain.
+func peek[A any](ab Alphabet[A], oit *cooperate.OperationIteratorOf[A]) Kind {
+	if oit.More() {
+		return ab.Kind(oit.Peek())
+	}
+	return Unknown
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+//
+// Compose panics with cooperate.ErrDeleteMismatch if b deletes contents
+// other than what a inserted.
+func Compose[A any](ab Alphabet[A], a, b *cooperate.OperationIteratorOf[A]) (cooperate.OperationOf[A], error) {
+
+	var composedActions []A // new list of actions
+
+ComposeLoop:
+	for {
+
+		ak, bk := peek(ab, a), peek(ab, b)
+
+		switch {
+
+		case (a.More() && ak == Unknown) || (b.More() && bk == Unknown):
+			return nil, cooperate.ErrUnknownAction
+
+		// what a deletes is not seen by b, and what b inserts was not seen by
+		// a, so these pass through unchanged
+		case ak == Delete:
+			composedActions = append(composedActions, a.Consume())
+
+		case bk == Insert:
+			composedActions = append(composedActions, b.Consume())
+
+		// if we are out of actions from either, we are finished
+		case !a.More() || !b.More():
+			break ComposeLoop
+
+		case ak == Insert && bk == Delete:
+			if !ab.Matches(a.Peek(), b.Peek()) {
+				panic(cooperate.ErrDeleteMismatch)
+			}
+			a.Consume()
+			b.Consume()
+
+		case ak == Insert && bk == Retain:
+			composedActions = append(composedActions, a.Consume())
+			b.Consume()
+
+		case ak == Retain && bk == Delete:
+			a.Consume()
+			composedActions = append(composedActions, b.Consume())
+
+		case ak == Retain && bk == Retain:
+			composedActions = append(composedActions, a.Consume())
+			b.Consume()
+		}
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce[A](ab, cooperate.OperationOf[A](composedActions)), nil
+}
+
+// Transform implements cooperate.Transformer for the actions of ab.
+//
+// This implementation favors b; that is, if a and b both act on the
+// same element, the effect is as though b's intended change was applied first.
+// If aFirst is set, insertions of a at the same location as insertions of b
+// are placed first instead.
+func Transform[A any](ab Alphabet[A], a, b *cooperate.OperationIteratorOf[A], aFirst bool) (aa, bb cooperate.OperationOf[A], err error) {
+
+	var aPrime, bPrime []A // new list of actions
+
+	retain1 := ab.Retain(1)
+
+TransformLoop:
+	for {
+
+		ak, bk := peek(ab, a), peek(ab, b)
+
+		switch {
+
+		// if we reach the ends at the same time, we are done
+		case !a.More() && !b.More():
+			break TransformLoop
+
+		case (a.More() && ak == Unknown) || (b.More() && bk == Unknown):
+			return nil, nil, cooperate.ErrUnknownAction
+
+		// if we are at the end of b but not a, then a must contain only inserts
+		case a.More() && !b.More():
+			if ak == Insert {
+				aPrime = append(aPrime, a.Consume())
+				bPrime = append(bPrime, retain1)
+				continue
+			}
+			break TransformLoop
+
+		// if we are at the end of a but not b, then b must contain only inserts
+		case !a.More() && b.More():
+			if bk == Insert {
+				aPrime = append(aPrime, retain1)
+				bPrime = append(bPrime, b.Consume())
+				continue
+			}
+			break TransformLoop
+
+		case ak == Insert && bk == Insert && aFirst:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, retain1)
+
+		case ak == Insert && bk == Insert:
+			aPrime = append(aPrime, retain1)
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == Insert && bk == Delete:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, retain1)
+
+		case ak == Insert && bk == Retain:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, retain1)
+
+		case ak == Delete && bk == Insert:
+			aPrime = append(aPrime, retain1)
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == Delete && bk == Delete:
+			a.Consume()
+			b.Consume()
+
+		case ak == Delete && bk == Retain:
+			aPrime = append(aPrime, a.Consume())
+			b.Consume()
+
+		case ak == Retain && bk == Insert:
+			aPrime = append(aPrime, retain1)
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == Retain && bk == Delete:
+			a.Consume()
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == Retain && bk == Retain:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, b.Consume())
+
+		}
+
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce[A](ab, cooperate.OperationOf[A](aPrime)), cooperate.Reduce[A](ab, cooperate.OperationOf[A](bPrime)), nil
+
+}
@@ -0,0 +1,232 @@
+package json
+
+import (
+	encjson "encoding/json"
+	"errors"
+	"reflect"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+var (
+	// ErrInvalidPath indicates that an action's path does not address a
+	// location that exists in the document.
+	ErrInvalidPath = errors.New("invalid path")
+
+	// ErrTypeMismatch indicates that an action was applied to a value of the
+	// wrong type, such as a list action addressing an object.
+	ErrTypeMismatch = errors.New("type mismatch")
+)
+
+// A JSONDocument is an arbitrary JSON value that implements the
+// cooperate.Document interface.
+//
+// Values are represented the same way encoding/json decodes into an empty
+// interface: map[string]interface{}, []interface{}, float64, string, bool
+// and nil.
+type JSONDocument struct {
+	contents interface{}
+}
+
+// NewJSONDocument initializes a JSONDocument with a starting value of initial.
+func NewJSONDocument(initial interface{}) *JSONDocument {
+	return &JSONDocument{
+		contents: clone(initial),
+	}
+}
+
+// Value returns the current contents of the document. The returned value
+// must not be modified.
+func (jd *JSONDocument) Value() interface{} {
+	return jd.contents
+}
+
+// MarshalJSON encodes the current contents of the document.
+func (jd *JSONDocument) MarshalJSON() ([]byte, error) {
+	return encjson.Marshal(jd.contents)
+}
+
+// UnmarshalJSON replaces the contents of the document with the decoded data.
+func (jd *JSONDocument) UnmarshalJSON(data []byte) error {
+	return encjson.Unmarshal(data, &jd.contents)
+}
+
+// Apply performs op against the JSONDocument. The actions of op are applied
+// in order; if any of them fails, the document is left unchanged.
+func (jd *JSONDocument) Apply(op cooperate.Operation) error {
+
+	components, err := toComponents(op)
+	if err != nil {
+		return err
+	}
+
+	contents := clone(jd.contents)
+	for _, c := range components {
+		if contents, err = applyComponent(contents, c); err != nil {
+			return err
+		}
+	}
+
+	jd.contents = contents
+	return nil
+
+}
+
+// applyComponent performs c against v and returns the resulting value. It
+// may modify v in place.
+func applyComponent(v interface{}, c component) (interface{}, error) {
+
+	// number and text edits act on the addressed value itself
+	if c.hasNa || c.hasT {
+		return update(v, c.p, func(v interface{}) (interface{}, error) {
+			if c.hasNa {
+				n, ok := v.(float64)
+				if !ok {
+					return nil, ErrTypeMismatch
+				}
+				return n + c.na, nil
+			}
+			s, ok := v.(string)
+			if !ok {
+				return nil, ErrTypeMismatch
+			}
+			td := text.NewTextDocument(s)
+			if err := td.Apply(c.t); err != nil {
+				return nil, err
+			}
+			return td.String(), nil
+		})
+	}
+
+	if len(c.p) == 0 {
+		return nil, ErrInvalidPath
+	}
+
+	// everything else acts on the container of the addressed value
+	parent, last := c.p[:len(c.p)-1], c.p[len(c.p)-1]
+	return update(v, parent, func(v interface{}) (interface{}, error) {
+		switch v := v.(type) {
+
+		case map[string]interface{}:
+			key, ok := last.(string)
+			if !ok {
+				return nil, ErrInvalidPath
+			}
+			if c.hasOd {
+				if existing, ok := v[key]; !ok || !reflect.DeepEqual(existing, c.od) {
+					return nil, cooperate.ErrDeleteMismatch
+				}
+				delete(v, key)
+			}
+			if c.hasOi {
+				v[key] = clone(c.oi)
+			}
+			if c.hasLi || c.hasLd || c.hasLm {
+				return nil, ErrTypeMismatch
+			}
+			return v, nil
+
+		case []interface{}:
+			i, ok := last.(int)
+			if !ok || i < 0 {
+				return nil, ErrInvalidPath
+			}
+			if c.hasLd {
+				if i >= len(v) {
+					return nil, ErrInvalidPath
+				} else if !reflect.DeepEqual(v[i], c.ld) {
+					return nil, cooperate.ErrDeleteMismatch
+				}
+				v = append(v[:i], v[i+1:]...)
+			}
+			if c.hasLi {
+				if i > len(v) {
+					return nil, ErrInvalidPath
+				}
+				v = append(v, nil)
+				copy(v[i+1:], v[i:])
+				v[i] = clone(c.li)
+			}
+			if c.hasLm {
+				if i >= len(v) || c.lm < 0 || c.lm >= len(v) {
+					return nil, ErrInvalidPath
+				}
+				moved := v[i]
+				v = append(v[:i], v[i+1:]...)
+				v = append(v, nil)
+				copy(v[c.lm+1:], v[c.lm:])
+				v[c.lm] = moved
+			}
+			if c.hasOi || c.hasOd {
+				return nil, ErrTypeMismatch
+			}
+			return v, nil
+
+		}
+		return nil, ErrTypeMismatch
+	})
+
+}
+
+// update replaces the value at path p within v with the result of f.
+func update(v interface{}, p Path, f func(interface{}) (interface{}, error)) (interface{}, error) {
+
+	if len(p) == 0 {
+		return f(v)
+	}
+
+	switch container := v.(type) {
+
+	case map[string]interface{}:
+		key, ok := p[0].(string)
+		if !ok {
+			return nil, ErrInvalidPath
+		}
+		child, ok := container[key]
+		if !ok {
+			return nil, ErrInvalidPath
+		}
+		updated, err := update(child, p[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		container[key] = updated
+		return container, nil
+
+	case []interface{}:
+		i, ok := p[0].(int)
+		if !ok || i < 0 || i >= len(container) {
+			return nil, ErrInvalidPath
+		}
+		updated, err := update(container[i], p[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		container[i] = updated
+		return container, nil
+
+	}
+
+	return nil, ErrInvalidPath
+
+}
+
+// clone returns a deep copy of the JSON value v.
+func clone(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, x := range v {
+			m[k] = clone(x)
+		}
+		return m
+	case []interface{}:
+		l := make([]interface{}, len(v))
+		for i, x := range v {
+			l[i] = clone(x)
+		}
+		return l
+	}
+	return v
+}
@@ -0,0 +1,79 @@
+package json
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestJSONDocument(t *testing.T) {
+
+	cases := []struct {
+		ExistingContents interface{}
+		Operation        cooperate.Operation
+		ExpectedError    error
+		ExpectedContents interface{}
+	}{
+		{
+			ExistingContents: map[string]interface{}{},
+			Operation: cooperate.Operation([]cooperate.Action{
+				ObjectInsertAction{Path: Path{"name"}, Value: "cooperate"},
+			}),
+			ExpectedContents: map[string]interface{}{"name": "cooperate"},
+		},
+		{
+			ExistingContents: map[string]interface{}{"name": "cooperate", "stars": 1.0},
+			Operation: cooperate.Operation([]cooperate.Action{
+				ObjectReplaceAction{Path: Path{"name"}, Old: "cooperate", New: "ot"},
+				NumberAddAction{Path: Path{"stars"}, Amount: 2},
+			}),
+			ExpectedContents: map[string]interface{}{"name": "ot", "stars": 3.0},
+		},
+		{
+			ExistingContents: map[string]interface{}{"tags": []interface{}{"a", "b", "c"}},
+			Operation: cooperate.Operation([]cooperate.Action{
+				ListInsertAction{Path: Path{"tags", 1}, Value: "x"},
+				ListDeleteAction{Path: Path{"tags", 3}, Value: "c"},
+				ListMoveAction{Path: Path{"tags", 0}, To: 2},
+			}),
+			ExpectedContents: map[string]interface{}{"tags": []interface{}{"x", "b", "a"}},
+		},
+		{
+			ExistingContents: []interface{}{"spar"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				TextAction{Path: Path{0}, Op: cooperate.Operation{text.RetainAction(1), text.DeleteAction("p"), text.RetainAction(2)}},
+			}),
+			ExpectedContents: []interface{}{"sar"},
+		},
+		{
+			ExistingContents: map[string]interface{}{"name": "cooperate"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				ObjectDeleteAction{Path: Path{"name"}, Value: "ot"},
+			}),
+			ExpectedError:    cooperate.ErrDeleteMismatch,
+			ExpectedContents: map[string]interface{}{"name": "cooperate"},
+		},
+		{
+			ExistingContents: map[string]interface{}{"name": "cooperate"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				ListInsertAction{Path: Path{"name", 0}, Value: "x"},
+			}),
+			ExpectedError:    ErrTypeMismatch,
+			ExpectedContents: map[string]interface{}{"name": "cooperate"},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewJSONDocument(c.ExistingContents)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Value(), c.ExpectedContents) {
+			t.Errorf("[case %d] unexpected document: expected '%v' but got '%v'", i, c.ExpectedContents, doc.Value())
+		}
+	}
+
+}
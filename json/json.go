@@ -0,0 +1,668 @@
+// Package json implements collaborative editing of arbitrary JSON values.
+//
+// Its actions and transformation rules follow ShareDB's json0 type: every
+// action addresses a location in the document by path, and an operation is
+// a list of actions applied one after another.
+package json
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/json0"
+)
+
+type (
+	// A JSONHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the actions defined in this package.
+	JSONHandler struct {
+		// Priority decides whose change wins when a and b conflict.
+		Priority cooperate.Priority
+	}
+
+	// A Path addresses a value within a JSON document. Each element is either
+	// a string naming an object key or an int indexing into a list.
+	Path []interface{}
+
+	// ObjectInsertAction sets the key at Path, which must not already exist,
+	// to Value.
+	ObjectInsertAction struct {
+		Path  Path
+		Value interface{}
+	}
+
+	// ObjectDeleteAction asserts that the key at Path holds Value, and then
+	// removes it.
+	ObjectDeleteAction struct {
+		Path  Path
+		Value interface{}
+	}
+
+	// ObjectReplaceAction asserts that the key at Path holds Old, and then
+	// replaces it with New.
+	ObjectReplaceAction struct {
+		Path     Path
+		Old, New interface{}
+	}
+
+	// ListInsertAction inserts Value into a list so that it ends up at the
+	// index given by the last element of Path.
+	ListInsertAction struct {
+		Path  Path
+		Value interface{}
+	}
+
+	// ListDeleteAction asserts that the list element at Path is Value, and
+	// then removes it.
+	ListDeleteAction struct {
+		Path  Path
+		Value interface{}
+	}
+
+	// ListReplaceAction asserts that the list element at Path is Old, and
+	// then replaces it with New.
+	ListReplaceAction struct {
+		Path     Path
+		Old, New interface{}
+	}
+
+	// ListMoveAction moves the list element at Path so that it ends up at
+	// index To of the same list.
+	ListMoveAction struct {
+		Path Path
+		To   int
+	}
+
+	// NumberAddAction adds Amount to the number at Path.
+	NumberAddAction struct {
+		Path   Path
+		Amount float64
+	}
+
+	// TextAction applies Op, an operation made of text actions, to the
+	// string at Path.
+	TextAction struct {
+		Path Path
+		Op   cooperate.Operation
+	}
+)
+
+func (a ObjectInsertAction) GoString() string {
+	return fmt.Sprintf("OI(%v, %v)", a.Path, a.Value)
+}
+
+func (a ObjectDeleteAction) GoString() string {
+	return fmt.Sprintf("OD(%v, %v)", a.Path, a.Value)
+}
+
+func (a ObjectReplaceAction) GoString() string {
+	return fmt.Sprintf("OR(%v, %v, %v)", a.Path, a.Old, a.New)
+}
+
+func (a ListInsertAction) GoString() string {
+	return fmt.Sprintf("LI(%v, %v)", a.Path, a.Value)
+}
+
+func (a ListDeleteAction) GoString() string {
+	return fmt.Sprintf("LD(%v, %v)", a.Path, a.Value)
+}
+
+func (a ListReplaceAction) GoString() string {
+	return fmt.Sprintf("LR(%v, %v, %v)", a.Path, a.Old, a.New)
+}
+
+func (a ListMoveAction) GoString() string {
+	return fmt.Sprintf("LM(%v, %d)", a.Path, a.To)
+}
+
+func (a NumberAddAction) GoString() string {
+	return fmt.Sprintf("NA(%v, %v)", a.Path, a.Amount)
+}
+
+func (a TextAction) GoString() string {
+	return fmt.Sprintf("T(%v, %#v)", a.Path, a.Op)
+}
+
+// Expand returns a unchanged, since JSON actions are already atomic.
+func (jh JSONHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce merges consecutive number additions or text edits that address the
+// same path.
+func (jh JSONHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	switch a := a.(type) {
+	case NumberAddAction:
+		if b, ok := b.(NumberAddAction); ok && pathEqual(a.Path, b.Path) {
+			return NumberAddAction{Path: a.Path, Amount: a.Amount + b.Amount}, true
+		}
+	case TextAction:
+		if b, ok := b.(TextAction); ok && pathEqual(a.Path, b.Path) {
+			if op, err := json0.ComposeText(a.Op, b.Op); err == nil {
+				return TextAction{Path: a.Path, Op: op}, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (jh JSONHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var composed []component
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			c, err := toComponent(it.Consume())
+			if err != nil {
+				return nil, err
+			}
+			if composed, err = appendComponent(composed, c); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return fromComponents(composed), nil
+
+}
+
+// Transform implements cooperate.Transformer for the JSON actions defined in
+// this package.
+//
+// Like text.TextHandler, this implementation favors b by default: if a and b
+// insert at the same location, or replace or move the same element, b's
+// change wins. jh.Priority may favor a instead.
+func (jh JSONHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	aComponents, err := consumeComponents(a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bComponents, err := consumeComponents(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transform := func(c, other component, isLeft bool) ([]component, error) {
+		return transformComponent(nil, c, other, isLeft)
+	}
+
+	// the left operation wins conflicts
+	if jh.Priority == cooperate.FavorA {
+		aPrime, bPrime, err := json0.TransformSequences(aComponents, bComponents, transform, appendComponent)
+		if err != nil {
+			return nil, nil, err
+		}
+		return fromComponents(aPrime), fromComponents(bPrime), nil
+	}
+
+	bPrime, aPrime, err := json0.TransformSequences(bComponents, aComponents, transform, appendComponent)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fromComponents(aPrime), fromComponents(bPrime), nil
+
+}
+
+// A component is the json0 representation of an action, in which an
+// action is described by the combination of fields that are present.
+type component struct {
+	p Path
+
+	li, ld, oi, od interface{}
+	lm             int
+	na             float64
+	t              cooperate.Operation
+
+	hasLi, hasLd, hasOi, hasOd, hasLm, hasNa, hasT bool
+}
+
+func toComponent(a cooperate.Action) (component, error) {
+	switch a := a.(type) {
+	case ObjectInsertAction:
+		return component{p: clonePath(a.Path), oi: a.Value, hasOi: true}, nil
+	case ObjectDeleteAction:
+		return component{p: clonePath(a.Path), od: a.Value, hasOd: true}, nil
+	case ObjectReplaceAction:
+		return component{p: clonePath(a.Path), od: a.Old, oi: a.New, hasOd: true, hasOi: true}, nil
+	case ListInsertAction:
+		return component{p: clonePath(a.Path), li: a.Value, hasLi: true}, nil
+	case ListDeleteAction:
+		return component{p: clonePath(a.Path), ld: a.Value, hasLd: true}, nil
+	case ListReplaceAction:
+		return component{p: clonePath(a.Path), ld: a.Old, li: a.New, hasLd: true, hasLi: true}, nil
+	case ListMoveAction:
+		return component{p: clonePath(a.Path), lm: a.To, hasLm: true}, nil
+	case NumberAddAction:
+		return component{p: clonePath(a.Path), na: a.Amount, hasNa: true}, nil
+	case TextAction:
+		return component{p: clonePath(a.Path), t: a.Op, hasT: true}, nil
+	}
+	return component{}, cooperate.ErrUnknownAction
+}
+
+func toComponents(op cooperate.Operation) ([]component, error) {
+	return consumeComponents(cooperate.NewOperationIterator(op))
+}
+
+func consumeComponents(it *cooperate.OperationIterator) ([]component, error) {
+	var components []component
+	for it.More() {
+		c, err := toComponent(it.Consume())
+		if err != nil {
+			return nil, err
+		}
+		components = append(components, c)
+	}
+	return components, nil
+}
+
+func (c component) action() cooperate.Action {
+	switch {
+	case c.hasOd && c.hasOi:
+		return ObjectReplaceAction{Path: c.p, Old: c.od, New: c.oi}
+	case c.hasOi:
+		return ObjectInsertAction{Path: c.p, Value: c.oi}
+	case c.hasOd:
+		return ObjectDeleteAction{Path: c.p, Value: c.od}
+	case c.hasLd && c.hasLi:
+		return ListReplaceAction{Path: c.p, Old: c.ld, New: c.li}
+	case c.hasLi:
+		return ListInsertAction{Path: c.p, Value: c.li}
+	case c.hasLd:
+		return ListDeleteAction{Path: c.p, Value: c.ld}
+	case c.hasLm:
+		return ListMoveAction{Path: c.p, To: c.lm}
+	case c.hasNa:
+		return NumberAddAction{Path: c.p, Amount: c.na}
+	case c.hasT:
+		return TextAction{Path: c.p, Op: c.t}
+	}
+	return nil
+}
+
+func fromComponents(components []component) cooperate.Operation {
+	var actions []cooperate.Action
+	for _, c := range components {
+		actions = append(actions, c.action())
+	}
+	return cooperate.Operation(actions)
+}
+
+// appendComponent adds c to the end of dest, merging it with the last
+// component of dest where possible.
+func appendComponent(dest []component, c component) ([]component, error) {
+
+	c.p = clonePath(c.p)
+
+	if c.hasLm && index(c.p[len(c.p)-1]) == c.lm {
+		// moving an element to where it already is is a noop
+		return dest, nil
+	}
+
+	if len(dest) == 0 {
+		return append(dest, c), nil
+	}
+
+	last := &dest[len(dest)-1]
+	if !pathEqual(c.p, last.p) {
+		return append(dest, c), nil
+	}
+
+	switch {
+
+	case c.hasT && last.hasT:
+		op, err := json0.ComposeText(last.t, c.t)
+		if err != nil {
+			return nil, err
+		}
+		last.t = op
+
+	case c.hasNa && last.hasNa:
+		last.na += c.na
+
+	case last.hasLi && !c.hasLi && c.hasLd && reflect.DeepEqual(c.ld, last.li):
+		// an insert immediately followed by a delete is a noop
+		if last.hasLd {
+			last.li, last.hasLi = nil, false
+		} else {
+			dest = dest[:len(dest)-1]
+		}
+
+	case last.hasOd && !last.hasOi && c.hasOi && !c.hasOd:
+		last.oi, last.hasOi = c.oi, true
+
+	case last.hasOi && c.hasOd:
+		// the last component inserted what c deletes or replaces
+		if c.hasOi {
+			last.oi = c.oi
+		} else if last.hasOd {
+			last.oi, last.hasOi = nil, false
+		} else {
+			dest = dest[:len(dest)-1]
+		}
+
+	default:
+		dest = append(dest, c)
+
+	}
+
+	return dest, nil
+
+}
+
+// transformComponent transforms c so that it applies after otherC, and
+// appends the result to dest. If c and otherC conflict, c wins when isLeft.
+func transformComponent(dest []component, c, otherC component, isLeft bool) ([]component, error) {
+
+	c.p = clonePath(c.p)
+
+	common := commonLength(otherC, c)
+	common2 := commonLength(c, otherC)
+	cplength, otherCplength := c.operandLength(), otherC.operandLength()
+
+	// if c is deleting something that otherC changes, c has to delete the
+	// changed value instead
+	if common2 >= 0 && otherCplength > cplength && pathElem(c.p, common2) == pathElem(otherC.p, common2) {
+		oc := otherC
+		oc.p = otherC.p[cplength:]
+		if c.hasLd {
+			v, err := applyComponent(clone(c.ld), oc)
+			if err != nil {
+				return nil, err
+			}
+			c.ld = v
+		} else if c.hasOd {
+			v, err := applyComponent(clone(c.od), oc)
+			if err != nil {
+				return nil, err
+			}
+			c.od = v
+		}
+	}
+
+	if common >= 0 {
+
+		commonOperand := cplength == otherCplength
+		cp, op := pathElem(c.p, common), pathElem(otherC.p, common)
+
+		switch {
+
+		case otherC.hasT:
+			if c.hasT {
+				var t cooperate.Operation
+				var err error
+				if isLeft {
+					_, t, err = json0.TransformText(otherC.t, c.t)
+				} else {
+					t, _, err = json0.TransformText(c.t, otherC.t)
+				}
+				if err != nil {
+					return nil, err
+				}
+				if len(t) > 0 {
+					c.t = t
+					return appendComponent(dest, c)
+				}
+				return dest, nil
+			}
+
+		case otherC.hasNa:
+			// adding to a number never affects other components
+
+		case otherC.hasLi && otherC.hasLd:
+			if op == cp {
+				if !commonOperand {
+					return dest, nil
+				} else if c.hasLd {
+					// we're both replacing one element; only one can survive
+					if c.hasLi && isLeft {
+						c.ld = clone(otherC.li)
+					} else {
+						return dest, nil
+					}
+				}
+			}
+
+		case otherC.hasLi:
+			if c.hasLi && !c.hasLd && commonOperand && cp == op {
+				// in an insert against an insert, left wins
+				if !isLeft {
+					c.p[common] = index(cp) + 1
+				}
+			} else if isIndex(cp) && isIndex(op) && index(op) <= index(cp) {
+				c.p[common] = index(cp) + 1
+			}
+
+			if c.hasLm && commonOperand && index(op) <= c.lm {
+				c.lm++
+			}
+
+		case otherC.hasLd:
+			if c.hasLm && commonOperand {
+				if op == cp {
+					// they deleted the thing we're trying to move
+					return dest, nil
+				}
+				p, from, to := index(op), index(cp), c.lm
+				if p < to || (p == to && from < to) {
+					c.lm--
+				}
+			}
+
+			if isIndex(cp) && isIndex(op) && index(op) < index(cp) {
+				c.p[common] = index(cp) - 1
+			} else if op == cp {
+				if otherCplength < cplength {
+					// we're below the deleted element
+					return dest, nil
+				} else if c.hasLd {
+					if c.hasLi {
+						// we're replacing, they're deleting; we become an insert
+						c.ld, c.hasLd = nil, false
+					} else {
+						// we're trying to delete the same element
+						return dest, nil
+					}
+				}
+			}
+
+		case otherC.hasLm && index(op) != otherC.lm:
+			if c.hasLm && cplength == otherCplength {
+				from, to := index(cp), c.lm
+				otherFrom, otherTo := index(op), otherC.lm
+				if otherFrom != otherTo {
+					if from == otherFrom {
+						// they moved it too; tie break
+						if !isLeft {
+							return dest, nil
+						}
+						c.p[common] = otherTo
+						if from == to {
+							c.lm = otherTo
+						}
+					} else {
+						// they moved around it
+						p := from
+						if from > otherFrom {
+							p--
+						}
+						if from > otherTo {
+							p++
+						} else if from == otherTo && otherFrom > otherTo {
+							p++
+							if from == to {
+								c.lm++
+							}
+						}
+						c.p[common] = p
+
+						// and where it ends up
+						if to > otherFrom {
+							c.lm--
+						} else if to == otherFrom && to > from {
+							c.lm--
+						}
+						if to > otherTo {
+							c.lm++
+						} else if to == otherTo {
+							// if we're both moving in the same direction, tie break
+							if (otherTo > otherFrom && to > from) || (otherTo < otherFrom && to < from) {
+								if !isLeft {
+									c.lm++
+								}
+							} else if to > from {
+								c.lm++
+							} else if to == otherFrom {
+								c.lm--
+							}
+						}
+					}
+				}
+			} else if c.hasLi && !c.hasLd && commonOperand {
+				from, to, p := index(op), otherC.lm, index(cp)
+				moved := p
+				if p > from {
+					moved--
+				}
+				if p > to {
+					moved++
+				}
+				c.p[common] = moved
+			} else if isIndex(cp) {
+				// everything else cares about where its element is after the move
+				from, to, p := index(op), otherC.lm, index(cp)
+				moved := p
+				if p == from {
+					moved = to
+				} else {
+					if p > from {
+						moved--
+					}
+					if p > to {
+						moved++
+					} else if p == to && from > to {
+						moved++
+					}
+				}
+				c.p[common] = moved
+			}
+
+		case otherC.hasOi && otherC.hasOd:
+			if cp == op {
+				if c.hasOi && commonOperand {
+					// we inserted where someone else replaced
+					if !isLeft {
+						return dest, nil
+					}
+					c.od, c.hasOd = otherC.oi, true
+				} else {
+					// they replaced the object we're acting in
+					return dest, nil
+				}
+			}
+
+		case otherC.hasOi:
+			if c.hasOi && cp == op {
+				// left wins if we both insert at the same place
+				if !isLeft {
+					return dest, nil
+				}
+				var err error
+				if dest, err = appendComponent(dest, component{p: c.p, od: otherC.oi, hasOd: true}); err != nil {
+					return nil, err
+				}
+			}
+
+		case otherC.hasOd:
+			if cp == op {
+				if !commonOperand {
+					return dest, nil
+				}
+				if c.hasOi {
+					c.od, c.hasOd = nil, false
+				} else {
+					return dest, nil
+				}
+			}
+
+		}
+
+	}
+
+	return appendComponent(dest, c)
+
+}
+
+// operandLength is the length of the path to the container c acts on. Number
+// and text edits act on the addressed value itself, so for them it is one
+// more than the length of their path.
+func (c component) operandLength() int {
+	if c.hasNa || c.hasT {
+		return len(c.p) + 1
+	}
+	return len(c.p)
+}
+
+// commonLength reports the length of the path to the container a acts on if
+// it is a prefix of b's path, or -1 otherwise.
+func commonLength(a, b component) int {
+
+	alen, blen := a.operandLength(), b.operandLength()
+	if alen == 0 || blen == 0 {
+		return -1
+	}
+
+	alen--
+	blen--
+
+	for i := 0; i < alen; i++ {
+		if i >= blen || pathElem(a.p, i) != pathElem(b.p, i) {
+			return -1
+		}
+	}
+
+	return alen
+
+}
+
+// noElem stands in for path elements beyond the end of a path.
+type noElem struct{}
+
+func pathElem(p Path, i int) interface{} {
+	if i < len(p) {
+		return p[i]
+	}
+	return noElem{}
+}
+
+func isIndex(e interface{}) bool {
+	_, ok := e.(int)
+	return ok
+}
+
+func index(e interface{}) int {
+	i, _ := e.(int)
+	return i
+}
+
+func pathEqual(a, b Path) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func clonePath(p Path) Path {
+	return append(Path(nil), p...)
+}
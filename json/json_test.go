@@ -0,0 +1,278 @@
+package json
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestCompose(t *testing.T) {
+
+	cases := []struct {
+		First       cooperate.Operation
+		Second      cooperate.Operation
+		Composition cooperate.Operation
+	}{
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				NumberAddAction{Path: Path{"n"}, Amount: 1},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				NumberAddAction{Path: Path{"n"}, Amount: 2},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				NumberAddAction{Path: Path{"n"}, Amount: 3},
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				ListInsertAction{Path: Path{"l", 0}, Value: "x"},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				ListDeleteAction{Path: Path{"l", 0}, Value: "x"},
+			}),
+			Composition: nil,
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				ObjectDeleteAction{Path: Path{"k"}, Value: 1.0},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				ObjectInsertAction{Path: Path{"k"}, Value: 2.0},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				ObjectReplaceAction{Path: Path{"k"}, Old: 1.0, New: 2.0},
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.InsertAction("ab")}},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("c")}},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.InsertAction("abc")}},
+			}),
+		},
+	}
+
+	var jh JSONHandler
+
+	for i, c := range cases {
+
+		aa, bb := cooperate.NewOperationIterator(cooperate.Expand(jh, c.First)), cooperate.NewOperationIterator(cooperate.Expand(jh, c.Second))
+
+		if sum, err := jh.Compose(aa, bb); err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(sum, c.Composition) {
+			t.Errorf("[case %d] unexpected composition: expected '%#v' but got '%#v'", i, c.Composition, sum)
+		}
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	cases := []struct {
+		Document       interface{}
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       interface{}
+	}{
+		// concurrent list inserts at the same index: b wins
+		{
+			Document: []interface{}{"x"},
+			A:        cooperate.Operation{ListInsertAction{Path: Path{0}, Value: "a"}},
+			B:        cooperate.Operation{ListInsertAction{Path: Path{0}, Value: "b"}},
+			APrime:   cooperate.Operation{ListInsertAction{Path: Path{1}, Value: "a"}},
+			BPrime:   cooperate.Operation{ListInsertAction{Path: Path{0}, Value: "b"}},
+			Expected: []interface{}{"b", "a", "x"},
+		},
+		// an edit inside a deleted element disappears, and the delete picks it up
+		{
+			Document: []interface{}{map[string]interface{}{"n": 1.0}},
+			A:        cooperate.Operation{NumberAddAction{Path: Path{0, "n"}, Amount: 1}},
+			B:        cooperate.Operation{ListDeleteAction{Path: Path{0}, Value: map[string]interface{}{"n": 1.0}}},
+			APrime:   nil,
+			BPrime:   cooperate.Operation{ListDeleteAction{Path: Path{0}, Value: map[string]interface{}{"n": 2.0}}},
+			Expected: []interface{}{},
+		},
+		// concurrent object inserts of the same key: b wins
+		{
+			Document: map[string]interface{}{},
+			A:        cooperate.Operation{ObjectInsertAction{Path: Path{"k"}, Value: "a"}},
+			B:        cooperate.Operation{ObjectInsertAction{Path: Path{"k"}, Value: "b"}},
+			APrime:   nil,
+			BPrime:   cooperate.Operation{ObjectReplaceAction{Path: Path{"k"}, Old: "a", New: "b"}},
+			Expected: map[string]interface{}{"k": "b"},
+		},
+		// a move shifts an edit to follow its element
+		{
+			Document: []interface{}{1.0, 2.0, 3.0},
+			A:        cooperate.Operation{NumberAddAction{Path: Path{0}, Amount: 10}},
+			B:        cooperate.Operation{ListMoveAction{Path: Path{0}, To: 2}},
+			APrime:   cooperate.Operation{NumberAddAction{Path: Path{2}, Amount: 10}},
+			BPrime:   cooperate.Operation{ListMoveAction{Path: Path{0}, To: 2}},
+			Expected: []interface{}{2.0, 3.0, 11.0},
+		},
+		// a move around an element leaves its index alone
+		{
+			Document: []interface{}{"x", "y", "z"},
+			A:        cooperate.Operation{ListReplaceAction{Path: Path{2}, Old: "z", New: "c"}},
+			B:        cooperate.Operation{ListMoveAction{Path: Path{0}, To: 1}},
+			APrime:   cooperate.Operation{ListReplaceAction{Path: Path{2}, Old: "z", New: "c"}},
+			BPrime:   cooperate.Operation{ListMoveAction{Path: Path{0}, To: 1}},
+			Expected: []interface{}{"y", "x", "c"},
+		},
+		// moving an element to where it already is changes nothing
+		{
+			Document: []interface{}{"x", "y"},
+			A:        cooperate.Operation{ListReplaceAction{Path: Path{1}, Old: "y", New: "b"}},
+			B:        cooperate.Operation{ListMoveAction{Path: Path{0}, To: 0}},
+			APrime:   cooperate.Operation{ListReplaceAction{Path: Path{1}, Old: "y", New: "b"}},
+			BPrime:   nil,
+			Expected: []interface{}{"x", "b"},
+		},
+		// embedded text edits are transformed by the text handler
+		{
+			Document: map[string]interface{}{"s": "ac"},
+			A:        cooperate.Operation{TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.RetainAction(1), text.InsertAction("b"), text.RetainAction(1)}}},
+			B:        cooperate.Operation{TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("d")}}},
+			APrime:   cooperate.Operation{TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.RetainAction(1), text.InsertAction("b"), text.RetainAction(2)}}},
+			BPrime:   cooperate.Operation{TextAction{Path: Path{"s"}, Op: cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}}},
+			Expected: map[string]interface{}{"s": "abcd"},
+		},
+	}
+
+	var jh JSONHandler
+
+	for i, c := range cases {
+
+		a, b := cooperate.NewOperationIterator(cooperate.Expand(jh, c.A)), cooperate.NewOperationIterator(cooperate.Expand(jh, c.B))
+
+		aPrime, bPrime, err := jh.Transform(a, b)
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewJSONDocument(c.Document), NewJSONDocument(c.Document)
+		if err := ab.Apply(c.A); err != nil {
+			t.Errorf("[case %d] apply error: %s", i, err)
+		} else if err := ab.Apply(bPrime); err != nil {
+			t.Errorf("[case %d] apply error: %s", i, err)
+		} else if err := ba.Apply(c.B); err != nil {
+			t.Errorf("[case %d] apply error: %s", i, err)
+		} else if err := ba.Apply(aPrime); err != nil {
+			t.Errorf("[case %d] apply error: %s", i, err)
+		} else if !reflect.DeepEqual(ab.Value(), c.Expected) || !reflect.DeepEqual(ba.Value(), c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%v' but got '%v' and '%v'", i, c.Expected, ab.Value(), ba.Value())
+		}
+	}
+
+}
+
+var jsonConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		l := make([]interface{}, r.Intn(4))
+		for i := range l {
+			l[i] = randomString(r)
+		}
+		return NewJSONDocument(map[string]interface{}{
+			"l": l,
+			"o": map[string]interface{}{},
+			"n": 0.0,
+			"s": randomString(r),
+		})
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return JSONHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		jd := NewJSONDocument(doc.(*JSONDocument).Value())
+		var op cooperate.Operation
+		for n := 1 + r.Intn(3); len(op) < n; {
+			if a := randomAction(r, jd.Value().(map[string]interface{})); a != nil {
+				if err := jd.Apply(cooperate.Operation{a}); err != nil {
+					panic(err)
+				}
+				op = append(op, a)
+			}
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		return reflect.DeepEqual(a.(*JSONDocument).Value(), b.(*JSONDocument).Value())
+	},
+}
+
+// randomAction returns a random action that applies to v, a document made by
+// jsonConfig, or nil.
+func randomAction(r *rand.Rand, v map[string]interface{}) cooperate.Action {
+	l, o, s := v["l"].([]interface{}), v["o"].(map[string]interface{}), v["s"].(string)
+	switch r.Intn(7) {
+	case 0:
+		i := r.Intn(len(l) + 1)
+		return ListInsertAction{Path: Path{"l", i}, Value: randomString(r)}
+	case 1:
+		if len(l) > 0 {
+			i := r.Intn(len(l))
+			return ListDeleteAction{Path: Path{"l", i}, Value: l[i]}
+		}
+	case 2:
+		if len(l) > 0 {
+			i := r.Intn(len(l))
+			return ListReplaceAction{Path: Path{"l", i}, Old: l[i], New: randomString(r)}
+		}
+	case 3:
+		k := []string{"x", "y"}[r.Intn(2)]
+		if old, ok := o[k]; !ok {
+			return ObjectInsertAction{Path: Path{"o", k}, Value: randomString(r)}
+		} else if r.Intn(2) == 0 {
+			return ObjectDeleteAction{Path: Path{"o", k}, Value: old}
+		} else {
+			return ObjectReplaceAction{Path: Path{"o", k}, Old: old, New: randomString(r)}
+		}
+	case 4:
+		return NumberAddAction{Path: Path{"n"}, Amount: float64(1 + r.Intn(9))}
+	case 5:
+		i, j := r.Intn(len(s)+1), r.Intn(len(s)+1)
+		if i > j {
+			i, j = j, i
+		}
+		op := cooperate.Operation{text.RetainAction(i)}
+		if i == j {
+			op = append(op, text.InsertAction(randomString(r)))
+		} else {
+			op = append(op, text.DeleteAction(s[i:j]))
+		}
+		return TextAction{Path: Path{"s"}, Op: append(op, text.RetainAction(len(s)-j))}
+	case 6:
+		if len(l) > 0 {
+			return ListMoveAction{Path: Path{"l", r.Intn(len(l))}, To: r.Intn(len(l))}
+		}
+	}
+	return nil
+}
+
+// randomString returns up to three copies of a random letter.
+func randomString(r *rand.Rand) string {
+	return strings.Repeat(string(rune('a'+r.Intn(26))), 1+r.Intn(3))
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, jsonConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, jsonConfig, 200)
+}
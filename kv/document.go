@@ -0,0 +1,41 @@
+package kv
+
+import "github.com/tylerchr/cooperate"
+
+// A KVDocument is a map of string keys to arbitrary values that implements
+// the cooperate.Document interface.
+type KVDocument map[string]interface{}
+
+// NewKVDocument initializes a KVDocument with a copy of initial.
+func NewKVDocument(initial map[string]interface{}) KVDocument {
+	kd := make(KVDocument, len(initial))
+	for k, v := range initial {
+		kd[k] = v
+	}
+	return kd
+}
+
+// Apply performs op against the KVDocument. If op contains an unknown action
+// the document is left unchanged.
+func (kd KVDocument) Apply(op cooperate.Operation) error {
+
+	for _, a := range op {
+		switch a.(type) {
+		case SetAction, DeleteAction:
+		default:
+			return cooperate.ErrUnknownAction
+		}
+	}
+
+	for _, a := range op {
+		switch a := a.(type) {
+		case SetAction:
+			kd[a.Key] = a.Value
+		case DeleteAction:
+			delete(kd, a.Key)
+		}
+	}
+
+	return nil
+
+}
@@ -0,0 +1,49 @@
+package kv
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestKVDocument(t *testing.T) {
+
+	cases := []struct {
+		Existing      map[string]interface{}
+		Operation     cooperate.Operation
+		ExpectedError error
+		Expected      KVDocument
+	}{
+		{
+			Existing: map[string]interface{}{"name": "ada"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				SetAction{Key: "name", Value: "grace"},
+				SetAction{Key: "age", Value: 85.0},
+				DeleteAction{Key: "missing"},
+			}),
+			Expected: KVDocument{"name": "grace", "age": 85.0},
+		},
+		{
+			Existing: map[string]interface{}{"name": "ada"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				DeleteAction{Key: "name"},
+				"bogus",
+			}),
+			ExpectedError: cooperate.ErrUnknownAction,
+			Expected:      KVDocument{"name": "ada"},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewKVDocument(c.Existing)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc, c.Expected) {
+			t.Errorf("[case %d] unexpected document: expected '%v' but got '%v'", i, c.Expected, doc)
+		}
+	}
+
+}
@@ -0,0 +1,190 @@
+// Package kv implements collaborative editing of a map of string keys to
+// arbitrary values, such as the state of a form.
+package kv
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+)
+
+type (
+	// A KVHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the key-value operations: set and delete.
+	KVHandler struct {
+		// Merge, if set, resolves concurrent sets of the same key. When nil,
+		// or when either side deletes the key, the write favored by Priority
+		// wins.
+		Merge MergeFunc
+
+		// Priority decides whose write wins when a and b write the same key,
+		// and which value is passed to Merge first.
+		Priority cooperate.Priority
+	}
+
+	// A MergeFunc combines the values a and b that two concurrent operations
+	// set for key into the value both should converge on. a is the value
+	// favored by the handler's Priority, so that a Client using FavorA and a
+	// Server using FavorB pass the same arguments, but Merge must still be
+	// deterministic, and symmetric in a and b if the handlers of a Client and
+	// its Server might not favor the same side.
+	//
+	// A Server transforms an operation against the composition of those it
+	// missed, in which a later write to a key hides an earlier one, while a
+	// Client transforms its pending operations against each in turn. They
+	// converge only if a merge with an overwritten value leaves no trace:
+	// Merge(Merge(a, b), c) must equal Merge(a, c).
+	MergeFunc func(key string, a, b interface{}) interface{}
+
+	// SetAction sets Key to Value, whether or not it already exists.
+	SetAction struct {
+		Key   string
+		Value interface{}
+	}
+
+	// DeleteAction removes Key, whether or not it exists.
+	DeleteAction struct {
+		Key string
+	}
+)
+
+func (a SetAction) GoString() string    { return fmt.Sprintf("S(%s, %v)", a.Key, a.Value) }
+func (a DeleteAction) GoString() string { return fmt.Sprintf("D(%s)", a.Key) }
+
+// Expand returns a unchanged, since key-value actions are already atomic.
+func (kh KVHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce merges two writes to the same key, in which case the later write b
+// is all that remains.
+func (kh KVHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	ak, aok := key(a)
+	bk, bok := key(b)
+	if !aok || !bok || ak != bk {
+		return nil, false
+	}
+	return b, true
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (kh KVHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var w writes
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			if err := w.add(it.Consume()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return w.operation(), nil
+
+}
+
+// Transform implements cooperate.Transformer for the key-value operations
+// defined in this package.
+//
+// Writes to distinct keys never conflict. If a and b both write the same key
+// and Merge is set and both sides set the key, both converge on the merged
+// value; otherwise the write favored by kh.Priority wins. By default that is
+// b's write, so that the operation Server commits last takes effect.
+func (kh KVHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	var aw, bw writes
+
+	for a.More() {
+		if err := aw.add(a.Consume()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for b.More() {
+		if err := bw.add(b.Consume()); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	var aPrime, bPrime writes
+	overwritten := make(map[string]bool) // b's writes that lose to a's
+
+	for _, k := range aw.keys {
+		bAction, conflict := bw.actions[k]
+		if !conflict {
+			aPrime.add(aw.actions[k])
+			continue
+		}
+
+		as, aSet := aw.actions[k].(SetAction)
+		bs, bSet := bAction.(SetAction)
+		switch {
+		case kh.Merge != nil && aSet && bSet:
+			var value interface{}
+			if kh.Priority == cooperate.FavorA {
+				value = kh.Merge(k, as.Value, bs.Value)
+			} else {
+				value = kh.Merge(k, bs.Value, as.Value)
+			}
+			merged := SetAction{Key: k, Value: value}
+			aPrime.add(merged)
+			bw.actions[k] = merged
+
+		case kh.Priority == cooperate.FavorA:
+			aPrime.add(aw.actions[k])
+			overwritten[k] = true
+		}
+	}
+
+	for _, k := range bw.keys {
+		if !overwritten[k] {
+			bPrime.add(bw.actions[k])
+		}
+	}
+
+	return aPrime.operation(), bPrime.operation(), nil
+
+}
+
+// writes records the net effect of a sequence of actions: the last write to
+// each key, in the order the keys were first written.
+type writes struct {
+	keys    []string
+	actions map[string]cooperate.Action
+}
+
+func (w *writes) add(a cooperate.Action) error {
+	k, ok := key(a)
+	if !ok {
+		return cooperate.ErrUnknownAction
+	}
+	if w.actions == nil {
+		w.actions = make(map[string]cooperate.Action)
+	}
+	if _, exists := w.actions[k]; !exists {
+		w.keys = append(w.keys, k)
+	}
+	w.actions[k] = a
+	return nil
+}
+
+func (w *writes) operation() cooperate.Operation {
+	var actions []cooperate.Action
+	for _, k := range w.keys {
+		actions = append(actions, w.actions[k])
+	}
+	return cooperate.Operation(actions)
+}
+
+// key returns the key that a writes, or false if a is not a key-value action.
+func key(a cooperate.Action) (string, bool) {
+	switch a := a.(type) {
+	case SetAction:
+		return a.Key, true
+	case DeleteAction:
+		return a.Key, true
+	}
+	return "", false
+}
@@ -0,0 +1,193 @@
+package kv
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestCompose(t *testing.T) {
+
+	var kh KVHandler
+
+	a := cooperate.NewOperationIterator(cooperate.Operation{SetAction{Key: "a", Value: 1}, SetAction{Key: "b", Value: 1}})
+	b := cooperate.NewOperationIterator(cooperate.Operation{DeleteAction{Key: "a"}, SetAction{Key: "c", Value: 1}})
+
+	expected := cooperate.Operation{DeleteAction{Key: "a"}, SetAction{Key: "b", Value: 1}, SetAction{Key: "c", Value: 1}}
+
+	if sum, err := kh.Compose(a, b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(sum, expected) {
+		t.Errorf("unexpected composition: expected '%#v' but got '%#v'", expected, sum)
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	cases := []struct {
+		Handler        KVHandler
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       KVDocument
+	}{
+		// distinct keys don't conflict
+		{
+			A:        cooperate.Operation{SetAction{Key: "a", Value: 1}},
+			B:        cooperate.Operation{SetAction{Key: "b", Value: 2}},
+			APrime:   cooperate.Operation{SetAction{Key: "a", Value: 1}},
+			BPrime:   cooperate.Operation{SetAction{Key: "b", Value: 2}},
+			Expected: KVDocument{"a": 1, "b": 2},
+		},
+		// without a merge function, b wins
+		{
+			A:        cooperate.Operation{SetAction{Key: "k", Value: 1}},
+			B:        cooperate.Operation{DeleteAction{Key: "k"}},
+			APrime:   nil,
+			BPrime:   cooperate.Operation{DeleteAction{Key: "k"}},
+			Expected: KVDocument{},
+		},
+		// a wins if it is favored
+		{
+			Handler:  KVHandler{Priority: cooperate.FavorA},
+			A:        cooperate.Operation{SetAction{Key: "k", Value: 1}},
+			B:        cooperate.Operation{DeleteAction{Key: "k"}},
+			APrime:   cooperate.Operation{SetAction{Key: "k", Value: 1}},
+			BPrime:   nil,
+			Expected: KVDocument{"k": 1},
+		},
+		// with a merge function, both converge on the merged value
+		{
+			Handler: KVHandler{
+				Merge: func(key string, a, b interface{}) interface{} {
+					return a.(int) + b.(int)
+				},
+			},
+			A:        cooperate.Operation{SetAction{Key: "k", Value: 1}},
+			B:        cooperate.Operation{SetAction{Key: "k", Value: 2}},
+			APrime:   cooperate.Operation{SetAction{Key: "k", Value: 3}},
+			BPrime:   cooperate.Operation{SetAction{Key: "k", Value: 3}},
+			Expected: KVDocument{"k": 3},
+		},
+		// the favored value is passed to the merge function first
+		{
+			Handler: KVHandler{
+				Merge: func(key string, a, b interface{}) interface{} {
+					return fmt.Sprintf("%v,%v", a, b)
+				},
+			},
+			A:        cooperate.Operation{SetAction{Key: "k", Value: "a"}},
+			B:        cooperate.Operation{SetAction{Key: "k", Value: "b"}},
+			APrime:   cooperate.Operation{SetAction{Key: "k", Value: "b,a"}},
+			BPrime:   cooperate.Operation{SetAction{Key: "k", Value: "b,a"}},
+			Expected: KVDocument{"k": "b,a"},
+		},
+	}
+
+	for i, c := range cases {
+
+		aPrime, bPrime, err := c.Handler.Transform(cooperate.NewOperationIterator(c.A), cooperate.NewOperationIterator(c.B))
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewKVDocument(nil), NewKVDocument(nil)
+		ab.Apply(c.A)
+		ab.Apply(bPrime)
+		ba.Apply(c.B)
+		ba.Apply(aPrime)
+		if !reflect.DeepEqual(ab, c.Expected) || !reflect.DeepEqual(ba, c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%v' but got '%v' and '%v'", i, c.Expected, ab, ba)
+		}
+	}
+
+}
+
+func TestServer(t *testing.T) {
+
+	doc := NewKVDocument(nil)
+
+	s := &cooperate.Server{
+		Document:           doc,
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      KVHandler{},
+		ComposeTransformer: KVHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{SetAction{Key: "name", Value: "ada"}}},
+		{Root: 1, Operation: cooperate.Operation{SetAction{Key: "email", Value: "ada@example.com"}}},
+		{Root: 1, Operation: cooperate.Operation{SetAction{Key: "email", Value: "grace@example.com"}}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if expected := (KVDocument{"name": "ada", "email": "grace@example.com"}); !reflect.DeepEqual(doc, expected) {
+		t.Errorf("unexpected document: expected '%v' but got '%v'", expected, doc)
+	}
+
+}
+
+// kvConfig describes key-value documents whose handlers merge with merge.
+func kvConfig(merge MergeFunc) ottest.Config[cooperate.Action] {
+	keys := []string{"a", "b", "c"}
+	return ottest.Config[cooperate.Action]{
+		NewDocument: func(r *rand.Rand) cooperate.Document {
+			doc := NewKVDocument(nil)
+			for _, k := range keys[:r.Intn(len(keys)+1)] {
+				doc[k] = r.Intn(10)
+			}
+			return doc
+		},
+		Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+			return KVHandler{Merge: merge, Priority: p}
+		},
+		RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+			var op cooperate.Operation
+			for i := r.Intn(3); i >= 0; i-- {
+				if k := keys[r.Intn(len(keys))]; r.Intn(3) == 0 {
+					op = append(op, DeleteAction{Key: k})
+				} else {
+					op = append(op, SetAction{Key: k, Value: r.Intn(10)})
+				}
+			}
+			return op
+		},
+	}
+}
+
+func TestTransform_Random(t *testing.T) {
+
+	// concat is a merge that depends on the order of its arguments
+	concat := func(key string, a, b interface{}) interface{} {
+		return fmt.Sprintf("%v,%v", a, b)
+	}
+
+	for _, merge := range []MergeFunc{nil, concat} {
+		ottest.Transform(t, kvConfig(merge), 2000)
+	}
+
+}
+
+func TestClient_Random(t *testing.T) {
+
+	// merges such as concat keep overwritten values, which a MergeFunc must
+	// not for clients to converge, so none is used here
+	ottest.Converge(t, kvConfig(nil), 200)
+
+}
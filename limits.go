@@ -0,0 +1,241 @@
+package cooperate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+var (
+	// ErrRateLimited indicates that a session or document has submitted
+	// operations faster than its Rate allows.
+	ErrRateLimited = errors.New("rate limited")
+
+	// ErrOperationTooLarge indicates that an operation exceeds
+	// Limits.MaxOperationSize.
+	ErrOperationTooLarge = errors.New("operation too large")
+
+	// ErrDocumentTooLarge indicates that an operation would grow a document
+	// beyond Limits.MaxDocumentSize.
+	ErrDocumentTooLarge = errors.New("document too large")
+
+	// ErrNoSizer indicates that a server limits a size but has no Sizer to
+	// measure it with.
+	ErrNoSizer = errors.New("size limited without a sizer")
+)
+
+// minBucketSweep is the fewest session buckets a server sweeps for idle
+// ones.
+const minBucketSweep = 64
+
+type (
+	// Limits restrict the operations a server accepts. The zero value
+	// imposes no limits.
+	Limits struct {
+		// SessionRate limits the operations of each session, and
+		// DocumentRate those of all sessions together.
+		SessionRate  Rate
+		DocumentRate Rate
+
+		// MaxOperationSize and MaxDocumentSize, if positive, limit the sizes
+		// measured by the server's Sizer. A server without one rejects every
+		// operation with ErrNoSizer.
+		MaxOperationSize int
+		MaxDocumentSize  int
+
+		// Now returns the current time for rate limiting. It defaults to
+		// time.Now.
+		Now func() time.Time
+	}
+
+	// A Rate allows Burst operations at once, refilled at PerSecond
+	// operations per second, as a token bucket. A Rate with a PerSecond but
+	// no Burst allows one operation at once, and the zero Rate is unlimited.
+	Rate struct {
+		PerSecond float64
+		Burst     int
+	}
+
+	// A SizerOf measures operations and documents for Limits, in units
+	// meaningful to their type, such as bytes of text.
+	SizerOf[A any] interface {
+		// OperationSize returns the size of op, such as the length of the
+		// text it inserts and deletes.
+		OperationSize(op OperationOf[A]) int
+
+		// DocumentSize returns the size doc will have once op is applied to
+		// it, which for an empty op is the size doc has already.
+		DocumentSize(doc DocumentOf[A], op OperationOf[A]) int
+	}
+
+	// Sizer is a SizerOf untyped actions.
+	Sizer = SizerOf[Action]
+)
+
+// A LimitError is returned by a server when an operation exceeds its Limits.
+// Err is ErrRateLimited, ErrOperationTooLarge or ErrDocumentTooLarge.
+type LimitError struct {
+	Session  string
+	Document string
+	Err      error
+
+	// RetryAfter is how long to wait before the operation would be within
+	// the rate limit, if Err is ErrRateLimited.
+	RetryAfter time.Duration
+}
+
+func (e *LimitError) Error() string {
+	if e.RetryAfter > 0 {
+		return fmt.Sprintf("session %q exceeded a limit on document %q: %s (retry after %s)", e.Session, e.Document, e.Err, e.RetryAfter)
+	}
+	return fmt.Sprintf("session %q exceeded a limit on document %q: %s", e.Session, e.Document, e.Err)
+}
+
+func (e *LimitError) Unwrap() error {
+	return e.Err
+}
+
+// A bucket is a token bucket for a Rate.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take removes a token from b, which is limited by r, or returns how long to
+// wait until one is available.
+func (b *bucket) take(r Rate, now time.Time) (wait time.Duration, ok bool) {
+
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * r.PerSecond
+	} else {
+		b.tokens = float64(r.burst())
+	}
+	b.tokens = math.Min(b.tokens, float64(r.burst()))
+	b.last = now
+
+	if b.tokens < 1 {
+		if r.PerSecond <= 0 {
+			return 0, false
+		}
+		return time.Duration((1 - b.tokens) / r.PerSecond * float64(time.Second)), false
+	}
+
+	b.tokens--
+	return 0, true
+
+}
+
+// idle reports whether b, which is limited by r, has refilled by now, so
+// that it is no different from a new bucket. A bucket that is never refilled
+// is never idle.
+func (b *bucket) idle(r Rate, now time.Time) bool {
+	return r.PerSecond > 0 && b.tokens+now.Sub(b.last).Seconds()*r.PerSecond >= float64(r.burst())
+}
+
+// limited reports whether r limits anything.
+func (r Rate) limited() bool {
+	return r.Burst > 0 || r.PerSecond > 0
+}
+
+// burst returns the number of operations r allows at once.
+func (r Rate) burst() int {
+	if r.Burst < 1 && r.PerSecond > 0 {
+		return 1
+	}
+	return r.Burst
+}
+
+// checkRate takes a token from the buckets of session and of the document,
+// or returns a *LimitError if either is empty. A token is only taken from
+// either if both have one.
+func (s *ServerOf[A]) checkRate(session string) error {
+
+	l := s.Limits
+	if !l.SessionRate.limited() && !l.DocumentRate.limited() {
+		return nil
+	}
+
+	now := time.Now()
+	if l.Now != nil {
+		now = l.Now()
+	}
+
+	var sb *bucket
+	if l.SessionRate.limited() {
+		sb = s.sessionBucket(session, now)
+		if wait, ok := sb.take(l.SessionRate, now); !ok {
+			return &LimitError{Session: session, Document: s.ID, Err: ErrRateLimited, RetryAfter: wait}
+		}
+	}
+
+	if l.DocumentRate.limited() {
+		if wait, ok := s.documentBucket.take(l.DocumentRate, now); !ok {
+			if sb != nil {
+				sb.tokens++
+			}
+			return &LimitError{Session: session, Document: s.ID, Err: ErrRateLimited, RetryAfter: wait}
+		}
+	}
+
+	return nil
+
+}
+
+// sessionBucket returns the bucket of session, creating it if need be. An
+// idle bucket is no different from a new one, so whenever the number of
+// buckets has doubled since they were last swept, the idle ones are evicted.
+func (s *ServerOf[A]) sessionBucket(session string, now time.Time) *bucket {
+
+	if sb := s.sessionBuckets[session]; sb != nil {
+		return sb
+	}
+
+	if s.sessionBuckets == nil {
+		s.sessionBuckets = make(map[string]*bucket)
+	}
+
+	if len(s.sessionBuckets) >= s.bucketSweep {
+		for id, b := range s.sessionBuckets {
+			if b.idle(s.Limits.SessionRate, now) {
+				delete(s.sessionBuckets, id)
+			}
+		}
+		s.bucketSweep = max(2*len(s.sessionBuckets), minBucketSweep)
+	}
+
+	sb := &bucket{}
+	s.sessionBuckets[session] = sb
+	return sb
+
+}
+
+// checkOperationSize returns a *LimitError if op is too large.
+func (s *ServerOf[A]) checkOperationSize(session string, op OperationOf[A]) error {
+	l := s.Limits
+	if l.MaxOperationSize <= 0 {
+		return nil
+	} else if s.Sizer == nil {
+		return ErrNoSizer
+	}
+	if s.Sizer.OperationSize(op) > l.MaxOperationSize {
+		return &LimitError{Session: session, Document: s.ID, Err: ErrOperationTooLarge}
+	}
+	return nil
+}
+
+// checkDocumentSize returns a *LimitError if op, which has been transformed
+// against history, would make the document too large. A document that is
+// already too large, say because the limit was lowered, may still shrink.
+func (s *ServerOf[A]) checkDocumentSize(session string, op OperationOf[A]) error {
+	l := s.Limits
+	if l.MaxDocumentSize <= 0 {
+		return nil
+	} else if s.Sizer == nil {
+		return ErrNoSizer
+	}
+	if size := s.Sizer.DocumentSize(s.Document, op); size > l.MaxDocumentSize && size > s.Sizer.DocumentSize(s.Document, nil) {
+		return &LimitError{Session: session, Document: s.ID, Err: ErrDocumentTooLarge}
+	}
+	return nil
+}
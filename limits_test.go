@@ -0,0 +1,243 @@
+package cooperate_test
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestServer_RateLimits(t *testing.T) {
+
+	now := time.Unix(1500000000, 0)
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		ID:                 "notes",
+		Limits: cooperate.Limits{
+			SessionRate:  cooperate.Rate{PerSecond: 1, Burst: 2},
+			DocumentRate: cooperate.Rate{PerSecond: 2, Burst: 3},
+			Now:          func() time.Time { return now },
+		},
+	}
+
+	cases := []struct {
+		Session    string
+		Advance    time.Duration
+		RetryAfter time.Duration
+	}{
+		{Session: "ada"},
+		{Session: "ada"},
+		{Session: "ada", RetryAfter: time.Second},
+		{Session: "bob"},
+		{Session: "bob", RetryAfter: 500 * time.Millisecond},
+		{Session: "bob", Advance: 500 * time.Millisecond},
+		{Session: "ada", RetryAfter: 500 * time.Millisecond},
+		{Session: "ada", Advance: time.Second},
+	}
+
+	applied := 0
+	for i, c := range cases {
+
+		now = now.Add(c.Advance)
+
+		err := s.ApplyAs(c.Session, applied, cooperate.Operation{text.RetainAction(applied), text.InsertAction("x")})
+		if c.RetryAfter == 0 {
+			if err != nil {
+				t.Errorf("[case %d] unexpected error: %s", i, err)
+			}
+			applied++
+			continue
+		}
+
+		var limitErr *cooperate.LimitError
+		if !errors.As(err, &limitErr) || limitErr.Err != cooperate.ErrRateLimited || limitErr.Session != c.Session || limitErr.Document != "notes" {
+			t.Errorf("[case %d] unexpected error: %v", i, err)
+		} else if limitErr.RetryAfter != c.RetryAfter {
+			t.Errorf("[case %d] unexpected retry: expected %s but got %s", i, c.RetryAfter, limitErr.RetryAfter)
+		}
+	}
+
+	if seqno := s.History.SequenceNumber(); seqno != applied {
+		t.Errorf("unexpected sequence number: expected %d but got %d", applied, seqno)
+	}
+
+}
+
+func TestServer_SizeLimits(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Sizer:              text.TextHandler{},
+		Limits: cooperate.Limits{
+			MaxOperationSize: 4,
+			MaxDocumentSize:  6,
+		},
+	}
+
+	cases := []struct {
+		Root      int
+		Operation cooperate.Operation
+		Error     error
+	}{
+		{
+			Root:      0,
+			Operation: cooperate.Operation{text.InsertAction("abcde")},
+			Error:     cooperate.ErrOperationTooLarge,
+		},
+		{
+			Root:      0,
+			Operation: cooperate.Operation{text.InsertAction("abcd")},
+		},
+		{
+			Root:      1,
+			Operation: cooperate.Operation{text.RetainAction(4), text.InsertAction("efg")},
+			Error:     cooperate.ErrDocumentTooLarge,
+		},
+		{
+			// the document is only too large once this is transformed
+			Root:      0,
+			Operation: cooperate.Operation{text.InsertAction("xyz")},
+			Error:     cooperate.ErrDocumentTooLarge,
+		},
+		{
+			Root:      1,
+			Operation: cooperate.Operation{text.DeleteAction("ab"), text.InsertAction("xy"), text.RetainAction(2)},
+		},
+	}
+
+	for i, c := range cases {
+		var limitErr *cooperate.LimitError
+		if err := s.Apply(c.Root, c.Operation); c.Error == nil && err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+		} else if c.Error != nil && (!errors.As(err, &limitErr) || !errors.Is(err, c.Error)) {
+			t.Errorf("[case %d] unexpected error: expected '%s' but got '%v'", i, c.Error, err)
+		}
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "xycd" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+	// a document already over a lowered limit may shrink, but not grow
+	s.Limits.MaxDocumentSize = 2
+	if err := s.Apply(2, cooperate.Operation{text.DeleteAction("x"), text.RetainAction(3)}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+	if err := s.Apply(3, cooperate.Operation{text.InsertAction("z"), text.RetainAction(3)}); !errors.Is(err, cooperate.ErrDocumentTooLarge) {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", cooperate.ErrDocumentTooLarge, err)
+	}
+
+}
+
+func TestServer_RateWithoutBurst(t *testing.T) {
+
+	now := time.Unix(1500000000, 0)
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Limits: cooperate.Limits{
+			SessionRate: cooperate.Rate{PerSecond: 2},
+			Now:         func() time.Time { return now },
+		},
+	}
+
+	// a Rate without a Burst allows one operation at once
+	if err := s.ApplyAs("ada", 0, cooperate.Operation{text.InsertAction("x")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	var limitErr *cooperate.LimitError
+	if err := s.ApplyAs("ada", 1, cooperate.Operation{text.RetainAction(1), text.InsertAction("x")}); !errors.As(err, &limitErr) || limitErr.RetryAfter != 500*time.Millisecond {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if err := s.ApplyAs("ada", 1, cooperate.Operation{text.RetainAction(1), text.InsertAction("x")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+}
+
+func TestServer_RateManySessions(t *testing.T) {
+
+	now := time.Unix(1500000000, 0)
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Limits: cooperate.Limits{
+			SessionRate: cooperate.Rate{PerSecond: 0.1, Burst: 1},
+			Now:         func() time.Time { return now },
+		},
+	}
+
+	apply := func(session string) error {
+		seqno := s.History.SequenceNumber()
+		return s.ApplyAs(session, seqno, cooperate.Operation{text.RetainAction(seqno), text.InsertAction("x")})
+	}
+
+	if err := apply("ada"); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the buckets are swept as sessions come along, but ada's is kept, as it
+	// has yet to refill
+	for i := 0; i < 1000; i++ {
+		if err := apply(strconv.Itoa(i)); err != nil {
+			t.Fatalf("[session %d] unexpected error: %s", i, err)
+		}
+		now = now.Add(time.Millisecond)
+	}
+
+	if err := apply("ada"); !errors.Is(err, cooperate.ErrRateLimited) {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", cooperate.ErrRateLimited, err)
+	}
+
+	// once the bucket of a session has refilled, evicting it makes no
+	// difference
+	now = now.Add(time.Hour)
+	for i := 0; i < 1000; i++ {
+		if err := apply("new" + strconv.Itoa(i)); err != nil {
+			t.Fatalf("[session %d] unexpected error: %s", i, err)
+		}
+	}
+	if err := apply("0"); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+}
+
+func TestServer_SizeLimitsWithoutSizer(t *testing.T) {
+
+	for _, limits := range []cooperate.Limits{{MaxOperationSize: 4}, {MaxDocumentSize: 4}} {
+
+		s := &cooperate.Server{
+			Document:           text.NewTextDocument(""),
+			History:            &cooperate.MemoryHistory{},
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+			Limits:             limits,
+		}
+
+		if err := s.Apply(0, cooperate.Operation{text.InsertAction("x")}); err != cooperate.ErrNoSizer {
+			t.Errorf("[%+v] unexpected error: expected '%s' but got '%v'", limits, cooperate.ErrNoSizer, err)
+		}
+
+	}
+
+}
@@ -0,0 +1,88 @@
+package list
+
+import (
+	"reflect"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// A ListDocument is a sequence of opaque items that implements the
+// cooperate.Document interface. Items are compared with reflect.DeepEqual.
+type ListDocument struct {
+	items []interface{}
+}
+
+// NewListDocument initializes a ListDocument with a starting value of initial.
+func NewListDocument(initial []interface{}) *ListDocument {
+	return &ListDocument{
+		items: append([]interface{}(nil), initial...),
+	}
+}
+
+// Items returns the current contents of the document. The returned slice
+// must not be modified.
+func (ld *ListDocument) Items() []interface{} {
+	return ld.items
+}
+
+// Len returns the number of items in the document.
+func (ld *ListDocument) Len() int {
+	return len(ld.items)
+}
+
+// Apply performs op against the ListDocument.
+func (ld *ListDocument) Apply(op cooperate.Operation) error {
+
+	// verify that operation will apply cleanly to document
+	if pre, _ := Lengths(op); len(ld.items) != pre {
+		return cooperate.ErrDocumentSizeMismatch
+	}
+
+	// verify that deletes and replacements match the document before
+	// changing anything
+	var cursor int
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			cursor += int(a)
+		case DeleteAction:
+			for _, item := range a {
+				if !reflect.DeepEqual(ld.items[cursor], item) {
+					return cooperate.ErrDeleteMismatch
+				}
+				cursor++
+			}
+		case ReplaceAction:
+			if !reflect.DeepEqual(ld.items[cursor], a.Old) {
+				return cooperate.ErrDeleteMismatch
+			}
+			cursor++
+		case InsertAction:
+		default:
+			return cooperate.ErrUnknownAction
+		}
+	}
+
+	_, post := Lengths(op)
+	items := make([]interface{}, 0, post)
+	cursor = 0
+
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			items = append(items, ld.items[cursor:cursor+int(a)]...)
+			cursor += int(a)
+		case InsertAction:
+			items = append(items, a...)
+		case DeleteAction:
+			cursor += len(a)
+		case ReplaceAction:
+			items = append(items, a.New)
+			cursor++
+		}
+	}
+
+	ld.items = items
+	return nil
+
+}
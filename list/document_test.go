@@ -0,0 +1,65 @@
+package list
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestListDocument(t *testing.T) {
+
+	cases := []struct {
+		ExistingItems []interface{}
+		Operation     cooperate.Operation
+		ExpectedError error
+		ExpectedItems []interface{}
+	}{
+		{
+			ExistingItems: nil,
+			Operation: cooperate.Operation([]cooperate.Action{
+				InsertAction{"a", "b"},
+			}),
+			ExpectedItems: []interface{}{"a", "b"},
+		},
+		{
+			ExistingItems: []interface{}{"a", "b", "c"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				DeleteAction{"a"},
+				ReplaceAction{Old: "b", New: "B"},
+				InsertAction{"x"},
+				RetainAction(1),
+			}),
+			ExpectedItems: []interface{}{"B", "x", "c"},
+		},
+		{
+			ExistingItems: []interface{}{"a", "b"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				DeleteAction{"c"},
+			}),
+			ExpectedError: cooperate.ErrDeleteMismatch,
+			ExpectedItems: []interface{}{"a", "b"},
+		},
+		{
+			ExistingItems: []interface{}{"a", "b"},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+			}),
+			ExpectedError: cooperate.ErrDocumentSizeMismatch,
+			ExpectedItems: []interface{}{"a", "b"},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewListDocument(c.ExistingItems)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Items(), c.ExpectedItems) {
+			t.Errorf("[case %d] unexpected document: expected '%v' but got '%v'", i, c.ExpectedItems, doc.Items())
+		}
+	}
+
+}
@@ -0,0 +1,334 @@
+// Package list implements collaborative editing of an ordered sequence of
+// opaque items, such as the cards of a task board.
+package list
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/tylerchr/cooperate"
+)
+
+type (
+	// A ListHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the list operations: retain, insert, delete
+	// and replace.
+	ListHandler struct {
+		// Priority decides whose items come first when a and b insert at the
+		// same location, and whose replacement wins when both replace the
+		// same item.
+		Priority cooperate.Priority
+	}
+
+	// RetainAction moves the cursor forward a specified number of items.
+	RetainAction int
+
+	// InsertAction inserts the given items at the current location.
+	InsertAction []interface{}
+
+	// DeleteAction asserts that the given items immediately follow the
+	// cursor, and then removes them.
+	DeleteAction []interface{}
+
+	// ReplaceAction asserts that Old immediately follows the cursor, and then
+	// replaces it with New.
+	ReplaceAction struct {
+		Old, New interface{}
+	}
+)
+
+func (a RetainAction) GoString() string  { return fmt.Sprintf("R(%d)", a) }
+func (a InsertAction) GoString() string  { return fmt.Sprintf("I(%v)", []interface{}(a)) }
+func (a DeleteAction) GoString() string  { return fmt.Sprintf("D(%v)", []interface{}(a)) }
+func (a ReplaceAction) GoString() string { return fmt.Sprintf("X(%v, %v)", a.Old, a.New) }
+
+// Expand inflates a such that it affects only one item.
+func (lh ListHandler) Expand(a cooperate.Action) []cooperate.Action {
+	var actions []cooperate.Action
+	switch a := a.(type) {
+	case RetainAction:
+		for i := 0; i < int(a); i++ {
+			actions = append(actions, RetainAction(1))
+		}
+	case InsertAction:
+		for _, x := range a {
+			actions = append(actions, InsertAction{x})
+		}
+	case DeleteAction:
+		for _, x := range a {
+			actions = append(actions, DeleteAction{x})
+		}
+	case ReplaceAction:
+		actions = append(actions, a)
+	}
+	return actions
+}
+
+// Reduce combines two retains, inserts or deletes into a single action with
+// the same effect. Replacements are never combined.
+func (lh ListHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	switch a := a.(type) {
+	case RetainAction:
+		if b, ok := b.(RetainAction); ok {
+			return a + b, true
+		}
+	case InsertAction:
+		if b, ok := b.(InsertAction); ok {
+			return append(append(InsertAction(nil), a...), b...), true
+		}
+	case DeleteAction:
+		if b, ok := b.(DeleteAction); ok {
+			return append(append(DeleteAction(nil), a...), b...), true
+		}
+	}
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (lh ListHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var composedActions []cooperate.Action // new list of actions
+
+ComposeLoop:
+	for {
+
+		switch {
+
+		// a's deletes never reach b, so they can be applied immediately
+		case a.More() && peekKind(a) == del:
+			composedActions = append(composedActions, a.Consume())
+
+		// likewise, b's inserts don't depend on a at all
+		case b.More() && peekKind(b) == insert:
+			composedActions = append(composedActions, b.Consume())
+
+		// if we are out of actions from either, we are finished
+		case !a.More() || !b.More():
+			break ComposeLoop
+
+		default:
+
+			// what remains is a producing an item that b then acts on
+			switch ak, bk := peekKind(a), peekKind(b); {
+
+			case ak == unknown || bk == unknown:
+				return nil, cooperate.ErrUnknownAction
+
+			case ak == retain && bk == retain:
+				composedActions = append(composedActions, a.Consume())
+				b.Consume()
+
+			case ak == retain:
+				a.Consume()
+				composedActions = append(composedActions, b.Consume())
+
+			case bk == retain:
+				composedActions = append(composedActions, a.Consume())
+				b.Consume()
+
+			default:
+				produced := produces(a.Consume())
+				if consumed := consumes(b.Peek()); !reflect.DeepEqual(produced.item, consumed) {
+					return nil, cooperate.ErrDeleteMismatch
+				}
+
+				switch next := b.Consume().(type) {
+				case DeleteAction:
+					if produced.replaced {
+						composedActions = append(composedActions, DeleteAction{produced.old})
+					}
+				case ReplaceAction:
+					if produced.replaced {
+						composedActions = append(composedActions, ReplaceAction{Old: produced.old, New: next.New})
+					} else {
+						composedActions = append(composedActions, InsertAction{next.New})
+					}
+				}
+
+			}
+		}
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce(lh, cooperate.Operation(composedActions)), nil
+}
+
+// Transform implements cooperate.Transformer for the list operations defined
+// in this package.
+//
+// By default this implementation favors b: concurrent inserts at the same
+// location place b's items first, and if both replace the same item b's
+// replacement wins. lh.Priority may favor a instead. A replacement always wins against a concurrent delete of the same
+// item, so that an edit is never silently lost.
+func (lh ListHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	var aPrime, bPrime []cooperate.Action // new list of actions
+
+TransformLoop:
+	for {
+
+		ak, bk := peekKind(a), peekKind(b)
+
+		switch {
+
+		// if we reach the ends at the same time, we are done
+		case !a.More() && !b.More():
+			break TransformLoop
+
+		case (a.More() && ak == unknown) || (b.More() && bk == unknown):
+			return nil, nil, cooperate.ErrUnknownAction
+
+		case a.More() && ak == insert && lh.Priority == cooperate.FavorA:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, RetainAction(1))
+
+		case b.More() && bk == insert:
+			aPrime = append(aPrime, RetainAction(1))
+			bPrime = append(bPrime, b.Consume())
+
+		case a.More() && ak == insert:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, RetainAction(1))
+
+		// if either is finished, the other must have contained only inserts
+		case !a.More() || !b.More():
+			break TransformLoop
+
+		case ak == retain && bk == retain:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == retain:
+			a.Consume()
+			if bk == replace {
+				aPrime = append(aPrime, RetainAction(1))
+			}
+			bPrime = append(bPrime, b.Consume())
+
+		case bk == retain:
+			b.Consume()
+			if ak == replace {
+				bPrime = append(bPrime, RetainAction(1))
+			}
+			aPrime = append(aPrime, a.Consume())
+
+		case ak == del && bk == del:
+			a.Consume()
+			b.Consume()
+
+		case ak == del && bk == replace:
+			a.Consume()
+			aPrime = append(aPrime, RetainAction(1))
+			bPrime = append(bPrime, InsertAction{b.Consume().(ReplaceAction).New})
+
+		case ak == replace && bk == del:
+			b.Consume()
+			aPrime = append(aPrime, InsertAction{a.Consume().(ReplaceAction).New})
+			bPrime = append(bPrime, RetainAction(1))
+
+		case ak == replace && bk == replace:
+			ar, br := a.Consume().(ReplaceAction), b.Consume().(ReplaceAction)
+			if lh.Priority == cooperate.FavorA {
+				aPrime = append(aPrime, ReplaceAction{Old: br.New, New: ar.New})
+				bPrime = append(bPrime, RetainAction(1))
+			} else {
+				aPrime = append(aPrime, RetainAction(1))
+				bPrime = append(bPrime, ReplaceAction{Old: ar.New, New: br.New})
+			}
+
+		}
+
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce(lh, cooperate.Operation(aPrime)), cooperate.Reduce(lh, cooperate.Operation(bPrime)), nil
+
+}
+
+// Lengths calculates the lengths of the list op expects to be applied to
+// and the length of that list after applying op.
+func Lengths(op cooperate.Operation) (pre, post int) {
+	for _, a := range []cooperate.Action(op) {
+		switch a := a.(type) {
+		case RetainAction:
+			pre += int(a)
+			post += int(a)
+		case InsertAction:
+			post += len(a)
+		case DeleteAction:
+			pre += len(a)
+		case ReplaceAction:
+			pre++
+			post++
+		}
+	}
+	return
+}
+
+// kind identifies the list action type of a, for use in type switches over
+// pairs of actions.
+type kind int
+
+const (
+	unknown kind = iota
+	retain
+	insert
+	del
+	replace
+)
+
+func peekKind(oit *cooperate.OperationIterator) kind {
+	if !oit.More() {
+		return unknown
+	}
+	switch oit.Peek().(type) {
+	case RetainAction:
+		return retain
+	case InsertAction:
+		return insert
+	case DeleteAction:
+		return del
+	case ReplaceAction:
+		return replace
+	}
+	return unknown
+}
+
+// production describes the item that an expanded insert or replace leaves
+// in the document, and the item it replaced, if any.
+type production struct {
+	item     interface{}
+	old      interface{}
+	replaced bool
+}
+
+func produces(a cooperate.Action) production {
+	switch a := a.(type) {
+	case InsertAction:
+		return production{item: a[0]}
+	case ReplaceAction:
+		return production{item: a.New, old: a.Old, replaced: true}
+	}
+	return production{}
+}
+
+// consumes returns the item that an expanded delete or replace expects to
+// find in the document.
+func consumes(a cooperate.Action) interface{} {
+	switch a := a.(type) {
+	case DeleteAction:
+		return a[0]
+	case ReplaceAction:
+		return a.Old
+	}
+	return nil
+}
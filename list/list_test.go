@@ -0,0 +1,201 @@
+package list
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestCompose(t *testing.T) {
+
+	cases := []struct {
+		First       cooperate.Operation
+		Second      cooperate.Operation
+		Composition cooperate.Operation
+		Error       error
+	}{
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				InsertAction{"a"},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				DeleteAction{"a"},
+			}),
+			Composition: nil,
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				InsertAction{"b"},
+				RetainAction(1),
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				ReplaceAction{Old: "a", New: "A"},
+				ReplaceAction{Old: "b", New: "B"},
+				RetainAction(1),
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				ReplaceAction{Old: "a", New: "A"},
+				InsertAction{"B"},
+				RetainAction(1),
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				ReplaceAction{Old: "a", New: "b"},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				DeleteAction{"b"},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				DeleteAction{"a"},
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				InsertAction{"a"},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				DeleteAction{"b"},
+			}),
+			Error: cooperate.ErrDeleteMismatch,
+		},
+	}
+
+	var lh ListHandler
+
+	for i, c := range cases {
+
+		aa, bb := cooperate.NewOperationIterator(cooperate.Expand(lh, c.First)), cooperate.NewOperationIterator(cooperate.Expand(lh, c.Second))
+
+		if sum, err := lh.Compose(aa, bb); err != c.Error {
+			t.Errorf("[case %d] unexpected error state: expected '%v' but got '%v'", i, c.Error, err)
+		} else if !reflect.DeepEqual(sum, c.Composition) {
+			t.Errorf("[case %d] unexpected composition: expected '%#v' but got '%#v'", i, c.Composition, sum)
+		}
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	cases := []struct {
+		Items          []interface{}
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       []interface{}
+	}{
+		// concurrent inserts at the same location: b's items come first
+		{
+			Items:    []interface{}{"x"},
+			A:        cooperate.Operation{RetainAction(1), InsertAction{"a"}},
+			B:        cooperate.Operation{RetainAction(1), InsertAction{"b"}},
+			APrime:   cooperate.Operation{RetainAction(2), InsertAction{"a"}},
+			BPrime:   cooperate.Operation{RetainAction(1), InsertAction{"b"}, RetainAction(1)},
+			Expected: []interface{}{"x", "b", "a"},
+		},
+		// concurrent replacements of the same item: b wins
+		{
+			Items:    []interface{}{"x", "y"},
+			A:        cooperate.Operation{ReplaceAction{Old: "x", New: "a"}, RetainAction(1)},
+			B:        cooperate.Operation{ReplaceAction{Old: "x", New: "b"}, RetainAction(1)},
+			APrime:   cooperate.Operation{RetainAction(2)},
+			BPrime:   cooperate.Operation{ReplaceAction{Old: "a", New: "b"}, RetainAction(1)},
+			Expected: []interface{}{"b", "y"},
+		},
+		// a replacement wins against a concurrent delete
+		{
+			Items:    []interface{}{"x", "y"},
+			A:        cooperate.Operation{DeleteAction{"x"}, RetainAction(1)},
+			B:        cooperate.Operation{ReplaceAction{Old: "x", New: "b"}, RetainAction(1)},
+			APrime:   cooperate.Operation{RetainAction(2)},
+			BPrime:   cooperate.Operation{InsertAction{"b"}, RetainAction(1)},
+			Expected: []interface{}{"b", "y"},
+		},
+		// concurrent deletes of the same item
+		{
+			Items:    []interface{}{"x", "y"},
+			A:        cooperate.Operation{DeleteAction{"x", "y"}},
+			B:        cooperate.Operation{RetainAction(1), DeleteAction{"y"}},
+			APrime:   cooperate.Operation{DeleteAction{"x"}},
+			BPrime:   nil,
+			Expected: []interface{}{},
+		},
+	}
+
+	var lh ListHandler
+
+	for i, c := range cases {
+
+		a, b := cooperate.NewOperationIterator(cooperate.Expand(lh, c.A)), cooperate.NewOperationIterator(cooperate.Expand(lh, c.B))
+
+		aPrime, bPrime, err := lh.Transform(a, b)
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewListDocument(c.Items), NewListDocument(c.Items)
+		for _, step := range []struct {
+			Doc *ListDocument
+			Op  cooperate.Operation
+		}{{ab, c.A}, {ab, bPrime}, {ba, c.B}, {ba, aPrime}} {
+			if err := step.Doc.Apply(step.Op); err != nil {
+				t.Errorf("[case %d] apply error: %s", i, err)
+			}
+		}
+		if !reflect.DeepEqual(ab.Items(), c.Expected) || !reflect.DeepEqual(ba.Items(), c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%v' but got '%v' and '%v'", i, c.Expected, ab.Items(), ba.Items())
+		}
+	}
+
+}
+
+var listConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return NewListDocument([]interface{}{"x", "y", "z"}[:r.Intn(4)])
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return ListHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		items := doc.(*ListDocument).Items()
+		var op cooperate.Operation
+		for cur := 0; cur < len(items); {
+			n := 1 + r.Intn(len(items)-cur)
+			switch r.Intn(4) {
+			case 0:
+				op = append(op, RetainAction(n))
+			case 1:
+				op = append(op, DeleteAction(append([]interface{}(nil), items[cur:cur+n]...)))
+			case 2:
+				op = append(op, InsertAction{r.Intn(100)}, RetainAction(n))
+			case 3:
+				n = 1
+				op = append(op, ReplaceAction{Old: items[cur], New: r.Intn(100)})
+			}
+			cur += n
+		}
+		if len(op) == 0 || r.Intn(3) == 0 {
+			op = append(op, InsertAction{r.Intn(100)})
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		return reflect.DeepEqual(a.(*ListDocument).Items(), b.(*ListDocument).Items())
+	},
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, listConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, listConfig, 200)
+}
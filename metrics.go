@@ -0,0 +1,308 @@
+package cooperate
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// depthBuckets are the upper bounds of the transform depth histogram.
+	depthBuckets = []float64{0, 1, 2, 5, 10, 20, 50, 100, 200, 500, 1000}
+
+	// latencyBuckets are the upper bounds of the latency histograms, in
+	// seconds.
+	latencyBuckets = []float64{.00001, .00005, .0001, .0005, .001, .005, .01, .05, .1, .5, 1}
+)
+
+// Metrics collects measurements of the servers and histories that record to
+// it, and serves them over HTTP in the Prometheus text exposition format, so
+// that they can be scraped without any other service. Many servers may share
+// one Metrics. The zero Metrics is empty and ready to use, and a nil *Metrics
+// records nothing and writes metrics as if empty.
+//
+// The history size of a document is read from the function given to
+// ObserveHistory, if any, whenever the metrics are written. Otherwise it is
+// the revision produced by the last operation its server committed.
+//
+// Errors reading or storing history are counted apart from the operations
+// servers reject, since they are no fault of the operations.
+type Metrics struct {
+	mu sync.Mutex
+
+	committed     uint64
+	rejected      map[string]uint64
+	historyErrors uint64
+	sessions      int64
+	historySize   map[string]int
+	historyFuncs  map[string]func() (int, error)
+
+	depth, compose, transform, store histogram
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	m := &Metrics{}
+	m.init()
+	return m
+}
+
+// init prepares the maps and histograms of a zero Metrics. m.mu must be
+// held.
+func (m *Metrics) init() {
+	if m.rejected != nil {
+		return
+	}
+	m.rejected = make(map[string]uint64)
+	m.historySize = make(map[string]int)
+	m.historyFuncs = make(map[string]func() (int, error))
+	m.depth.bounds = depthBuckets
+	m.compose.bounds = latencyBuckets
+	m.transform.bounds = latencyBuckets
+	m.store.bounds = latencyBuckets
+}
+
+// SessionConnected records that a session has connected. Servers do not
+// know of connections, so the transport is responsible for calling it.
+func (m *Metrics) SessionConnected() { m.addSessions(1) }
+
+// SessionDisconnected records that a session has disconnected.
+func (m *Metrics) SessionDisconnected() { m.addSessions(-1) }
+
+func (m *Metrics) addSessions(n int64) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sessions += n
+}
+
+// ObserveHistory has m report size() as the size of the history of
+// document, such as the ReadSequenceNumber method of a sqlhistory.History,
+// so that it is known even before a server commits to it. size is called
+// whenever the metrics are written, which may be concurrently with servers.
+func (m *Metrics) ObserveHistory(document string, size func() (int, error)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.historyFuncs[document] = size
+}
+
+// committedOp records an operation committed to document, that was depth
+// revisions behind and produced revision seqno.
+func (m *Metrics) committedOp(document string, depth, seqno int) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.committed++
+	m.depth.observe(float64(depth))
+	m.historySize[document] = seqno
+}
+
+// rejectedOp records an operation rejected with err.
+func (m *Metrics) rejectedOp(err error) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.rejected[reason(err)]++
+}
+
+// historyFailed records an error reading or storing history.
+func (m *Metrics) historyFailed() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	m.historyErrors++
+}
+
+// since records the time elapsed since start in h.
+func (m *Metrics) since(h func(m *Metrics) *histogram, start time.Time) {
+	if m == nil {
+		return
+	}
+	d := time.Since(start)
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.init()
+	h(m).observe(d.Seconds())
+}
+
+func composeLatency(m *Metrics) *histogram   { return &m.compose }
+func transformLatency(m *Metrics) *histogram { return &m.transform }
+func storeLatency(m *Metrics) *histogram     { return &m.store }
+
+// reason classifies the error an operation was rejected with.
+func reason(err error) string {
+	var authErr *AuthorizationError
+	switch {
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrOperationTooLarge):
+		return "operation_too_large"
+	case errors.Is(err, ErrDocumentTooLarge):
+		return "document_too_large"
+	case errors.As(err, &authErr):
+		return "unauthorized"
+	case errors.Is(err, ErrStaleSubmission):
+		return "stale_submission"
+	}
+	return "invalid"
+}
+
+// ServeHTTP writes the metrics in the text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	m.WriteTo(w)
+}
+
+// WriteTo writes the metrics to w in the text exposition format.
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+
+	if m == nil {
+		m = &Metrics{}
+	}
+
+	// read the observed histories without holding up servers
+	m.mu.Lock()
+	m.init()
+	funcs := make(map[string]func() (int, error), len(m.historyFuncs))
+	for k, f := range m.historyFuncs {
+		funcs[k] = f
+	}
+	m.mu.Unlock()
+
+	sizes := make(map[string]int, len(funcs))
+	var failed uint64
+	for k, f := range funcs {
+		if size, err := f(); err == nil {
+			sizes[k] = size
+		} else {
+			failed++
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.historyErrors += failed
+	for k, size := range m.historySize {
+		if _, ok := funcs[k]; !ok {
+			sizes[k] = size
+		}
+	}
+
+	var b strings.Builder
+
+	header(&b, "cooperate_operations_committed_total", "counter", "Operations committed by servers.")
+	fmt.Fprintf(&b, "cooperate_operations_committed_total %d\n", m.committed)
+
+	header(&b, "cooperate_operations_rejected_total", "counter", "Operations rejected by servers, by reason.")
+	for _, k := range sortedKeys(m.rejected) {
+		fmt.Fprintf(&b, "cooperate_operations_rejected_total{reason=%s} %d\n", quote(k), m.rejected[k])
+	}
+
+	header(&b, "cooperate_history_errors_total", "counter", "Errors reading or storing history.")
+	fmt.Fprintf(&b, "cooperate_history_errors_total %d\n", m.historyErrors)
+
+	header(&b, "cooperate_transform_depth", "histogram", "Revisions committed since the root of each operation.")
+	m.depth.write(&b, "cooperate_transform_depth")
+
+	header(&b, "cooperate_compose_duration_seconds", "histogram", "Time taken to compose operations.")
+	m.compose.write(&b, "cooperate_compose_duration_seconds")
+
+	header(&b, "cooperate_transform_duration_seconds", "histogram", "Time taken to transform operations.")
+	m.transform.write(&b, "cooperate_transform_duration_seconds")
+
+	header(&b, "cooperate_history_store_duration_seconds", "histogram", "Time taken to store operations in history.")
+	m.store.write(&b, "cooperate_history_store_duration_seconds")
+
+	header(&b, "cooperate_sessions_connected", "gauge", "Sessions connected.")
+	fmt.Fprintf(&b, "cooperate_sessions_connected %d\n", m.sessions)
+
+	header(&b, "cooperate_history_size", "gauge", "Operations in the history of each document.")
+	for _, k := range sortedKeys(sizes) {
+		fmt.Fprintf(&b, "cooperate_history_size{document=%s} %d\n", quote(k), sizes[k])
+	}
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+
+}
+
+func header(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns s as a label value.
+func quote(s string) string {
+	return `"` + labelEscaper.Replace(s) + `"`
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// A histogram counts observations into buckets with the upper bounds
+// bounds, as a Prometheus histogram.
+type histogram struct {
+	bounds []float64
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+func (h *histogram) observe(v float64) {
+	if h.counts == nil {
+		h.counts = make([]uint64, len(h.bounds))
+	}
+	for i, bound := range h.bounds {
+		if v <= bound {
+			h.counts[i]++
+		}
+	}
+	h.count++
+	h.sum += v
+}
+
+func (h *histogram) write(b *strings.Builder, name string) {
+	for i, bound := range h.bounds {
+		var n uint64
+		if h.counts != nil {
+			n = h.counts[i]
+		}
+		fmt.Fprintf(b, "%s_bucket{le=%s} %d\n", name, quote(formatFloat(bound)), n)
+	}
+	fmt.Fprintf(b, "%s_bucket{le=\"+Inf\"} %d\n", name, h.count)
+	fmt.Fprintf(b, "%s_sum %s\n", name, formatFloat(h.sum))
+	fmt.Fprintf(b, "%s_count %d\n", name, h.count)
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
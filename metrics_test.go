@@ -0,0 +1,177 @@
+package cooperate_test
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func TestMetrics(t *testing.T) {
+
+	m := cooperate.NewMetrics()
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.DedupMemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Sizer:              text.TextHandler{},
+		ID:                 `say "hi"`,
+		Limits:             cooperate.Limits{MaxOperationSize: 3},
+		Metrics:            m,
+	}
+
+	m.SessionConnected()
+	m.SessionConnected()
+	m.SessionDisconnected()
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{text.InsertAction("a")}},
+		{Root: 0, Operation: cooperate.Operation{text.InsertAction("b")}},
+		{Root: 0, Operation: cooperate.Operation{text.InsertAction("c")}},
+		{Root: 3, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("toolong")}},
+		{Root: 3, Operation: cooperate.Operation{text.RetainAction(4)}},
+	}
+	for _, op := range ops {
+		s.Apply(op.Root, op.Operation)
+	}
+
+	s.Submit(cooperate.Submission{ClientID: "ada", Seq: 2, Root: 3, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}})
+	s.Submit(cooperate.Submission{ClientID: "ada", Seq: 1, Root: 3, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}})
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type: %s", ct)
+	}
+
+	body := rec.Body.String()
+	for _, line := range []string{
+		"# TYPE cooperate_operations_committed_total counter",
+		"cooperate_operations_committed_total 4",
+		`cooperate_operations_rejected_total{reason="invalid"} 1`,
+		`cooperate_operations_rejected_total{reason="operation_too_large"} 1`,
+		`cooperate_operations_rejected_total{reason="stale_submission"} 1`,
+		"# TYPE cooperate_transform_depth histogram",
+		`cooperate_transform_depth_bucket{le="0"} 2`,
+		`cooperate_transform_depth_bucket{le="1"} 3`,
+		`cooperate_transform_depth_bucket{le="2"} 4`,
+		`cooperate_transform_depth_bucket{le="+Inf"} 4`,
+		"cooperate_transform_depth_sum 3",
+		"cooperate_transform_duration_seconds_count 2",
+		"cooperate_history_store_duration_seconds_count 4",
+		"cooperate_sessions_connected 1",
+		`cooperate_history_size{document="say \"hi\""} 4`,
+	} {
+		if !strings.Contains(body, line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, body)
+		}
+	}
+
+}
+
+func TestMetrics_Zero(t *testing.T) {
+
+	var m cooperate.Metrics
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		ID:                 "notes",
+		Metrics:            &m,
+	}
+	s.Apply(0, cooperate.Operation{text.InsertAction("a")})
+	s.Apply(0, cooperate.Operation{text.RetainAction(1)})
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	for _, line := range []string{
+		"cooperate_operations_committed_total 1",
+		`cooperate_operations_rejected_total{reason="invalid"} 1`,
+		`cooperate_transform_depth_bucket{le="0"} 1`,
+		`cooperate_history_size{document="notes"} 1`,
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, b.String())
+		}
+	}
+
+}
+
+func TestMetrics_Nil(t *testing.T) {
+
+	var m *cooperate.Metrics
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if body := rec.Body.String(); !strings.Contains(body, "cooperate_operations_committed_total 0\n") || !strings.Contains(body, `cooperate_transform_depth_bucket{le="0"} 0`+"\n") {
+		t.Errorf("unexpected metrics:\n%s", body)
+	}
+
+}
+
+func TestMetrics_History(t *testing.T) {
+
+	m := cooperate.NewMetrics()
+
+	h := &failingHistory{MemoryHistory: &cooperate.MemoryHistory{}}
+	h.Store(cooperate.Operation{text.InsertAction("ab")})
+	h.Store(cooperate.Operation{text.RetainAction(2), text.InsertAction("c")})
+
+	m.ObserveHistory("notes", func() (int, error) { return h.SequenceNumber(), nil })
+	m.ObserveHistory("gone", func() (int, error) { return 0, errStore })
+
+	var b strings.Builder
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for _, line := range []string{
+		`cooperate_history_size{document="notes"} 2`,
+		"cooperate_history_errors_total 1",
+	} {
+		if !strings.Contains(b.String(), line+"\n") {
+			t.Errorf("missing line %q in:\n%s", line, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `document="gone"`) {
+		t.Errorf("unexpected size for unreadable history in:\n%s", b.String())
+	}
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument("abc"),
+		History:            h,
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		ID:                 "notes",
+		Metrics:            m,
+	}
+	h.fail = true
+	if err := s.Apply(2, cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}); err != errStore {
+		t.Fatalf("unexpected error: expected '%v' but got '%v'", errStore, err)
+	}
+
+	b.Reset()
+	if _, err := m.WriteTo(&b); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !strings.Contains(b.String(), "cooperate_history_errors_total 3\n") {
+		t.Errorf("missing history errors in:\n%s", b.String())
+	}
+	if strings.Contains(b.String(), "cooperate_operations_rejected_total{") {
+		t.Errorf("unexpected rejection in:\n%s", b.String())
+	}
+
+}
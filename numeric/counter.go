@@ -0,0 +1,114 @@
+// Package numeric implements two of the smallest useful collaborative
+// documents: a counter and a register.
+//
+// They are intended as reference implementations of the interfaces in
+// package cooperate as much as for their own sake.
+package numeric
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+)
+
+type (
+	// A CounterDocument is an integer that implements the cooperate.Document
+	// interface. It changes only by increments, which commute.
+	CounterDocument struct {
+		value int64
+	}
+
+	// A CounterHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for counter operations.
+	CounterHandler struct{}
+
+	// IncrementAction adds its value, which may be negative, to a counter.
+	IncrementAction int64
+)
+
+func (a IncrementAction) GoString() string { return fmt.Sprintf("Inc(%d)", a) }
+
+// NewCounterDocument initializes a CounterDocument with a starting value of
+// initial.
+func NewCounterDocument(initial int64) *CounterDocument {
+	return &CounterDocument{value: initial}
+}
+
+// Value returns the current value of the counter.
+func (cd *CounterDocument) Value() int64 {
+	return cd.value
+}
+
+// Apply performs op against the CounterDocument.
+func (cd *CounterDocument) Apply(op cooperate.Operation) error {
+	sum, err := sumIncrements(op)
+	if err != nil {
+		return err
+	}
+	cd.value += sum
+	return nil
+}
+
+// Expand returns a unchanged, since an increment is already atomic.
+func (ch CounterHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce adds two increments together.
+func (ch CounterHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	ai, aok := a.(IncrementAction)
+	bi, bok := b.(IncrementAction)
+	if !aok || !bok {
+		return nil, false
+	}
+	return ai + bi, true
+}
+
+// Compose merges a and b into a single increment by the sum of both.
+func (ch CounterHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var sum IncrementAction
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			inc, ok := it.Consume().(IncrementAction)
+			if !ok {
+				return nil, cooperate.ErrUnknownAction
+			}
+			sum += inc
+		}
+	}
+
+	return cooperate.Operation{sum}, nil
+
+}
+
+// Transform returns a and b unchanged: since increments commute, applying a
+// then b has the same effect as applying b then a.
+func (ch CounterHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	aa, bb = cooperate.Operation(a.Actions[a.Cursor:]), cooperate.Operation(b.Actions[b.Cursor:])
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			if _, ok := it.Consume().(IncrementAction); !ok {
+				return nil, nil, cooperate.ErrUnknownAction
+			}
+		}
+	}
+
+	return aa, bb, nil
+
+}
+
+func sumIncrements(op cooperate.Operation) (int64, error) {
+	var sum int64
+	for _, a := range op {
+		inc, ok := a.(IncrementAction)
+		if !ok {
+			return 0, cooperate.ErrUnknownAction
+		}
+		sum += int64(inc)
+	}
+	return sum, nil
+}
@@ -0,0 +1,136 @@
+package numeric
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestCounter(t *testing.T) {
+
+	doc := NewCounterDocument(10)
+
+	s := &cooperate.Server{
+		Document:           doc,
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      CounterHandler{},
+		ComposeTransformer: CounterHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{IncrementAction(1)}},
+		{Root: 0, Operation: cooperate.Operation{IncrementAction(5)}},
+		{Root: 1, Operation: cooperate.Operation{IncrementAction(-2), IncrementAction(-2)}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if doc.Value() != 12 {
+		t.Errorf("unexpected counter: expected %d but got %d", 12, doc.Value())
+	}
+
+}
+
+func TestRegister(t *testing.T) {
+
+	doc := NewRegisterDocument(0)
+
+	s := &cooperate.Server{
+		Document:           doc,
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      RegisterHandler{},
+		ComposeTransformer: RegisterHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{SetAction(1)}},
+		{Root: 0, Operation: cooperate.Operation{SetAction(2)}},
+		{Root: 0, Operation: cooperate.Operation{}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if doc.Value() != 2 {
+		t.Errorf("unexpected register: expected %v but got %v", 2, doc.Value())
+	}
+
+}
+
+func TestClient_Counter(t *testing.T) {
+
+	client := &cooperate.Client{
+		Document:           NewCounterDocument(0),
+		ExpandReducer:      CounterHandler{},
+		ComposeTransformer: CounterHandler{},
+	}
+
+	for _, op := range []cooperate.Operation{{IncrementAction(1)}, {IncrementAction(2)}, {IncrementAction(3)}} {
+		if err := client.ApplyLocal(op); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if err := client.ApplyReceived(cooperate.Operation{IncrementAction(10)}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+
+	if value := client.Document.(*CounterDocument).Value(); value != 16 {
+		t.Errorf("unexpected counter: expected %d but got %d", 16, value)
+	}
+
+	if expected := (cooperate.Operation{IncrementAction(5)}); !reflect.DeepEqual(client.Buffer, expected) {
+		t.Errorf("unexpected buffered operation: expected '%#v' but got '%#v'", expected, client.Buffer)
+	}
+
+}
+
+var counterConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return NewCounterDocument(int64(r.Intn(10)))
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return CounterHandler{}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		return cooperate.Operation{IncrementAction(r.Intn(21) - 10)}
+	},
+}
+
+var registerConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return NewRegisterDocument(float64(r.Intn(10)))
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return RegisterHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		return cooperate.Operation{SetAction(r.Intn(10))}
+	},
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, counterConfig, 2000)
+	ottest.Transform(t, registerConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, counterConfig, 200)
+	ottest.Converge(t, registerConfig, 200)
+}
@@ -0,0 +1,127 @@
+package numeric
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+)
+
+type (
+	// A RegisterDocument is a number that implements the cooperate.Document
+	// interface. It changes only by being overwritten.
+	RegisterDocument struct {
+		value float64
+	}
+
+	// A RegisterHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for register operations.
+	RegisterHandler struct {
+		// Priority decides whose value survives when a and b both set the
+		// register.
+		Priority cooperate.Priority
+	}
+
+	// SetAction overwrites a register with its value.
+	SetAction float64
+)
+
+func (a SetAction) GoString() string { return fmt.Sprintf("Set(%v)", float64(a)) }
+
+// NewRegisterDocument initializes a RegisterDocument with a starting value
+// of initial.
+func NewRegisterDocument(initial float64) *RegisterDocument {
+	return &RegisterDocument{value: initial}
+}
+
+// Value returns the current value of the register.
+func (rd *RegisterDocument) Value() float64 {
+	return rd.value
+}
+
+// Apply performs op against the RegisterDocument.
+func (rd *RegisterDocument) Apply(op cooperate.Operation) error {
+	last, ok, err := lastSet(cooperate.NewOperationIterator(op))
+	if err != nil {
+		return err
+	} else if ok {
+		rd.value = float64(last)
+	}
+	return nil
+}
+
+// Expand returns a unchanged, since a set is already atomic.
+func (rh RegisterHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce merges two sets, of which only the later one, b, has any effect.
+func (rh RegisterHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	_, aok := a.(SetAction)
+	_, bok := b.(SetAction)
+	if !aok || !bok {
+		return nil, false
+	}
+	return b, true
+}
+
+// Compose merges a and b into a single set of the last value written.
+func (rh RegisterHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	aLast, aok, err := lastSet(a)
+	if err != nil {
+		return nil, err
+	}
+
+	bLast, bok, err := lastSet(b)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case bok:
+		return cooperate.Operation{bLast}, nil
+	case aok:
+		return cooperate.Operation{aLast}, nil
+	}
+	return nil, nil
+
+}
+
+// Transform implements cooperate.Transformer for register operations.
+//
+// By default this implementation favors b: if both a and b set the register,
+// a' is empty so that b's value survives whichever order they are applied in.
+// rh.Priority may favor a instead.
+func (rh RegisterHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	aLast, aok, err := lastSet(a)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	bLast, bok, err := lastSet(b)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch {
+	case aok && rh.Priority == cooperate.FavorA:
+		return cooperate.Operation{aLast}, nil, nil
+	case bok:
+		return nil, cooperate.Operation{bLast}, nil
+	case aok:
+		return cooperate.Operation{aLast}, nil, nil
+	}
+	return nil, nil, nil
+
+}
+
+// lastSet consumes it and returns its final set, if it has one.
+func lastSet(it *cooperate.OperationIterator) (last SetAction, ok bool, err error) {
+	for it.More() {
+		if last, ok = it.Consume().(SetAction); !ok {
+			return 0, false, cooperate.ErrUnknownAction
+		}
+	}
+	return
+}
@@ -0,0 +1,17 @@
+package cooperate
+
+// A Priority decides which of two operations a Transformer favors when their
+// changes conflict, such as concurrent insertions at the same location or
+// concurrent replacements of the same value.
+type Priority int
+
+const (
+	// FavorB favors b.
+	FavorB Priority = iota
+
+	// FavorA favors a. Since a Client transforms its own pending operations
+	// as a, while a Server transforms incoming operations as b, a Client
+	// whose Server uses FavorB should use FavorA so that they resolve
+	// conflicts alike.
+	FavorA
+)
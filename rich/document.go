@@ -0,0 +1,131 @@
+package rich
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// A Run is a span of text that is formatted uniformly.
+type Run struct {
+	Text       string
+	Attributes Attributes
+}
+
+// A RichTextDocument is formatted text that implements the
+// cooperate.Document interface.
+type RichTextDocument struct {
+	runs []Run
+}
+
+// NewRichTextDocument initializes a RichTextDocument with a starting value
+// of initial, which may be empty.
+func NewRichTextDocument(initial ...Run) *RichTextDocument {
+	rd := &RichTextDocument{}
+	for _, r := range initial {
+		rd.runs = appendRun(rd.runs, r)
+	}
+	return rd
+}
+
+// Runs returns the current contents of the document as a list of maximal
+// runs of uniformly formatted text. The returned slice must not be modified.
+func (rd *RichTextDocument) Runs() []Run {
+	return rd.runs
+}
+
+// String returns the current contents of the document without formatting.
+func (rd *RichTextDocument) String() string {
+	var sb strings.Builder
+	for _, r := range rd.runs {
+		sb.WriteString(r.Text)
+	}
+	return sb.String()
+}
+
+// Len returns the length of the document in runes.
+func (rd *RichTextDocument) Len() int {
+	var n int
+	for _, r := range rd.runs {
+		n += utf8.RuneCountInString(r.Text)
+	}
+	return n
+}
+
+// Apply performs op against the RichTextDocument.
+func (rd *RichTextDocument) Apply(op cooperate.Operation) error {
+
+	// verify that operation will apply cleanly to document
+	if pre, _ := Lengths(op); rd.Len() != pre {
+		return cooperate.ErrDocumentSizeMismatch
+	}
+
+	var runs []Run
+	cur := &runCursor{runs: rd.runs}
+
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			for _, r := range cur.take(a.N) {
+				runs = appendRun(runs, Run{Text: r.Text, Attributes: r.Attributes.Compose(a.Attributes, false)})
+			}
+		case InsertAction:
+			runs = appendRun(runs, Run{Text: a.Text, Attributes: a.Attributes})
+		case DeleteAction:
+			cur.take(int(a))
+		default:
+			return cooperate.ErrUnknownAction
+		}
+	}
+
+	rd.runs = runs
+	return nil
+
+}
+
+// appendRun adds r to the end of runs, merging it with the last run if both
+// are formatted identically.
+func appendRun(runs []Run, r Run) []Run {
+	r.Attributes = r.Attributes.normalize()
+	if r.Text == "" {
+		return runs
+	}
+	if n := len(runs); n > 0 && runs[n-1].Attributes.equal(r.Attributes) {
+		runs[n-1].Text += r.Text
+		return runs
+	}
+	return append(runs, r)
+}
+
+// runCursor reads successive spans of runes from a list of runs.
+type runCursor struct {
+	runs   []Run
+	offset int // byte offset into runs[0]
+}
+
+// take consumes the next n runes, returning them as runs.
+func (rc *runCursor) take(n int) []Run {
+	var taken []Run
+	for n > 0 && len(rc.runs) > 0 {
+		r := rc.runs[0]
+		text := r.Text[rc.offset:]
+
+		end := len(text)
+		if count := utf8.RuneCountInString(text); count > n {
+			end = 0
+			for i := 0; i < n; i++ {
+				_, size := utf8.DecodeRuneInString(text[end:])
+				end += size
+			}
+			n = 0
+			rc.offset += end
+		} else {
+			n -= count
+			rc.runs, rc.offset = rc.runs[1:], 0
+		}
+
+		taken = append(taken, Run{Text: text[:end], Attributes: r.Attributes})
+	}
+	return taken
+}
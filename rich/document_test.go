@@ -0,0 +1,72 @@
+package rich
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestRichTextDocument(t *testing.T) {
+
+	cases := []struct {
+		Existing      []Run
+		Operation     cooperate.Operation
+		ExpectedError error
+		ExpectedRuns  []Run
+	}{
+		{
+			Operation: cooperate.Operation([]cooperate.Action{
+				InsertAction{Text: "Hello "},
+				InsertAction{Text: "world", Attributes: Attributes{Bold: true}},
+			}),
+			ExpectedRuns: []Run{
+				{Text: "Hello "},
+				{Text: "world", Attributes: Attributes{Bold: true}},
+			},
+		},
+		{
+			Existing: []Run{{Text: "Hello world"}},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 6},
+				RetainAction{N: 5, Attributes: Attributes{Italic: true}},
+			}),
+			ExpectedRuns: []Run{
+				{Text: "Hello "},
+				{Text: "world", Attributes: Attributes{Italic: true}},
+			},
+		},
+		{
+			Existing: []Run{{Text: "héllo", Attributes: Attributes{Bold: true}}},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 2, Attributes: Attributes{Bold: nil}},
+				DeleteAction(1),
+				RetainAction{N: 2},
+			}),
+			ExpectedRuns: []Run{
+				{Text: "hé"},
+				{Text: "lo", Attributes: Attributes{Bold: true}},
+			},
+		},
+		{
+			Existing: []Run{{Text: "abc"}},
+			Operation: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 2},
+			}),
+			ExpectedError: cooperate.ErrDocumentSizeMismatch,
+			ExpectedRuns:  []Run{{Text: "abc"}},
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewRichTextDocument(c.Existing...)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Runs(), c.ExpectedRuns) {
+			t.Errorf("[case %d] unexpected document: expected '%#v' but got '%#v'", i, c.ExpectedRuns, doc.Runs())
+		}
+	}
+
+}
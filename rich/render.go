@@ -0,0 +1,210 @@
+package rich
+
+import (
+	"fmt"
+	"html"
+	"net/url"
+	"strings"
+)
+
+// The attributes understood by HTML and Markdown. Others are ignored.
+const (
+	Bold      = "bold"      // true to embolden text
+	Italic    = "italic"    // true to italicize text
+	Underline = "underline" // true to underline text; not rendered in Markdown
+	Strike    = "strike"    // true to strike through text
+	Code      = "code"      // true to format text as inline code
+	Link      = "link"      // the URL that text links to
+	Header    = "header"    // on a newline, the heading level of its line
+)
+
+// a line is a run of text up to a newline, along with the attributes of the
+// newline that formats it as a block.
+type line struct {
+	runs       []Run
+	attributes Attributes
+}
+
+func (rd *RichTextDocument) lines() []line {
+	var lines []line
+	var current line
+	for _, r := range rd.runs {
+		parts := strings.Split(r.Text, "\n")
+		for i, part := range parts {
+			if part != "" {
+				current.runs = append(current.runs, Run{Text: part, Attributes: r.Attributes})
+			}
+			if i < len(parts)-1 {
+				current.attributes = r.Attributes
+				lines = append(lines, current)
+				current = line{}
+			}
+		}
+	}
+	if len(current.runs) > 0 {
+		lines = append(lines, current)
+	}
+	return lines
+}
+
+// HTML renders the document as a sequence of HTML block elements, one per
+// line.
+func (rd *RichTextDocument) HTML() string {
+
+	var sb strings.Builder
+
+	for _, l := range rd.lines() {
+
+		tag := "p"
+		if level := headerLevel(l.attributes); level > 0 {
+			tag = fmt.Sprintf("h%d", level)
+		}
+
+		sb.WriteString("<" + tag + ">")
+		if len(l.runs) == 0 {
+			sb.WriteString("<br>")
+		}
+		for _, r := range l.runs {
+			text := html.EscapeString(r.Text)
+			for _, f := range []struct{ attr, tag string }{
+				{Code, "code"},
+				{Strike, "s"},
+				{Underline, "u"},
+				{Italic, "em"},
+				{Bold, "strong"},
+			} {
+				if isSet(r.Attributes, f.attr) {
+					text = "<" + f.tag + ">" + text + "</" + f.tag + ">"
+				}
+			}
+			if href, ok := link(r.Attributes); ok {
+				text = `<a href="` + html.EscapeString(href) + `">` + text + "</a>"
+			}
+			sb.WriteString(text)
+		}
+		sb.WriteString("</" + tag + ">")
+
+	}
+
+	return sb.String()
+
+}
+
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"`", "\\`",
+	"*", `\*`,
+	"_", `\_`,
+	"[", `\[`,
+	"]", `\]`,
+)
+
+// hrefEscaper escapes a URL for use as the destination of a Markdown link.
+var hrefEscaper = strings.NewReplacer(
+	`\`, `\\`,
+	"(", `\(`,
+	")", `\)`,
+	"<", `\<`,
+	">", `\>`,
+	" ", "%20",
+)
+
+// Markdown renders the document as Markdown, one line per line of the
+// document.
+func (rd *RichTextDocument) Markdown() string {
+
+	var lines []string
+
+	for _, l := range rd.lines() {
+
+		var sb strings.Builder
+		if level := headerLevel(l.attributes); level > 0 {
+			sb.WriteString(strings.Repeat("#", level) + " ")
+		}
+
+		for _, r := range l.runs {
+			text := markdownEscaper.Replace(r.Text)
+			if isSet(r.Attributes, Code) {
+				text = codeSpan(r.Text)
+			}
+			for _, f := range []struct{ attr, marker string }{
+				{Strike, "~~"},
+				{Italic, "_"},
+				{Bold, "**"},
+			} {
+				if isSet(r.Attributes, f.attr) {
+					text = f.marker + text + f.marker
+				}
+			}
+			if href, ok := link(r.Attributes); ok {
+				text = "[" + text + "](" + hrefEscaper.Replace(href) + ")"
+			}
+			sb.WriteString(text)
+		}
+
+		lines = append(lines, sb.String())
+
+	}
+
+	return strings.Join(lines, "\n")
+
+}
+
+// codeSpan formats text as a Markdown code span, delimited by more backticks
+// than text contains in a row, since backslashes do not escape within it.
+func codeSpan(text string) string {
+	var longest, run int
+	for _, c := range text {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	fence := strings.Repeat("`", longest+1)
+	if strings.HasPrefix(text, "`") || strings.HasSuffix(text, "`") {
+		text = " " + text + " "
+	}
+	return fence + text + fence
+}
+
+// link returns the URL in attrs that text links to. Only http, https and
+// mailto URLs are linked, so that a document cannot run scripts where it is
+// rendered.
+func link(attrs Attributes) (string, bool) {
+	href, ok := attrs[Link].(string)
+	if !ok {
+		return "", false
+	}
+	u, err := url.Parse(href)
+	if err != nil {
+		return "", false
+	}
+	switch u.Scheme {
+	case "http", "https", "mailto":
+		return href, true
+	}
+	return "", false
+}
+
+func isSet(attrs Attributes, name string) bool {
+	v, ok := attrs[name].(bool)
+	return ok && v
+}
+
+// headerLevel returns the heading level in attrs, accepting both ints and
+// the float64s produced by decoding JSON.
+func headerLevel(attrs Attributes) int {
+	var level int
+	switch v := attrs[Header].(type) {
+	case int:
+		level = v
+	case float64:
+		level = int(v)
+	}
+	if level > 6 {
+		return 6
+	}
+	return level
+}
@@ -0,0 +1,49 @@
+package rich
+
+import "testing"
+
+func TestRender(t *testing.T) {
+
+	doc := NewRichTextDocument(
+		Run{Text: "Title"},
+		Run{Text: "\n", Attributes: Attributes{Header: 1}},
+		Run{Text: "Some "},
+		Run{Text: "bold", Attributes: Attributes{Bold: true}},
+		Run{Text: " & "},
+		Run{Text: "linked", Attributes: Attributes{Link: "https://example.com", Italic: true}},
+		Run{Text: " text_\n\nend"},
+	)
+
+	if expected := `<h1>Title</h1><p>Some <strong>bold</strong> &amp; <a href="https://example.com"><em>linked</em></a> text_</p><p><br></p><p>end</p>`; doc.HTML() != expected {
+		t.Errorf("unexpected HTML:\nexpected %s\n     got %s", expected, doc.HTML())
+	}
+
+	if expected := "# Title\nSome **bold** & [_linked_](https://example.com) text\\_\n\nend"; doc.Markdown() != expected {
+		t.Errorf("unexpected Markdown:\nexpected %q\n     got %q", expected, doc.Markdown())
+	}
+
+}
+
+func TestRender_Escaping(t *testing.T) {
+
+	doc := NewRichTextDocument(
+		Run{Text: "run", Attributes: Attributes{Link: "javascript:alert(1)"}},
+		Run{Text: " "},
+		Run{Text: "mail", Attributes: Attributes{Link: "mailto:a@example.com"}},
+		Run{Text: " "},
+		Run{Text: "wiki", Attributes: Attributes{Link: "https://example.com/a_(b) c"}},
+		Run{Text: " "},
+		Run{Text: "a `b` c", Attributes: Attributes{Code: true}},
+		Run{Text: " "},
+		Run{Text: "`", Attributes: Attributes{Code: true}},
+	)
+
+	if expected := `<p>run <a href="mailto:a@example.com">mail</a> <a href="https://example.com/a_(b) c">wiki</a> <code>a ` + "`b`" + ` c</code> <code>` + "`" + `</code></p>`; doc.HTML() != expected {
+		t.Errorf("unexpected HTML:\nexpected %s\n     got %s", expected, doc.HTML())
+	}
+
+	if expected := "run [mail](mailto:a@example.com) [wiki](https://example.com/a_\\(b\\)%20c) ``a `b` c`` `` ` ``"; doc.Markdown() != expected {
+		t.Errorf("unexpected Markdown:\nexpected %q\n     got %q", expected, doc.Markdown())
+	}
+
+}
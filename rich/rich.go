@@ -0,0 +1,320 @@
+// Package rich implements collaborative editing of formatted text, modelled
+// on Quill's Delta format.
+//
+// Inserts carry the attributes of the inserted text, and retains may carry
+// attributes to apply to the retained text. An attribute whose value is nil
+// in a retain removes that attribute. Lengths are measured in runes.
+package rich
+
+import (
+	"fmt"
+	"reflect"
+	"unicode/utf8"
+
+	"github.com/tylerchr/cooperate"
+)
+
+type (
+	// A RichTextHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the rich text operations: retain, insert
+	// and delete.
+	RichTextHandler struct {
+		// Priority decides whose text comes first when a and b insert at the
+		// same location, and whose value wins when both set the same
+		// attribute.
+		Priority cooperate.Priority
+	}
+
+	// Attributes describe the formatting of text, such as {"bold": true} or
+	// {"link": "https://example.com"}. Formats that apply to a whole line,
+	// such as "header", are attached to the newline that ends the line.
+	Attributes map[string]interface{}
+
+	// RetainAction moves the cursor forward N runes, applying Attributes to
+	// them if any are given.
+	RetainAction struct {
+		N          int
+		Attributes Attributes
+	}
+
+	// InsertAction inserts Text, formatted with Attributes, at the current
+	// location.
+	InsertAction struct {
+		Text       string
+		Attributes Attributes
+	}
+
+	// DeleteAction removes the given number of runes following the cursor.
+	DeleteAction int
+)
+
+func (a RetainAction) GoString() string {
+	if len(a.Attributes) > 0 {
+		return fmt.Sprintf("R(%d, %v)", a.N, map[string]interface{}(a.Attributes))
+	}
+	return fmt.Sprintf("R(%d)", a.N)
+}
+
+func (a InsertAction) GoString() string {
+	if len(a.Attributes) > 0 {
+		return fmt.Sprintf("I(%s, %v)", a.Text, map[string]interface{}(a.Attributes))
+	}
+	return fmt.Sprintf("I(%s)", a.Text)
+}
+
+func (a DeleteAction) GoString() string { return fmt.Sprintf("D(%d)", a) }
+
+// Compose returns the attributes that result from applying b on top of a. If
+// keepNull is set, removals in a and b are retained in the result.
+func (a Attributes) Compose(b Attributes, keepNull bool) Attributes {
+	composed := make(Attributes)
+	for k, v := range b {
+		if v != nil || keepNull {
+			composed[k] = v
+		}
+	}
+	for k, v := range a {
+		if _, ok := b[k]; !ok && (v != nil || keepNull) {
+			composed[k] = v
+		}
+	}
+	return composed.normalize()
+}
+
+// Transform returns b transformed against a concurrent change a. If
+// priority is set, a's changes win where both set the same attribute.
+func (a Attributes) Transform(b Attributes, priority bool) Attributes {
+	if a == nil || !priority {
+		return b.normalize()
+	}
+	transformed := make(Attributes)
+	for k, v := range b {
+		if _, ok := a[k]; !ok {
+			transformed[k] = v
+		}
+	}
+	return transformed.normalize()
+}
+
+// normalize returns nil for empty attributes, so that formatting can be
+// compared with reflect.DeepEqual.
+func (a Attributes) normalize() Attributes {
+	if len(a) == 0 {
+		return nil
+	}
+	return a
+}
+
+func (a Attributes) equal(b Attributes) bool {
+	return reflect.DeepEqual(a.normalize(), b.normalize())
+}
+
+// Expand inflates a such that it affects only one rune.
+func (rh RichTextHandler) Expand(a cooperate.Action) []cooperate.Action {
+	var actions []cooperate.Action
+	switch a := a.(type) {
+	case RetainAction:
+		for i := 0; i < a.N; i++ {
+			actions = append(actions, RetainAction{N: 1, Attributes: a.Attributes})
+		}
+	case InsertAction:
+		for _, x := range a.Text {
+			actions = append(actions, InsertAction{Text: string(x), Attributes: a.Attributes})
+		}
+	case DeleteAction:
+		for i := 0; i < int(a); i++ {
+			actions = append(actions, DeleteAction(1))
+		}
+	}
+	return actions
+}
+
+// Reduce combines two actions of identical type and formatting into a single
+// action with the same effect.
+func (rh RichTextHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	switch a := a.(type) {
+	case RetainAction:
+		if b, ok := b.(RetainAction); ok && a.Attributes.equal(b.Attributes) {
+			return RetainAction{N: a.N + b.N, Attributes: a.Attributes.normalize()}, true
+		}
+	case InsertAction:
+		if b, ok := b.(InsertAction); ok && a.Attributes.equal(b.Attributes) {
+			return InsertAction{Text: a.Text + b.Text, Attributes: a.Attributes.normalize()}, true
+		}
+	case DeleteAction:
+		if b, ok := b.(DeleteAction); ok {
+			return a + b, true
+		}
+	}
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (rh RichTextHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var composedActions []cooperate.Action // new list of actions
+
+ComposeLoop:
+	for {
+
+		switch {
+
+		// what a deletes is not seen by b, and what b inserts was not seen by
+		// a, so these pass through unchanged, deletions first so that they
+		// transform like a followed by b
+		case a.More() && peekKind(a) == del:
+			composedActions = append(composedActions, a.Consume())
+
+		case b.More() && peekKind(b) == insert:
+			composedActions = append(composedActions, b.Consume())
+
+		// if we are out of actions from either, we are finished
+		case !a.More() || !b.More():
+			break ComposeLoop
+
+		case peekKind(a) == unknown || peekKind(b) == unknown:
+			return nil, cooperate.ErrUnknownAction
+
+		// b deletes what a retained or inserted
+		case peekKind(b) == del:
+			if peekKind(a) == retain {
+				composedActions = append(composedActions, b.Peek())
+			}
+			a.Consume()
+			b.Consume()
+
+		// b retains what a retained or inserted, perhaps changing its format
+		default:
+			attrs := b.Consume().(RetainAction).Attributes
+			switch x := a.Consume().(type) {
+			case RetainAction:
+				composedActions = append(composedActions, RetainAction{N: 1, Attributes: x.Attributes.Compose(attrs, true)})
+			case InsertAction:
+				composedActions = append(composedActions, InsertAction{Text: x.Text, Attributes: x.Attributes.Compose(attrs, false)})
+			}
+
+		}
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce(rh, cooperate.Operation(composedActions)), nil
+}
+
+// Transform implements cooperate.Transformer for the rich text operations
+// defined in this package.
+//
+// By default this implementation favors b; that is, if a and b insert at the
+// same location, b's text comes first, and if both format the same text with
+// the same attribute, b's value wins. rh.Priority may favor a instead.
+func (rh RichTextHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	var aPrime, bPrime []cooperate.Action // new list of actions
+	favorA := rh.Priority == cooperate.FavorA
+
+TransformLoop:
+	for {
+
+		ak, bk := peekKind(a), peekKind(b)
+
+		switch {
+
+		// if we reach the ends at the same time, we are done
+		case !a.More() && !b.More():
+			break TransformLoop
+
+		case (a.More() && ak == unknown) || (b.More() && bk == unknown):
+			return nil, nil, cooperate.ErrUnknownAction
+
+		case ak == insert && favorA:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, RetainAction{N: 1})
+
+		case bk == insert:
+			aPrime = append(aPrime, RetainAction{N: 1})
+			bPrime = append(bPrime, b.Consume())
+
+		case ak == insert:
+			aPrime = append(aPrime, a.Consume())
+			bPrime = append(bPrime, RetainAction{N: 1})
+
+		// if either is finished, the other must have contained only inserts
+		case !a.More() || !b.More():
+			break TransformLoop
+
+		case ak == del && bk == del:
+			a.Consume()
+			b.Consume()
+
+		case ak == del:
+			aPrime = append(aPrime, a.Consume())
+			b.Consume()
+
+		case bk == del:
+			a.Consume()
+			bPrime = append(bPrime, b.Consume())
+
+		default:
+			ar, br := a.Consume().(RetainAction), b.Consume().(RetainAction)
+			aPrime = append(aPrime, RetainAction{N: 1, Attributes: br.Attributes.Transform(ar.Attributes, !favorA)})
+			bPrime = append(bPrime, RetainAction{N: 1, Attributes: ar.Attributes.Transform(br.Attributes, favorA)})
+
+		}
+
+	}
+
+	// a document size mismatch occurs if we didn't process everything
+	if a.More() || b.More() {
+		return nil, nil, cooperate.ErrDocumentSizeMismatch
+	}
+
+	return cooperate.Reduce(rh, cooperate.Operation(aPrime)), cooperate.Reduce(rh, cooperate.Operation(bPrime)), nil
+
+}
+
+// Lengths calculates the lengths of the document op expects to be applied to
+// and the length of that document after applying op, in runes.
+func Lengths(op cooperate.Operation) (pre, post int) {
+	for _, a := range []cooperate.Action(op) {
+		switch a := a.(type) {
+		case RetainAction:
+			pre += a.N
+			post += a.N
+		case InsertAction:
+			post += utf8.RuneCountInString(a.Text)
+		case DeleteAction:
+			pre += int(a)
+		}
+	}
+	return
+}
+
+// kind identifies the rich text action type of a, for use in type switches
+// over pairs of actions.
+type kind int
+
+const (
+	unknown kind = iota
+	retain
+	insert
+	del
+)
+
+func peekKind(oit *cooperate.OperationIterator) kind {
+	if !oit.More() {
+		return unknown
+	}
+	switch oit.Peek().(type) {
+	case RetainAction:
+		return retain
+	case InsertAction:
+		return insert
+	case DeleteAction:
+		return del
+	}
+	return unknown
+}
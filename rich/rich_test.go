@@ -0,0 +1,224 @@
+package rich
+
+import (
+	"math/rand"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+)
+
+func TestCompose(t *testing.T) {
+
+	cases := []struct {
+		First       cooperate.Operation
+		Second      cooperate.Operation
+		Composition cooperate.Operation
+	}{
+		// formatting inserted text folds the format into the insert
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				InsertAction{Text: "ab", Attributes: Attributes{Italic: true}},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 1, Attributes: Attributes{Bold: true, Italic: nil}},
+				RetainAction{N: 1},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				InsertAction{Text: "a", Attributes: Attributes{Bold: true}},
+				InsertAction{Text: "b", Attributes: Attributes{Italic: true}},
+			}),
+		},
+		// successive formats of retained text are merged, keeping removals
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 2, Attributes: Attributes{Bold: true}},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 2, Attributes: Attributes{Link: nil}},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 2, Attributes: Attributes{Bold: true, Link: nil}},
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				InsertAction{Text: "foo"},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				DeleteAction(3),
+			}),
+			Composition: nil,
+		},
+		// removals in the first format survive a plain retain
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 1, Attributes: Attributes{Bold: nil}},
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 1},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				RetainAction{N: 1, Attributes: Attributes{Bold: nil}},
+			}),
+		},
+		// text inserted where text was deleted follows the deletion, as it
+		// was inserted after it
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				DeleteAction(1),
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				InsertAction{Text: "x"},
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				DeleteAction(1),
+				InsertAction{Text: "x"},
+			}),
+		},
+	}
+
+	var rh RichTextHandler
+
+	for i, c := range cases {
+
+		aa, bb := cooperate.NewOperationIterator(cooperate.Expand(rh, c.First)), cooperate.NewOperationIterator(cooperate.Expand(rh, c.Second))
+
+		if sum, err := rh.Compose(aa, bb); err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+		} else if !reflect.DeepEqual(sum, c.Composition) {
+			t.Errorf("[case %d] unexpected composition: expected '%#v' but got '%#v'", i, c.Composition, sum)
+		}
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	cases := []struct {
+		Existing       []Run
+		A, B           cooperate.Operation
+		APrime, BPrime cooperate.Operation
+		Expected       []Run
+	}{
+		// concurrent formats of the same attribute: b wins
+		{
+			Existing: []Run{{Text: "ab"}},
+			A:        cooperate.Operation{RetainAction{N: 2, Attributes: Attributes{Link: "a", Bold: true}}},
+			B:        cooperate.Operation{RetainAction{N: 1, Attributes: Attributes{Link: "b"}}, RetainAction{N: 1}},
+			APrime:   cooperate.Operation{RetainAction{N: 1, Attributes: Attributes{Bold: true}}, RetainAction{N: 1, Attributes: Attributes{Link: "a", Bold: true}}},
+			BPrime:   cooperate.Operation{RetainAction{N: 1, Attributes: Attributes{Link: "b"}}, RetainAction{N: 1}},
+			Expected: []Run{{Text: "a", Attributes: Attributes{Link: "b", Bold: true}}, {Text: "b", Attributes: Attributes{Link: "a", Bold: true}}},
+		},
+		// formatting around a concurrent insert leaves the insert alone
+		{
+			Existing: []Run{{Text: "ab"}},
+			A:        cooperate.Operation{RetainAction{N: 2, Attributes: Attributes{Bold: true}}},
+			B:        cooperate.Operation{RetainAction{N: 1}, InsertAction{Text: "x"}, RetainAction{N: 1}},
+			APrime:   cooperate.Operation{RetainAction{N: 1, Attributes: Attributes{Bold: true}}, RetainAction{N: 1}, RetainAction{N: 1, Attributes: Attributes{Bold: true}}},
+			BPrime:   cooperate.Operation{RetainAction{N: 1}, InsertAction{Text: "x"}, RetainAction{N: 1}},
+			Expected: []Run{{Text: "a", Attributes: Attributes{Bold: true}}, {Text: "x"}, {Text: "b", Attributes: Attributes{Bold: true}}},
+		},
+		// a format of concurrently deleted text disappears
+		{
+			Existing: []Run{{Text: "ab"}},
+			A:        cooperate.Operation{RetainAction{N: 2, Attributes: Attributes{Bold: true}}},
+			B:        cooperate.Operation{DeleteAction(1), RetainAction{N: 1}},
+			APrime:   cooperate.Operation{RetainAction{N: 1, Attributes: Attributes{Bold: true}}},
+			BPrime:   cooperate.Operation{DeleteAction(1), RetainAction{N: 1}},
+			Expected: []Run{{Text: "b", Attributes: Attributes{Bold: true}}},
+		},
+	}
+
+	var rh RichTextHandler
+
+	for i, c := range cases {
+
+		a, b := cooperate.NewOperationIterator(cooperate.Expand(rh, c.A)), cooperate.NewOperationIterator(cooperate.Expand(rh, c.B))
+
+		aPrime, bPrime, err := rh.Transform(a, b)
+		if err != nil {
+			t.Errorf("[case %d] unexpected error: %s", i, err)
+			continue
+		} else if !reflect.DeepEqual(aPrime, c.APrime) || !reflect.DeepEqual(bPrime, c.BPrime) {
+			t.Errorf("[case %d] unexpected transformation: expected (a':%#v, b':%#v) but got (a':%#v, b':%#v)", i, c.APrime, c.BPrime, aPrime, bPrime)
+		}
+
+		// both orders of application must converge
+		ab, ba := NewRichTextDocument(c.Existing...), NewRichTextDocument(c.Existing...)
+		for _, step := range []struct {
+			Doc *RichTextDocument
+			Op  cooperate.Operation
+		}{{ab, c.A}, {ab, bPrime}, {ba, c.B}, {ba, aPrime}} {
+			if err := step.Doc.Apply(step.Op); err != nil {
+				t.Errorf("[case %d] apply error: %s", i, err)
+			}
+		}
+		if !reflect.DeepEqual(ab.Runs(), c.Expected) || !reflect.DeepEqual(ba.Runs(), c.Expected) {
+			t.Errorf("[case %d] documents diverged: expected '%#v' but got '%#v' and '%#v'", i, c.Expected, ab.Runs(), ba.Runs())
+		}
+	}
+
+}
+
+var richConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		return NewRichTextDocument(Run{Text: "ab"[:r.Intn(3)]}, Run{Text: "c"[:r.Intn(2)], Attributes: randomAttributes(r, false)})
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return RichTextHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		var op cooperate.Operation
+		for cur, n := 0, doc.(*RichTextDocument).Len(); cur < n; {
+			k := 1 + r.Intn(n-cur)
+			switch r.Intn(4) {
+			case 0:
+				op = append(op, RetainAction{N: k})
+			case 1:
+				op = append(op, RetainAction{N: k, Attributes: randomAttributes(r, true)})
+			case 2:
+				op = append(op, DeleteAction(k))
+			case 3:
+				op = append(op, InsertAction{Text: string(rune('a' + r.Intn(26))), Attributes: randomAttributes(r, false)}, RetainAction{N: k})
+			}
+			cur += k
+		}
+		if len(op) == 0 || r.Intn(3) == 0 {
+			op = append(op, InsertAction{Text: string(rune('A' + r.Intn(26))), Attributes: randomAttributes(r, false)})
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		return reflect.DeepEqual(a.(*RichTextDocument).Runs(), b.(*RichTextDocument).Runs())
+	},
+}
+
+// randomAttributes returns attributes that set bold and color at random,
+// or remove them if remove is set, or none.
+func randomAttributes(r *rand.Rand, remove bool) Attributes {
+	attrs := make(Attributes)
+	for _, name := range []string{"bold", "color"} {
+		switch r.Intn(4) {
+		case 0:
+			if remove {
+				attrs[name] = nil
+			}
+		case 1:
+			attrs[name] = []interface{}{true, "red", "blue"}[r.Intn(3)]
+		}
+	}
+	if len(attrs) == 0 {
+		return nil
+	}
+	return attrs
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, richConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, richConfig, 200)
+}
@@ -1,61 +1,433 @@
 package cooperate
 
-import "fmt"
+import (
+	"errors"
+	"fmt"
+	"time"
+)
 
-type Server struct {
-	Document Document
-	History  History
+// ErrDocumentAhead indicates that a server's History failed to store an
+// operation that its Document had already applied, and that the server has
+// no Snapshotter with which to restore Document.
+var ErrDocumentAhead = errors.New("document is ahead of history")
+
+// A ServerOf is the authoritative copy of a document whose operations are
+// made of actions of type A.
+type ServerOf[A any] struct {
+	Document DocumentOf[A]
+	History  HistoryOf[A]
 
 	// these implement the core OT operations
-	ExpandReducer
-	ComposeTransformer
+	ExpandReducer      ExpandReducerOf[A]
+	ComposeTransformer ComposeTransformerOf[A]
+
+	// ID identifies the document to Authorizer.
+	ID string
+
+	// Authorizer, if set, is consulted before each operation is committed.
+	Authorizer AuthorizerOf[A]
+
+	// Limits restrict the operations the server accepts, measuring sizes
+	// with Sizer, which is needed only if a size is limited.
+	Limits Limits
+	Sizer  SizerOf[A]
+
+	// Metrics, if set, records the work of the server and its History.
+	Metrics *Metrics
+
+	// Snapshotter, if set, encodes Document every SnapshotInterval
+	// operations, or 100 if it is zero. If History implements
+	// SnapshotHistoryOf, each snapshot is committed along with the operation
+	// that produced it. Should History fail to store an operation, the
+	// server replaces Document with the last snapshot and the operations
+	// stored since; without a Snapshotter, it instead returns
+	// ErrDocumentAhead from then on.
+	Snapshotter      SnapshotterOf[A]
+	SnapshotInterval int
+
+	// blocks caches compositions of aligned blocks of history, which had
+	// blocksSeqno operations when last read
+	blocks      map[block]OperationOf[A]
+	blocksSeqno int
+
+	// these are the token buckets of Limits, and the number of session
+	// buckets at which idle ones are next evicted
+	sessionBuckets map[string]*bucket
+	documentBucket bucket
+	bucketSweep    int
+
+	// snapshot encodes Document as of revision snapshotSeqno, and ahead is
+	// set while Document has applied an operation that History lacks
+	snapshot      []byte
+	snapshotSeqno int
+	ahead         bool
+}
+
+// A block identifies the operations of history numbered from start up to
+// but not including start+size, where size is a power of two and start is a
+// multiple of it.
+type block struct {
+	start, size int
+}
+
+// defaultSnapshotInterval is the number of operations a server applies
+// between snapshots if its SnapshotInterval is zero.
+const defaultSnapshotInterval = 100
+
+// maxBlocks is the number of compositions of blocks a server caches. Beyond
+// it, the smallest blocks are evicted, since they save the least work and
+// are the quickest to compose again.
+const maxBlocks = 1024
+
+// A Server is a ServerOf untyped actions.
+type Server = ServerOf[Action]
+
+// Expand, Reduce, Compose and Transform call the server's ExpandReducer and
+// ComposeTransformer. Before operations were generic these were embedded, and
+// the methods remain so that a ServerOf still implements ExpandReducerOf and
+// ComposeTransformerOf.
+func (s *ServerOf[A]) Expand(a A) []A {
+	return s.ExpandReducer.Expand(a)
+}
+
+func (s *ServerOf[A]) Reduce(a, b A) (A, bool) {
+	return s.ExpandReducer.Reduce(a, b)
+}
+
+func (s *ServerOf[A]) Compose(a, b *OperationIteratorOf[A]) (OperationOf[A], error) {
+	return s.ComposeTransformer.Compose(a, b)
+}
+
+func (s *ServerOf[A]) Transform(a, b *OperationIteratorOf[A]) (aa, bb OperationOf[A], err error) {
+	return s.ComposeTransformer.Transform(a, b)
 }
 
 // Apply applies the received Operation.
-func (s *Server) Apply(root int, op Operation) error {
+func (s *ServerOf[A]) Apply(root int, op OperationOf[A]) error {
+	return s.ApplyAs("", root, op)
+}
+
+// ApplyAs is like Apply, but the operation is authorized and rate limited as
+// an edit by session. If the Authorizer rejects it, ApplyAs returns an
+// *AuthorizationError, and if it exceeds the server's Limits, a *LimitError.
+func (s *ServerOf[A]) ApplyAs(session string, root int, op OperationOf[A]) error {
+	_, err := s.apply(session, root, op, func(op OperationOf[A], snapshot []byte) (int, error) {
+		if h, ok := s.History.(SnapshotHistoryOf[A]); ok && snapshot != nil {
+			return h.StoreSnapshot(op, snapshot)
+		}
+		return s.History.Store(op)
+	})
+	return err
+}
+
+// Resync streams the operations committed after revision to cb, along with
+// the revisions they produced, so that a client that reconnects can catch up
+// with ClientOf.Catchup.
+//
+// If the client has an operation in flight, clientID and seq identify its
+// submission. When the History implements DedupHistoryOf and the operation
+// was committed before the client disconnected, it is passed with own set,
+// so that the client does not apply its own operation twice.
+func (s *ServerOf[A]) Resync(revision int, clientID string, seq int, cb func(revision int, op OperationOf[A], own bool) error) error {
+
+	seqno, err := sequenceNumber(s.History)
+	if err != nil {
+		return err
+	}
+	if revision < 0 || revision > seqno {
+		return ErrSeqnoOutOfRange
+	}
+
+	committed := -1
+	if h, ok := s.History.(DedupHistoryOf[A]); ok && clientID != "" {
+		seqno, ok, err := h.Committed(clientID, seq)
+		if err != nil {
+			return err
+		}
+		if ok {
+			committed = seqno
+		}
+	}
+
+	return s.History.Iterate(revision, func(seqno int, op OperationOf[A]) error {
+		return cb(seqno+1, op, seqno+1 == committed)
+	})
+
+}
+
+// apply transforms op, which is rooted at root, against history, authorizes
+// it as an edit by session, applies it and saves it with store, along with a
+// snapshot of the document if one is due, returning its seqno.
+func (s *ServerOf[A]) apply(session string, root int, op OperationOf[A], store func(op OperationOf[A], snapshot []byte) (int, error)) (seqno int, err error) {
+
+	// errors reading or storing history are not the operation's fault
+	historyErr := false
+	defer func() {
+		if err != nil && historyErr {
+			s.Metrics.historyFailed()
+		} else if err != nil {
+			s.Metrics.rejectedOp(err)
+		}
+	}()
+
+	// turn away floods before doing any work,
+	if err := s.checkRate(session); err != nil {
+		return 0, err
+	}
+	if err := s.checkOperationSize(session, op); err != nil {
+		return 0, err
+	}
+
+	// catch up with history if we failed to store an operation before,
+	if s.ahead {
+		if err := s.restore(); err != nil {
+			historyErr = true
+			return 0, err
+		}
+	}
 
 	// we need some way of knowing which state the operation is rooted at,
 
 	// then we need to look up everything since that state,
 	// compose it all together,
-	var meanwhile Operation
-	s.History.Iterate(root, func(seqno int, op Operation) (err error) {
-		if meanwhile == nil {
-			meanwhile = op
-		} else {
-			meanwhile, err = s.Compose(
-				NewOperationIterator(Expand(s.ExpandReducer, meanwhile)),
-				NewOperationIterator(Expand(s.ExpandReducer, op)),
-			)
-		}
-		return
-	})
+	meanwhile, err := s.composeHistory(root)
+	if err != nil {
+		historyErr = true
+		return 0, err
+	}
+	current := s.blocksSeqno // as composeHistory just read it
 
 	// transform op against it,
 	if meanwhile != nil {
-		_, opPrime, err := s.Transform(
+		start := time.Now()
+		_, opPrime, err := s.ComposeTransformer.Transform(
 			NewOperationIterator(Expand(s.ExpandReducer, meanwhile)),
 			NewOperationIterator(Expand(s.ExpandReducer, op)),
 		)
+		s.Metrics.since(transformLatency, start)
 		if err != nil {
-			return err
+			return 0, err
 		}
 		op = opPrime
 	}
 
+	// check that op' may be committed,
+	if err := s.checkDocumentSize(session, op); err != nil {
+		return 0, err
+	}
+	if s.Authorizer != nil {
+		if err := s.Authorizer.Authorize(session, s.ID, op); err != nil {
+			return 0, &AuthorizationError{Session: session, Document: s.ID, Err: err}
+		}
+	}
+
 	// apply op' to our copy of the state,
+	if s.Snapshotter != nil && s.snapshot == nil {
+		snapshot, err := s.Snapshotter.Snapshot(s.Document)
+		if err != nil {
+			historyErr = true
+			return 0, err
+		}
+		s.snapshot, s.snapshotSeqno = snapshot, current
+	}
 	if err := s.Document.Apply(op); err != nil {
-		return err
+		return 0, err
 	}
 
-	// save op' to the history,
-	if _, err := s.History.Store(op); err != nil {
-		return err
+	// save op' to the history, with a snapshot if one is due,
+	var snapshot []byte
+	if s.Snapshotter != nil && current+1-s.snapshotSeqno >= s.snapshotInterval() {
+		if snapshot, err = s.Snapshotter.Snapshot(s.Document); err != nil {
+			s.ahead, historyErr = true, true
+			return 0, err
+		}
+	}
+	start := time.Now()
+	seqno, err = store(op, snapshot)
+	s.Metrics.since(storeLatency, start)
+	if err != nil {
+		s.ahead, historyErr = true, true
+		return 0, err
 	}
+	if snapshot != nil {
+		s.snapshot, s.snapshotSeqno = snapshot, seqno
+	}
+	s.Metrics.committedOp(s.ID, seqno-1-root, seqno)
 
 	// and finally broadcast op' to everyone.
 	fmt.Printf("hey everyone apply this: %#v\n", op)
 
+	return seqno, nil
+
+}
+
+// composeHistory returns the composition of the operations of history from
+// root onwards, or nil if there are none.
+//
+// The operations are divided into the largest aligned blocks possible,
+// whose compositions are cached. Since history only grows, a block never
+// changes once complete, and composing everything since any root takes a
+// number of compositions logarithmic in the length of history, once the
+// blocks have been computed. Only the operations that follow the cached
+// blocks are read from history.
+func (s *ServerOf[A]) composeHistory(root int) (OperationOf[A], error) {
+
+	// a history that has shrunk is not the one the blocks were cached from
+	seqno, err := sequenceNumber(s.History)
+	if err != nil {
+		return nil, err
+	}
+	if seqno < s.blocksSeqno {
+		s.blocks = nil
+	}
+	s.blocksSeqno = seqno
+
+	var composed OperationOf[A]
+	add := func(op OperationOf[A]) (err error) {
+		if composed == nil {
+			composed = op
+		} else {
+			composed, err = s.compose(composed, op)
+		}
+		return err
+	}
+
+	// use the cached blocks that follow root,
+	tail := root
+	for {
+		b := alignedBlock(tail, seqno-tail)
+		op, ok := s.blocks[b]
+		if !ok {
+			break
+		}
+		if err := add(op); err != nil {
+			return nil, err
+		}
+		tail += b.size
+	}
+
+	// and compose the operations that follow them.
+	var ops []OperationOf[A]
+	if err := s.History.Iterate(tail, func(seqno int, op OperationOf[A]) error {
+		ops = append(ops, op)
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(ops); {
+		b := alignedBlock(tail+i, len(ops)-i)
+		op, err := s.composeBlock(b, ops[i:i+b.size])
+		if err != nil {
+			return nil, err
+		}
+		if err := add(op); err != nil {
+			return nil, err
+		}
+		i += b.size
+	}
+
+	return composed, nil
+
+}
+
+// alignedBlock returns the largest block that starts at start and spans at
+// most n operations, which must be at least one for the block to be valid.
+func alignedBlock(start, n int) block {
+	size := 1
+	for start%(size*2) == 0 && size*2 <= n {
+		size *= 2
+	}
+	return block{start: start, size: size}
+}
+
+// composeBlock returns the composition of ops, which are the operations of
+// b.
+func (s *ServerOf[A]) composeBlock(b block, ops []OperationOf[A]) (OperationOf[A], error) {
+
+	if b.size == 1 {
+		return ops[0], nil
+	}
+	if op, ok := s.blocks[b]; ok {
+		return op, nil
+	}
+
+	half := b.size / 2
+	first, err := s.composeBlock(block{start: b.start, size: half}, ops[:half])
+	if err != nil {
+		return nil, err
+	}
+	second, err := s.composeBlock(block{start: b.start + half, size: half}, ops[half:])
+	if err != nil {
+		return nil, err
+	}
+
+	op, err := s.compose(first, second)
+	if err != nil {
+		return nil, err
+	}
+
+	if s.blocks == nil {
+		s.blocks = make(map[block]OperationOf[A])
+	}
+	if len(s.blocks) >= maxBlocks {
+		s.evictBlock()
+	}
+	s.blocks[b] = op
+	return op, nil
+
+}
+
+// evictBlock removes the smallest cached block, or of those the one that
+// starts earliest in history.
+func (s *ServerOf[A]) evictBlock() {
+	var smallest block
+	for b := range s.blocks {
+		if smallest.size == 0 || b.size < smallest.size || (b.size == smallest.size && b.start < smallest.start) {
+			smallest = b
+		}
+	}
+	delete(s.blocks, smallest)
+}
+
+func (s *ServerOf[A]) compose(a, b OperationOf[A]) (OperationOf[A], error) {
+	defer s.Metrics.since(composeLatency, time.Now())
+	op, err := s.ComposeTransformer.Compose(
+		NewOperationIterator(Expand(s.ExpandReducer, a)),
+		NewOperationIterator(Expand(s.ExpandReducer, b)),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return Reduce(s.ExpandReducer, op), nil
+}
+
+func (s *ServerOf[A]) snapshotInterval() int {
+	if s.SnapshotInterval > 0 {
+		return s.SnapshotInterval
+	}
+	return defaultSnapshotInterval
+}
+
+// restore replaces Document with the last snapshot, brought up to date with
+// the operations stored since.
+func (s *ServerOf[A]) restore() error {
+
+	if s.snapshot == nil {
+		return ErrDocumentAhead
+	}
+
+	doc, err := s.Snapshotter.Restore(s.snapshot)
+	if err != nil {
+		return err
+	}
+	if err := s.History.Iterate(s.snapshotSeqno, func(_ int, op OperationOf[A]) error {
+		return doc.Apply(op)
+	}); err != nil {
+		return err
+	}
+
+	s.Document, s.ahead = doc, false
 	return nil
 
 }
@@ -1,9 +1,12 @@
 package cooperate_test
 
 import (
+	"errors"
+	"reflect"
 	"testing"
 
 	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/numeric"
 	"github.com/tylerchr/cooperate/text"
 )
 
@@ -99,3 +102,373 @@ func TestServer_OutOfOrder(t *testing.T) {
 	t.Logf("Server {seqno=%d}\n", s.History.SequenceNumber())
 
 }
+
+func TestServerOf_Typed(t *testing.T) {
+
+	doc := text.NewTextDocument("")
+
+	s := &cooperate.ServerOf[text.Action]{
+		Document:           doc.Typed(),
+		History:            &cooperate.MemoryHistoryOf[text.Action]{},
+		ExpandReducer:      text.TypedHandler{},
+		ComposeTransformer: text.TypedHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.OperationOf[text.Action]
+	}{
+		{
+			Root:      0,
+			Operation: cooperate.OperationOf[text.Action]{text.InsertAction("red")},
+		},
+		{
+			Root:      1,
+			Operation: cooperate.OperationOf[text.Action]{text.RetainAction(3), text.InsertAction("blue")},
+		},
+		{
+			Root:      0,
+			Operation: cooperate.OperationOf[text.Action]{text.InsertAction("green")},
+		},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if doc.String() != "greenredblue" {
+		t.Errorf("unexpected document: %s\n", doc.String())
+	}
+
+}
+
+func TestUntypedDocument(t *testing.T) {
+
+	doc := text.NewTextDocument("foo")
+	untyped := cooperate.UntypedDocument(doc.Typed())
+
+	if err := untyped.Apply(cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	} else if doc.String() != "food" {
+		t.Errorf("unexpected document: %s\n", doc.String())
+	}
+
+	if err := untyped.Apply(cooperate.Operation{"not a text action"}); err != cooperate.ErrUnknownAction {
+		t.Errorf("unexpected error: expected '%s' but got '%s'", cooperate.ErrUnknownAction, err)
+	}
+
+}
+
+func TestServer_Stale(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	// every operation appends a letter to the document as of its root, which
+	// is often several revisions behind
+	roots := []int{0, 0, 1, 3, 2, 5, 0, 7, 4, 8}
+	lengths := []int{0}
+	for i, root := range roots {
+		op := cooperate.Operation{text.InsertAction(string(rune('a' + i)))}
+		if lengths[root] > 0 {
+			op = append(cooperate.Operation{text.RetainAction(lengths[root])}, op...)
+		}
+		if err := s.Apply(root, op); err != nil {
+			t.Fatalf("[op %d] apply error: %s", i, err)
+		}
+		lengths = append(lengths, i+1)
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "gbaecdifhj" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+}
+
+// countingHistory counts the operations read from a MemoryHistory.
+type countingHistory struct {
+	*cooperate.MemoryHistory
+	read int
+}
+
+func (ch *countingHistory) Iterate(startingSeqno int, cb func(seqno int, op cooperate.Operation) error) error {
+	return ch.MemoryHistory.Iterate(startingSeqno, func(seqno int, op cooperate.Operation) error {
+		ch.read++
+		return cb(seqno, op)
+	})
+}
+
+func TestServer_StaleReadsTail(t *testing.T) {
+
+	h := &countingHistory{MemoryHistory: &cooperate.MemoryHistory{}}
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            h,
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	for i := 0; i < 1024; i++ {
+		if err := s.Apply(i, cooperate.Operation{text.RetainAction(i), text.InsertAction("a")}); err != nil {
+			t.Fatalf("[op %d] apply error: %s", i, err)
+		}
+	}
+
+	// the first stale operation reads all of history, but once its blocks are
+	// cached, only the operations that follow them are read
+	for i, expected := range []int{1024, 1} {
+		h.read = 0
+		if err := s.Apply(0, cooperate.Operation{text.InsertAction("b")}); err != nil {
+			t.Fatalf("[stale op %d] apply error: %s", i, err)
+		}
+		if h.read != expected {
+			t.Errorf("[stale op %d] unexpected operations read: expected %d but got %d", i, expected, h.read)
+		}
+	}
+
+}
+
+func TestServer_ReplacedHistory(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	for i, letter := range []string{"a", "b", "c", "d"} {
+		if err := s.Apply(i, cooperate.Operation{text.RetainAction(i), text.InsertAction(letter)}); err != nil {
+			t.Fatalf("[op %d] apply error: %s", i, err)
+		}
+	}
+	if err := s.Apply(0, cooperate.Operation{text.InsertAction("x")}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+
+	// blocks cached from the old history must not be used with the new one
+	s.Document = text.NewTextDocument("yz")
+	s.History = &cooperate.MemoryHistory{
+		{text.InsertAction("y")},
+		{text.RetainAction(1), text.InsertAction("z")},
+	}
+	if err := s.Apply(0, cooperate.Operation{text.InsertAction("x")}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "xyz" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+}
+
+func TestServer_StaleEvicts(t *testing.T) {
+
+	h := &countingHistory{MemoryHistory: &cooperate.MemoryHistory{}}
+	s := &cooperate.Server{
+		Document:           numeric.NewCounterDocument(0),
+		History:            h,
+		ExpandReducer:      numeric.CounterHandler{},
+		ComposeTransformer: numeric.CounterHandler{},
+	}
+
+	// rooting each operation at the start of history caches more blocks than
+	// a server keeps, but the largest blocks survive
+	for i := 0; i < 1100; i++ {
+		if err := s.Apply(0, cooperate.Operation{numeric.IncrementAction(1)}); err != nil {
+			t.Fatalf("[op %d] apply error: %s", i, err)
+		}
+	}
+
+	h.read = 0
+	if err := s.Apply(0, cooperate.Operation{numeric.IncrementAction(1)}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+	if h.read > 4 {
+		t.Errorf("unexpected operations read: expected at most 4 but got %d", h.read)
+	}
+
+	if value := s.Document.(*numeric.CounterDocument).Value(); value != 1101 {
+		t.Errorf("unexpected counter: expected %d but got %d", 1101, value)
+	}
+
+}
+
+// failingHistory is a MemoryHistory that fails to store operations while
+// fail is set.
+type failingHistory struct {
+	*cooperate.MemoryHistory
+	fail bool
+}
+
+var errStore = errors.New("store failed")
+
+func (fh *failingHistory) Store(op cooperate.Operation) (int, error) {
+	if fh.fail {
+		return 0, errStore
+	}
+	return fh.MemoryHistory.Store(op)
+}
+
+func TestServer_StoreError(t *testing.T) {
+
+	for _, snapshotter := range []cooperate.Snapshotter{nil, text.TextSnapshotter{}} {
+
+		h := &failingHistory{MemoryHistory: &cooperate.MemoryHistory{}}
+		s := &cooperate.Server{
+			Document:           text.NewTextDocument(""),
+			History:            h,
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+			Snapshotter:        snapshotter,
+			SnapshotInterval:   2,
+		}
+
+		for i, letter := range []string{"a", "b", "c"} {
+			if err := s.Apply(i, cooperate.Operation{text.RetainAction(i), text.InsertAction(letter)}); err != nil {
+				t.Fatalf("[%T] apply error: %s", snapshotter, err)
+			}
+		}
+
+		h.fail = true
+		if err := s.Apply(3, cooperate.Operation{text.RetainAction(3), text.InsertAction("x")}); err != errStore {
+			t.Fatalf("[%T] unexpected error: expected '%v' but got '%v'", snapshotter, errStore, err)
+		}
+		h.fail = false
+
+		// the document has applied an operation that history lacks, which a
+		// server with a Snapshotter can undo, and one without cannot
+		err := s.Apply(3, cooperate.Operation{text.RetainAction(3), text.InsertAction("d")})
+		if snapshotter == nil {
+			if err != cooperate.ErrDocumentAhead {
+				t.Errorf("[%T] unexpected error: expected '%v' but got '%v'", snapshotter, cooperate.ErrDocumentAhead, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[%T] apply error: %s", snapshotter, err)
+		}
+		if doc := s.Document.(*text.TextDocument); doc.String() != "abcd" {
+			t.Errorf("[%T] unexpected document: %s", snapshotter, doc.String())
+		}
+
+	}
+
+}
+
+// BenchmarkServer_Stale measures submitting operations rooted 1,000
+// revisions behind the head of history, both through a Server, which caches
+// compositions of history, and by composing history an operation at a time
+// on every submission, as servers used to.
+func BenchmarkServer_Stale(b *testing.B) {
+
+	b.Run("cached", func(b *testing.B) {
+		s := &cooperate.Server{
+			Document:           text.NewTextDocument(""),
+			History:            &cooperate.MemoryHistory{},
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+		}
+		benchmarkStale(b, s.Apply)
+	})
+
+	b.Run("uncached", func(b *testing.B) {
+		var (
+			doc     = text.NewTextDocument("")
+			history = &cooperate.MemoryHistory{}
+			th      = text.TextHandler{}
+		)
+		benchmarkStale(b, func(root int, op cooperate.Operation) error {
+			var meanwhile cooperate.Operation
+			if err := history.Iterate(root, func(seqno int, op cooperate.Operation) (err error) {
+				if meanwhile == nil {
+					meanwhile = op
+				} else {
+					meanwhile, err = th.Compose(
+						cooperate.NewOperationIterator(cooperate.Expand(th, meanwhile)),
+						cooperate.NewOperationIterator(cooperate.Expand(th, op)),
+					)
+				}
+				return
+			}); err != nil {
+				return err
+			}
+			if meanwhile != nil {
+				_, opPrime, err := th.Transform(
+					cooperate.NewOperationIterator(cooperate.Expand(th, meanwhile)),
+					cooperate.NewOperationIterator(cooperate.Expand(th, op)),
+				)
+				if err != nil {
+					return err
+				}
+				op = opPrime
+			}
+			if err := doc.Apply(op); err != nil {
+				return err
+			}
+			_, err := history.Store(op)
+			return err
+		})
+	})
+
+}
+
+// benchmarkStale applies b.N operations with apply, each rooted at the
+// document as it was 1,000 revisions before.
+func benchmarkStale(b *testing.B, apply func(root int, op cooperate.Operation) error) {
+
+	var lengths []int
+	for i := 0; i < 1000+b.N; i++ {
+		lengths = append(lengths, i)
+		if i >= 1000 {
+			if i == 1000 {
+				b.ResetTimer()
+			}
+			root := i - 1000
+			op := cooperate.Operation{text.InsertAction("b")}
+			if lengths[root] > 0 {
+				op = append(op, text.RetainAction(lengths[root]))
+			}
+			if err := apply(root, op); err != nil {
+				b.Fatal(err)
+			}
+			continue
+		}
+		if err := apply(i, cooperate.Operation{text.RetainAction(i), text.InsertAction("a")}); err != nil {
+			b.Fatal(err)
+		}
+	}
+
+}
+
+func TestServer_Handlers(t *testing.T) {
+
+	s := &cooperate.Server{
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	// a Server can stand in for its handlers, as it could when they were
+	// embedded
+	var er cooperate.ExpandReducer = s
+	var ct cooperate.ComposeTransformer = s
+
+	composed, err := ct.Compose(
+		cooperate.NewOperationIterator(cooperate.Expand(er, cooperate.Operation{text.InsertAction("ab")})),
+		cooperate.NewOperationIterator(cooperate.Expand(er, cooperate.Operation{text.RetainAction(2), text.InsertAction("c")})),
+	)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if reduced, expected := cooperate.Reduce(er, composed), (cooperate.Operation{text.InsertAction("abc")}); !reflect.DeepEqual(reduced, expected) {
+		t.Errorf("unexpected composition: expected %#v but got %#v", expected, reduced)
+	}
+
+}
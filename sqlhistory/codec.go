@@ -0,0 +1,34 @@
+package sqlhistory
+
+import (
+	"bytes"
+	"encoding/gob"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// A Codec encodes operations for storage.
+type Codec interface {
+	MarshalOperation(op cooperate.Operation) ([]byte, error)
+	UnmarshalOperation(data []byte) (cooperate.Operation, error)
+}
+
+// GobCodec is a Codec that uses encoding/gob. The action types used in
+// operations must be registered with gob.Register.
+type GobCodec struct{}
+
+func (GobCodec) MarshalOperation(op cooperate.Operation) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode([]cooperate.Action(op)); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func (GobCodec) UnmarshalOperation(data []byte) (cooperate.Operation, error) {
+	var actions []cooperate.Action
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&actions); err != nil {
+		return nil, err
+	}
+	return cooperate.Operation(actions), nil
+}
@@ -0,0 +1,272 @@
+package sqlhistory
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// fakeDriver is a database/sql driver that understands only the statements
+// issued by History, so that History can be tested without a real database.
+// Every data source name opens its own in-memory database.
+type fakeDriver struct {
+	mu  sync.Mutex
+	dbs map[string]*fakeDB
+}
+
+type fakeKey struct {
+	document string
+	seqno    int64
+}
+
+type fakeOperation struct {
+	author    string
+	time      int64
+	operation []byte
+}
+
+type fakeSubmissionKey struct {
+	document string
+	client   string
+}
+
+type fakeSubmission struct {
+	seq, seqno int64
+}
+
+type fakeDB struct {
+	mu         sync.Mutex
+	operations map[fakeKey]fakeOperation
+	snapshots  map[fakeKey][]byte
+
+	// submissions holds nil for submissions deleted by a transaction.
+	submissions map[fakeSubmissionKey]*fakeSubmission
+
+	// failSnapshots causes inserting snapshots to fail.
+	failSnapshots bool
+}
+
+func init() {
+	sql.Register("sqlhistory-fake", &fakeDriver{dbs: make(map[string]*fakeDB)})
+}
+
+func newFakeDB() *fakeDB {
+	return &fakeDB{
+		operations:  make(map[fakeKey]fakeOperation),
+		snapshots:   make(map[fakeKey][]byte),
+		submissions: make(map[fakeSubmissionKey]*fakeSubmission),
+	}
+}
+
+func (d *fakeDriver) Open(name string) (driver.Conn, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	db, ok := d.dbs[name]
+	if !ok {
+		db = newFakeDB()
+		d.dbs[name] = db
+	}
+	return &fakeConn{db: db}, nil
+}
+
+// fakeConn buffers the writes of a transaction until it is committed.
+type fakeConn struct {
+	db *fakeDB
+	tx *fakeDB
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.tx = newFakeDB()
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	c.db.mu.Lock()
+	defer c.db.mu.Unlock()
+	for k, v := range c.tx.operations {
+		c.db.operations[k] = v
+	}
+	for k, v := range c.tx.snapshots {
+		c.db.snapshots[k] = v
+	}
+	for k, v := range c.tx.submissions {
+		if v == nil {
+			delete(c.db.submissions, k)
+		} else {
+			c.db.submissions[k] = v
+		}
+	}
+	c.tx = nil
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	c.tx = nil
+	return nil
+}
+
+type fakeStmt struct {
+	conn  *fakeConn
+	query string
+}
+
+func (s *fakeStmt) Close() error  { return nil }
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	target := db
+	if s.conn.tx != nil {
+		target = s.conn.tx
+	}
+
+	switch {
+	case strings.HasPrefix(s.query, "CREATE TABLE"):
+
+	case s.query == insertOperation:
+		k := fakeKey{args[0].(string), args[1].(int64)}
+		if _, ok := db.operations[k]; ok {
+			return nil, errors.New("duplicate operation")
+		}
+		target.operations[k] = fakeOperation{args[2].(string), args[3].(int64), args[4].([]byte)}
+
+	case s.query == insertSnapshot:
+		if db.failSnapshots {
+			return nil, errors.New("snapshot failed")
+		}
+		target.snapshots[fakeKey{args[0].(string), args[1].(int64)}] = args[2].([]byte)
+
+	case s.query == deleteSubmission:
+		k := fakeSubmissionKey{args[0].(string), args[1].(string)}
+		if target == db {
+			delete(db.submissions, k)
+		} else {
+			target.submissions[k] = nil
+		}
+
+	case s.query == insertSubmission:
+		k := fakeSubmissionKey{args[0].(string), args[1].(string)}
+		if merge(db.submissions, target.submissions)[k] != nil {
+			return nil, errors.New("duplicate submission")
+		}
+		target.submissions[k] = &fakeSubmission{args[2].(int64), args[3].(int64)}
+
+	default:
+		return nil, errors.New("unsupported statement: " + s.query)
+	}
+
+	return driver.RowsAffected(1), nil
+
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+
+	db := s.conn.db
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	// reads see the writes of the current transaction
+	operations, snapshots, submissions := db.operations, db.snapshots, db.submissions
+	if tx := s.conn.tx; tx != nil {
+		operations, snapshots, submissions = merge(db.operations, tx.operations), merge(db.snapshots, tx.snapshots), merge(db.submissions, tx.submissions)
+	}
+
+	document := args[0].(string)
+	rows := &fakeRows{}
+
+	switch s.query {
+	case querySequenceNumber:
+		rows.columns = 1
+		var max int64
+		for k := range operations {
+			if k.document == document && k.seqno > max {
+				max = k.seqno
+			}
+		}
+		rows.values = [][]driver.Value{{max}}
+
+	case queryIterate:
+		rows.columns = 2
+		for k, v := range operations {
+			if k.document == document && k.seqno > args[1].(int64) {
+				rows.values = append(rows.values, []driver.Value{k.seqno, v.operation})
+			}
+		}
+		sort.Slice(rows.values, func(i, j int) bool { return rows.values[i][0].(int64) < rows.values[j][0].(int64) })
+
+	case queryRevision:
+		rows.columns = 3
+		if v, ok := operations[fakeKey{document, args[1].(int64)}]; ok {
+			rows.values = [][]driver.Value{{v.author, v.time, v.operation}}
+		}
+
+	case querySnapshot:
+		rows.columns = 2
+		var best int64 = -1
+		for k := range snapshots {
+			if k.document == document && k.seqno <= args[1].(int64) && k.seqno > best {
+				best = k.seqno
+			}
+		}
+		if best >= 0 {
+			rows.values = [][]driver.Value{{best, snapshots[fakeKey{document, best}]}}
+		}
+
+	case querySubmission:
+		rows.columns = 2
+		if v := submissions[fakeSubmissionKey{document, args[1].(string)}]; v != nil {
+			rows.values = [][]driver.Value{{v.seq, v.seqno}}
+		}
+
+	default:
+		return nil, errors.New("unsupported query: " + s.query)
+	}
+
+	return rows, nil
+
+}
+
+func merge[K comparable, V any](a, b map[K]V) map[K]V {
+	m := make(map[K]V, len(a)+len(b))
+	for k, v := range a {
+		m[k] = v
+	}
+	for k, v := range b {
+		m[k] = v
+	}
+	return m
+}
+
+type fakeRows struct {
+	columns int
+	values  [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string {
+	return make([]string, r.columns)
+}
+
+func (r *fakeRows) Close() error { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.values) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.values[0])
+	r.values = r.values[1:]
+	return nil
+}
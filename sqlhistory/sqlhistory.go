@@ -0,0 +1,326 @@
+// Package sqlhistory implements cooperate.History in a SQL database, such as
+// an embedded SQLite database, using database/sql.
+//
+// The operations of many documents can share one database. Each operation is
+// stored with its sequence number, author and commit time. Operations
+// committed with History.Commit may also be stored together with a snapshot
+// of the document they produced, so that loading a document need not replay
+// its whole history.
+//
+// A cooperate.Server stores operations with Store, which records no author,
+// or with StoreSubmission, which records the submitting client as the
+// author. A Server with a Snapshotter periodically stores snapshots as well,
+// with StoreSnapshot or StoreSubmissionSnapshot, which commit them in the
+// same transaction as the operation that produced them.
+//
+// Queries use "?" placeholders, as SQLite and MySQL do.
+package sqlhistory
+
+import (
+	"database/sql"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrNotFound indicates that no revision or snapshot exists at the requested
+// sequence number.
+var ErrNotFound = errors.New("not found")
+
+// Schema creates the tables used by History. The primary keys index
+// operations and snapshots by document and sequence number, and the last
+// submission committed for each client by document and client.
+var Schema = []string{
+	`CREATE TABLE IF NOT EXISTS operations (
+		document TEXT NOT NULL,
+		seqno INTEGER NOT NULL,
+		author TEXT NOT NULL,
+		time INTEGER NOT NULL,
+		operation BLOB NOT NULL,
+		PRIMARY KEY (document, seqno)
+	)`,
+	`CREATE TABLE IF NOT EXISTS snapshots (
+		document TEXT NOT NULL,
+		seqno INTEGER NOT NULL,
+		snapshot BLOB NOT NULL,
+		PRIMARY KEY (document, seqno)
+	)`,
+	`CREATE TABLE IF NOT EXISTS submissions (
+		document TEXT NOT NULL,
+		client TEXT NOT NULL,
+		seq INTEGER NOT NULL,
+		seqno INTEGER NOT NULL,
+		PRIMARY KEY (document, client)
+	)`,
+}
+
+const (
+	querySequenceNumber = `SELECT COALESCE(MAX(seqno), 0) FROM operations WHERE document = ?`
+	queryIterate        = `SELECT seqno, operation FROM operations WHERE document = ? AND seqno > ? ORDER BY seqno`
+	queryRevision       = `SELECT author, time, operation FROM operations WHERE document = ? AND seqno = ?`
+	querySnapshot       = `SELECT seqno, snapshot FROM snapshots WHERE document = ? AND seqno <= ? ORDER BY seqno DESC LIMIT 1`
+	insertOperation     = `INSERT INTO operations (document, seqno, author, time, operation) VALUES (?, ?, ?, ?, ?)`
+	insertSnapshot      = `INSERT INTO snapshots (document, seqno, snapshot) VALUES (?, ?, ?)`
+	querySubmission     = `SELECT seq, seqno FROM submissions WHERE document = ? AND client = ?`
+	deleteSubmission    = `DELETE FROM submissions WHERE document = ? AND client = ?`
+	insertSubmission    = `INSERT INTO submissions (document, client, seq, seqno) VALUES (?, ?, ?, ?)`
+)
+
+// CreateTables executes Schema against db.
+func CreateTables(db *sql.DB) error {
+	for _, stmt := range Schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// A History is the history of one document stored in a SQL database. It
+// implements cooperate.History, cooperate.SnapshotHistory and
+// cooperate.SnapshotDedupHistory.
+//
+// As with cooperate.MemoryHistory, Store returns the number of operations
+// stored so far, which is also the sequence number of the revision the
+// operation produced, while Iterate numbers operations from zero.
+type History struct {
+	db       *sql.DB
+	document string
+	codec    Codec
+
+	// Now returns the commit time of new operations. It defaults to
+	// time.Now.
+	Now func() time.Time
+
+	// ErrorLog logs the errors of methods that cannot return them, such as
+	// SequenceNumber. If nil, errors are logged by the log package's
+	// standard logger.
+	ErrorLog *log.Logger
+}
+
+// A Revision is an operation and the details of its commit.
+type Revision struct {
+	Seqno     int
+	Author    string
+	Time      time.Time
+	Operation cooperate.Operation
+}
+
+// New returns the History of document in db, whose operations are encoded
+// by codec. The tables of Schema must already exist.
+func New(db *sql.DB, document string, codec Codec) *History {
+	return &History{db: db, document: document, codec: codec}
+}
+
+// SequenceNumber returns the sequence number of the current state, or -1 if
+// the database cannot be read, in which case the error is logged to
+// ErrorLog. A cooperate.Server calls ReadSequenceNumber instead.
+func (h *History) SequenceNumber() int {
+	seqno, err := h.ReadSequenceNumber()
+	if err != nil {
+		h.logf("sqlhistory: reading the sequence number of %q: %s", h.document, err)
+		return -1
+	}
+	return seqno
+}
+
+// ReadSequenceNumber returns the sequence number of the current state, or
+// the error reading it from the database.
+// It may be given to Metrics.ObserveHistory to report the size of h.
+func (h *History) ReadSequenceNumber() (int, error) {
+	var seqno int
+	if err := h.db.QueryRow(querySequenceNumber, h.document).Scan(&seqno); err != nil {
+		return 0, err
+	}
+	return seqno, nil
+}
+
+func (h *History) logf(format string, args ...interface{}) {
+	if h.ErrorLog != nil {
+		h.ErrorLog.Printf(format, args...)
+	} else {
+		log.Printf(format, args...)
+	}
+}
+
+// Store appends an operation to the history, and returns its seqno. It is
+// equivalent to Commit with no author or snapshot.
+func (h *History) Store(op cooperate.Operation) (int, error) {
+	return h.Commit(op, "", nil)
+}
+
+// Commit appends op, written by author, to the history, and returns its
+// seqno. If snapshot is not nil, it is stored as the encoded state of the
+// document after op. The operation and the snapshot are written in a single
+// transaction, so that readers never see one without the other.
+func (h *History) Commit(op cooperate.Operation, author string, snapshot []byte) (seqno int, err error) {
+	return h.commit(op, author, snapshot, nil)
+}
+
+// StoreSnapshot is like Store, but also stores snapshot as the encoded
+// state of the document after op. It is equivalent to Commit with no author.
+func (h *History) StoreSnapshot(op cooperate.Operation, snapshot []byte) (int, error) {
+	return h.Commit(op, "", snapshot)
+}
+
+// StoreSubmission appends op, submitted by clientID as its operation seq, to
+// the history, and returns its seqno. It records the submission in the same
+// transaction, so that a server never commits an operation without being
+// able to recognize it when it is resent.
+func (h *History) StoreSubmission(clientID string, seq int, op cooperate.Operation) (int, error) {
+	return h.commit(op, clientID, nil, &submission{clientID, seq})
+}
+
+// StoreSubmissionSnapshot is like StoreSubmission, but also stores snapshot
+// in the same transaction, as StoreSnapshot does.
+func (h *History) StoreSubmissionSnapshot(clientID string, seq int, op cooperate.Operation, snapshot []byte) (int, error) {
+	return h.commit(op, clientID, snapshot, &submission{clientID, seq})
+}
+
+// A submission identifies an operation submitted by a client.
+type submission struct {
+	client string
+	seq    int
+}
+
+func (h *History) commit(op cooperate.Operation, author string, snapshot []byte, sub *submission) (seqno int, err error) {
+
+	data, err := h.codec.MarshalOperation(op)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now
+	if h.Now != nil {
+		now = h.Now
+	}
+
+	tx, err := h.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() {
+		if err != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := tx.QueryRow(querySequenceNumber, h.document).Scan(&seqno); err != nil {
+		return 0, err
+	}
+	seqno++
+
+	if _, err := tx.Exec(insertOperation, h.document, seqno, author, now().UnixNano(), data); err != nil {
+		return 0, err
+	}
+
+	if snapshot != nil {
+		if _, err := tx.Exec(insertSnapshot, h.document, seqno, snapshot); err != nil {
+			return 0, err
+		}
+	}
+
+	if sub != nil {
+		if _, err := tx.Exec(deleteSubmission, h.document, sub.client); err != nil {
+			return 0, err
+		}
+		if _, err := tx.Exec(insertSubmission, h.document, sub.client, sub.seq, seqno); err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return seqno, nil
+
+}
+
+// Committed returns the seqno of the operation committed for submission seq
+// of clientID, and whether there is one. It returns
+// cooperate.ErrStaleSubmission if seq precedes the last submission committed
+// for clientID.
+func (h *History) Committed(clientID string, seq int) (seqno int, ok bool, err error) {
+	var last int
+	switch err := h.db.QueryRow(querySubmission, h.document, clientID).Scan(&last, &seqno); {
+	case err == sql.ErrNoRows:
+		return 0, false, nil
+	case err != nil:
+		return 0, false, err
+	case seq > last:
+		return 0, false, nil
+	case seq < last:
+		return 0, false, cooperate.ErrStaleSubmission
+	}
+	return seqno, true, nil
+}
+
+// Iterate traverses through all operations between startingSeqno and
+// SequenceNumber() inclusive.
+func (h *History) Iterate(startingSeqno int, cb func(seqno int, op cooperate.Operation) error) error {
+
+	rows, err := h.db.Query(queryIterate, h.document, startingSeqno)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var seqno int
+		var data []byte
+		if err := rows.Scan(&seqno, &data); err != nil {
+			return err
+		}
+		op, err := h.codec.UnmarshalOperation(data)
+		if err != nil {
+			return err
+		}
+		if err := cb(seqno-1, op); err != nil {
+			return err
+		}
+	}
+
+	return rows.Err()
+
+}
+
+// Revision returns the operation that produced revision seqno, along with
+// the details of its commit.
+func (h *History) Revision(seqno int) (Revision, error) {
+
+	r := Revision{Seqno: seqno}
+
+	var nanos int64
+	var data []byte
+	switch err := h.db.QueryRow(queryRevision, h.document, seqno).Scan(&r.Author, &nanos, &data); {
+	case err == sql.ErrNoRows:
+		return Revision{}, ErrNotFound
+	case err != nil:
+		return Revision{}, err
+	}
+
+	op, err := h.codec.UnmarshalOperation(data)
+	if err != nil {
+		return Revision{}, err
+	}
+
+	r.Time, r.Operation = time.Unix(0, nanos), op
+	return r, nil
+
+}
+
+// Snapshot returns the latest snapshot taken at or before revision seqno,
+// and the revision it was taken at. Applying the operations that follow it
+// brings the document up to date.
+func (h *History) Snapshot(seqno int) (at int, snapshot []byte, err error) {
+	switch err := h.db.QueryRow(querySnapshot, h.document, seqno).Scan(&at, &snapshot); {
+	case err == sql.ErrNoRows:
+		return 0, nil, ErrNotFound
+	case err != nil:
+		return 0, nil, err
+	}
+	return at, snapshot, nil
+}
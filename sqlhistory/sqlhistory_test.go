@@ -0,0 +1,330 @@
+package sqlhistory
+
+import (
+	"database/sql"
+	"encoding/gob"
+	"log"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func init() {
+	gob.Register(text.RetainAction(0))
+	gob.Register(text.InsertAction(""))
+	gob.Register(text.DeleteAction(""))
+}
+
+// driverName and dataSourceName choose the database the tests run against:
+// by default the fake driver of driver_test.go, and with the sqlite build tag,
+// SQLite.
+var (
+	driverName     = "sqlhistory-fake"
+	dataSourceName = func(t *testing.T) string { return t.Name() }
+)
+
+func open(t *testing.T) *sql.DB {
+	db, err := sql.Open(driverName, dataSourceName(t))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := CreateTables(db); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	return db
+}
+
+func TestHistory(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+	h.Now = func() time.Time { return time.Unix(1500000000, 0) }
+
+	ops := []cooperate.Operation{
+		{text.InsertAction("foo")},
+		{text.RetainAction(3), text.InsertAction(" bar")},
+		{text.DeleteAction("foo"), text.RetainAction(4)},
+	}
+
+	for i, op := range ops {
+		if seqno, err := h.Commit(op, "ada", nil); err != nil {
+			t.Fatalf("[op %d] unexpected error: %s", i, err)
+		} else if seqno != i+1 {
+			t.Errorf("[op %d] unexpected seqno: expected %d but got %d", i, i+1, seqno)
+		}
+	}
+
+	// another document in the same database has its own history
+	if seqno, err := New(db, "other", GobCodec{}).Store(cooperate.Operation{text.InsertAction("x")}); err != nil || seqno != 1 {
+		t.Errorf("unexpected result storing another document: %d, %v", seqno, err)
+	}
+
+	if seqno := h.SequenceNumber(); seqno != 3 {
+		t.Errorf("unexpected sequence number: expected 3 but got %d", seqno)
+	}
+
+	// iterating must behave exactly like MemoryHistory
+	mh := cooperate.MemoryHistory(ops)
+	for start := 0; start <= len(ops); start++ {
+		var expected, got []cooperate.Operation
+		mh.Iterate(start, func(seqno int, op cooperate.Operation) error {
+			expected = append(expected, op)
+			return nil
+		})
+		if err := h.Iterate(start, func(seqno int, op cooperate.Operation) error {
+			if seqno != start+len(got) {
+				t.Errorf("[start %d] unexpected seqno %d", start, seqno)
+			}
+			got = append(got, op)
+			return nil
+		}); err != nil {
+			t.Fatalf("[start %d] unexpected error: %s", start, err)
+		}
+		if !reflect.DeepEqual(got, expected) {
+			t.Errorf("[start %d] unexpected operations: expected %#v but got %#v", start, expected, got)
+		}
+	}
+
+	expected := Revision{Seqno: 2, Author: "ada", Time: time.Unix(1500000000, 0), Operation: ops[1]}
+	if r, err := h.Revision(2); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	} else if !reflect.DeepEqual(r, expected) {
+		t.Errorf("unexpected revision: expected %#v but got %#v", expected, r)
+	}
+
+	if _, err := h.Revision(4); err != ErrNotFound {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", ErrNotFound, err)
+	}
+
+}
+
+func TestHistory_Snapshot(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+
+	h.Commit(cooperate.Operation{text.InsertAction("foo")}, "ada", []byte("foo"))
+	h.Commit(cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}, "ada", nil)
+
+	if at, snapshot, err := h.Snapshot(h.SequenceNumber()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if at != 1 || string(snapshot) != "foo" {
+		t.Errorf("unexpected snapshot: expected (1, foo) but got (%d, %s)", at, snapshot)
+	}
+
+	if _, _, err := h.Snapshot(0); err != ErrNotFound {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", ErrNotFound, err)
+	}
+
+}
+
+func TestHistory_SequenceNumberError(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+
+	var logged strings.Builder
+	h.ErrorLog = log.New(&logged, "", 0)
+
+	db.Close()
+
+	if seqno := h.SequenceNumber(); seqno != -1 {
+		t.Errorf("unexpected sequence number: expected -1 but got %d", seqno)
+	}
+	if !strings.Contains(logged.String(), "database is closed") {
+		t.Errorf("unexpected log: %q", logged.String())
+	}
+
+	// a server sees the error itself
+	if _, err := h.ReadSequenceNumber(); err == nil || !strings.Contains(err.Error(), "database is closed") {
+		t.Errorf("unexpected error: %v", err)
+	}
+	s := &cooperate.Server{Document: text.NewTextDocument(""), History: h}
+	if err := s.Resync(0, "", 0, nil); err == nil || err == cooperate.ErrSeqnoOutOfRange {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+}
+
+func TestHistory_Atomic(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+
+	h.Store(cooperate.Operation{text.InsertAction("foo")})
+
+	// if the snapshot can't be written, neither is the operation
+	fakeDatabase(t).failSnapshots = true
+
+	if _, err := h.Commit(cooperate.Operation{text.RetainAction(3), text.InsertAction("d")}, "ada", []byte("food")); err == nil {
+		t.Fatalf("expected error")
+	}
+
+	if seqno := h.SequenceNumber(); seqno != 1 {
+		t.Errorf("unexpected sequence number: expected 1 but got %d", seqno)
+	}
+
+}
+
+func TestHistory_Submissions(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+
+	for i, seq := range []int{1, 2} {
+		if seqno, err := h.StoreSubmission("alice", seq, cooperate.Operation{text.InsertAction("a")}); err != nil {
+			t.Fatalf("[seq %d] unexpected error: %s", seq, err)
+		} else if seqno != i+1 {
+			t.Errorf("[seq %d] unexpected seqno: expected %d but got %d", seq, i+1, seqno)
+		}
+	}
+
+	// the submissions are remembered by a new History for the document, but
+	// not by that of another document
+	h = New(db, "doc", GobCodec{})
+
+	cases := []struct {
+		Document string
+		ClientID string
+		Seq      int
+		Seqno    int
+		OK       bool
+		Error    error
+	}{
+		{Document: "doc", ClientID: "alice", Seq: 2, Seqno: 2, OK: true},
+		{Document: "doc", ClientID: "alice", Seq: 3},
+		{Document: "doc", ClientID: "alice", Seq: 1, Error: cooperate.ErrStaleSubmission},
+		{Document: "doc", ClientID: "bob", Seq: 1},
+		{Document: "other", ClientID: "alice", Seq: 2},
+	}
+
+	for i, c := range cases {
+		if seqno, ok, err := New(db, c.Document, GobCodec{}).Committed(c.ClientID, c.Seq); err != c.Error {
+			t.Errorf("[case %d] unexpected error state: expected '%v' but got '%v'", i, c.Error, err)
+		} else if seqno != c.Seqno || ok != c.OK {
+			t.Errorf("[case %d] unexpected result: expected (%d, %t) but got (%d, %t)", i, c.Seqno, c.OK, seqno, ok)
+		}
+	}
+
+	if r, err := h.Revision(2); err != nil || r.Author != "alice" {
+		t.Errorf("unexpected revision: %+v, %v", r, err)
+	}
+
+}
+
+func TestServer_Submit(t *testing.T) {
+
+	db := open(t)
+	sub := cooperate.Submission{ClientID: "alice", Seq: 1, Operation: cooperate.Operation{text.InsertAction("foo")}}
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            New(db, "doc", GobCodec{}),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+	if _, err := s.Submit(sub); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a restarted server recognizes the resent operation
+	s = &cooperate.Server{
+		Document:           text.NewTextDocument("foo"),
+		History:            New(db, "doc", GobCodec{}),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+	if ack, err := s.Submit(sub); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if expected := (cooperate.Ack{Revision: 1, Duplicate: true}); ack != expected {
+		t.Errorf("unexpected ack: expected %+v but got %+v", expected, ack)
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "foo" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+}
+
+func TestServer(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            New(open(t), "doc", GobCodec{}),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	ops := []struct {
+		Root      int
+		Operation cooperate.Operation
+	}{
+		{Root: 0, Operation: cooperate.Operation{text.InsertAction("red")}},
+		{Root: 1, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("blue")}},
+		{Root: 0, Operation: cooperate.Operation{text.InsertAction("green")}},
+	}
+
+	for _, op := range ops {
+		if err := s.Apply(op.Root, op.Operation); err != nil {
+			t.Fatalf("apply error: %s", err)
+		}
+	}
+
+	if doc := s.Document.(*text.TextDocument); doc.String() != "greenredblue" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+}
+
+func TestServer_Snapshots(t *testing.T) {
+
+	db := open(t)
+	h := New(db, "doc", GobCodec{})
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument(""),
+		History:            h,
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Snapshotter:        text.TextSnapshotter{},
+		SnapshotInterval:   2,
+	}
+
+	if err := s.Apply(0, cooperate.Operation{text.InsertAction("red")}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+	sub := cooperate.Submission{ClientID: "alice", Seq: 1, Root: 1, Operation: cooperate.Operation{text.RetainAction(3), text.InsertAction("blue")}}
+	if _, err := s.Submit(sub); err != nil {
+		t.Fatalf("submit error: %s", err)
+	}
+	if err := s.Apply(2, cooperate.Operation{text.InsertAction("green"), text.RetainAction(7)}); err != nil {
+		t.Fatalf("apply error: %s", err)
+	}
+
+	// the submission was committed with a snapshot
+	if at, snapshot, err := h.Snapshot(h.SequenceNumber()); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if at != 2 || string(snapshot) != "redblue" {
+		t.Errorf("unexpected snapshot: expected (2, redblue) but got (%d, %s)", at, snapshot)
+	}
+	if r, err := h.Revision(2); err != nil || r.Author != "alice" {
+		t.Errorf("unexpected revision: %+v, %v", r, err)
+	}
+
+}
+
+// fakeDatabase returns the database opened by the current test, which is
+// skipped if it runs against a real database.
+func fakeDatabase(t *testing.T) *fakeDB {
+	if driverName != "sqlhistory-fake" {
+		t.Skip("requires the fake driver")
+	}
+	db, _ := sql.Open("sqlhistory-fake", t.Name())
+	d := db.Driver().(*fakeDriver)
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.dbs[t.Name()]
+}
@@ -0,0 +1,23 @@
+//go:build sqlite
+
+package sqlhistory
+
+// Run the tests against SQLite, rather than the fake driver, with
+//
+//	go test -tags sqlite ./sqlhistory
+//
+// which requires cgo.
+
+import (
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func init() {
+	driverName = "sqlite3"
+	dataSourceName = func(t *testing.T) string {
+		return "file:" + filepath.Join(t.TempDir(), "history.db")
+	}
+}
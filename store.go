@@ -0,0 +1,299 @@
+package cooperate
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	// ErrNoStore indicates that a client without a Store was saved or
+	// loaded.
+	ErrNoStore = errors.New("client has no store")
+
+	// ErrNoSnapshotter indicates that a client without a Snapshotter was
+	// saved or loaded.
+	ErrNoSnapshotter = errors.New("client has no snapshotter")
+)
+
+// A SaveError indicates that a client changed, but failed to save its state
+// to its Store afterwards. The change has taken effect and must not be made
+// again: the Store keeps the state last saved until the client next saves,
+// which saves the change as well.
+type SaveError struct {
+	Err error
+}
+
+func (e *SaveError) Error() string {
+	return fmt.Sprintf("saving client state: %s", e.Err)
+}
+
+func (e *SaveError) Unwrap() error {
+	return e.Err
+}
+
+// snapshotInterval is the number of changes a client saves before it
+// snapshots its document again.
+const snapshotInterval = 100
+
+type (
+	// A ClientStateOf is the state of a ClientOf that must survive a restart
+	// of its process for pending edits to reach the server.
+	ClientStateOf[A any] struct {
+		// Snapshot is the client's Document as of some earlier save, encoded
+		// by its Snapshotter, and Changes are the operations applied to it
+		// since, in order. Snapshot is the same from one save to the next
+		// until Changes are folded into it.
+		Snapshot []byte
+		Changes  []OperationOf[A]
+
+		Revision int
+		Seq      int
+		InFlight OperationOf[A]
+		Buffer   OperationOf[A]
+	}
+
+	// A ClientStoreOf persists the state of a client locally, such as in a
+	// file or a mobile platform's storage.
+	ClientStoreOf[A any] interface {
+		// Save replaces the stored state with state.
+		Save(state ClientStateOf[A]) error
+
+		// Load returns the stored state, and whether there is one.
+		Load() (state ClientStateOf[A], ok bool, err error)
+	}
+
+	// A SnapshotterOf encodes documents for a ClientStoreOf.
+	SnapshotterOf[A any] interface {
+		Snapshot(doc DocumentOf[A]) ([]byte, error)
+		Restore(data []byte) (DocumentOf[A], error)
+	}
+
+	// MemoryClientStoreOf is the simplest possible ClientStoreOf
+	// implementation, keeping the last state saved in memory.
+	MemoryClientStoreOf[A any] struct {
+		state *ClientStateOf[A]
+	}
+
+	// FileClientStoreOf is a ClientStoreOf that keeps the last state saved
+	// in a file, encoded by MarshalClientState.
+	FileClientStoreOf[A any] struct {
+		Path string
+	}
+
+	// ClientState is a ClientStateOf untyped actions.
+	ClientState = ClientStateOf[Action]
+
+	// ClientStore is a ClientStoreOf untyped actions.
+	ClientStore = ClientStoreOf[Action]
+
+	// Snapshotter is a SnapshotterOf untyped actions.
+	Snapshotter = SnapshotterOf[Action]
+
+	// MemoryClientStore is a MemoryClientStoreOf untyped actions.
+	MemoryClientStore = MemoryClientStoreOf[Action]
+
+	// FileClientStore is a FileClientStoreOf untyped actions.
+	FileClientStore = FileClientStoreOf[Action]
+)
+
+func (ms *MemoryClientStoreOf[A]) Save(state ClientStateOf[A]) error {
+	ms.state = &state
+	return nil
+}
+
+func (ms *MemoryClientStoreOf[A]) Load() (ClientStateOf[A], bool, error) {
+	if ms.state == nil {
+		return ClientStateOf[A]{}, false, nil
+	}
+	return *ms.state, true, nil
+}
+
+// Save replaces the file with state, by way of a temporary file so that a
+// crash cannot leave it half written.
+func (fs FileClientStoreOf[A]) Save(state ClientStateOf[A]) error {
+
+	data, err := MarshalClientState(state)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.CreateTemp(filepath.Dir(fs.Path), filepath.Base(fs.Path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), fs.Path)
+
+}
+
+// Load reads the file, which need not exist.
+func (fs FileClientStoreOf[A]) Load() (ClientStateOf[A], bool, error) {
+
+	data, err := os.ReadFile(fs.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ClientStateOf[A]{}, false, nil
+	} else if err != nil {
+		return ClientStateOf[A]{}, false, err
+	}
+
+	state, err := UnmarshalClientState[A](data)
+	if err != nil {
+		return ClientStateOf[A]{}, false, err
+	}
+	return state, true, nil
+
+}
+
+// clientStateGob is the gob encoding of a ClientStateOf. gob decodes an
+// empty slice as nil, so it records whether InFlight is set as well.
+type clientStateGob[A any] struct {
+	State       ClientStateOf[A]
+	HasInFlight bool
+}
+
+// MarshalClientState encodes state using encoding/gob. If A is an interface
+// type, such as Action, the action types used in operations must be
+// registered with gob.Register.
+func MarshalClientState[A any](state ClientStateOf[A]) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(clientStateGob[A]{State: state, HasInFlight: state.InFlight != nil}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalClientState decodes a state encoded by MarshalClientState.
+func UnmarshalClientState[A any](data []byte) (ClientStateOf[A], error) {
+	var sg clientStateGob[A]
+	if err := gob.NewDecoder(bytes.NewReader(data)).Decode(&sg); err != nil {
+		return ClientStateOf[A]{}, err
+	}
+	if sg.HasInFlight && sg.State.InFlight == nil {
+		sg.State.InFlight = OperationOf[A]{}
+	}
+	return sg.State, nil
+}
+
+// Save snapshots Document and writes the state of the client to its Store.
+// The client saves itself after every change to its state, so Save need only
+// be called to persist a client that was not created by Load.
+func (c *ClientOf[A]) Save() error {
+
+	if c.Store == nil {
+		return ErrNoStore
+	} else if c.Snapshotter == nil {
+		return ErrNoSnapshotter
+	}
+
+	snapshot, err := c.Snapshotter.Snapshot(c.Document)
+	if err != nil {
+		return err
+	}
+
+	c.snapshot, c.changes = snapshot, nil
+	return c.store()
+
+}
+
+// Load restores the state of the client from its Store, and reports whether
+// there was any. A client whose InFlight operation is restored should catch
+// up with ServerOf.Resync before proposing it again.
+func (c *ClientOf[A]) Load() (bool, error) {
+
+	if c.Store == nil {
+		return false, ErrNoStore
+	} else if c.Snapshotter == nil {
+		return false, ErrNoSnapshotter
+	}
+
+	state, ok, err := c.Store.Load()
+	if err != nil || !ok {
+		return false, err
+	}
+
+	doc, err := c.Snapshotter.Restore(state.Snapshot)
+	if err != nil {
+		return false, err
+	}
+	for _, op := range state.Changes {
+		if err := doc.Apply(op); err != nil {
+			return false, err
+		}
+	}
+
+	c.Document = doc
+	c.Revision, c.Seq = state.Revision, state.Seq
+	c.InFlight, c.Buffer = state.InFlight, state.Buffer
+
+	// clip the changes so that appending to them leaves the stored state be
+	c.snapshot, c.changes = state.Snapshot, state.Changes[:len(state.Changes):len(state.Changes)]
+
+	return true, nil
+
+}
+
+// checkStore returns ErrNoSnapshotter if the client has a Store it cannot
+// save to, before the client changes rather than after.
+func (c *ClientOf[A]) checkStore() error {
+	if c.Store != nil && c.Snapshotter == nil {
+		return ErrNoSnapshotter
+	}
+	return nil
+}
+
+// save saves the client, if it has a Store, after op was applied to
+// Document, returning a *SaveError if it fails. Snapshotting Document costs
+// O(document), so rather than do so after every change, save records the
+// changes made since the last snapshot and snapshots Document only once
+// there are snapshotInterval of them.
+func (c *ClientOf[A]) save(op OperationOf[A]) error {
+
+	if c.Store == nil {
+		return nil
+	}
+
+	var err error
+	if c.snapshot == nil || len(c.changes) >= snapshotInterval {
+		err = c.Save()
+	} else {
+		if op != nil {
+			c.changes = append(c.changes, op)
+		}
+		err = c.store()
+	}
+
+	if err != nil {
+		return &SaveError{Err: err}
+	}
+	return nil
+
+}
+
+// store writes the state of the client to its Store.
+func (c *ClientOf[A]) store() error {
+	return c.Store.Save(ClientStateOf[A]{
+		Snapshot: c.snapshot,
+		Changes:  c.changes,
+		Revision: c.Revision,
+		Seq:      c.Seq,
+		InFlight: c.InFlight,
+		Buffer:   c.Buffer,
+	})
+}
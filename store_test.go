@@ -0,0 +1,243 @@
+package cooperate_test
+
+import (
+	"encoding/gob"
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+func init() {
+	gob.Register(text.RetainAction(0))
+	gob.Register(text.InsertAction(""))
+	gob.Register(text.DeleteAction(""))
+}
+
+func TestClient_Store(t *testing.T) {
+
+	store := &cooperate.MemoryClientStore{}
+
+	newClient := func() *cooperate.Client {
+		return &cooperate.Client{
+			Document:           text.NewTextDocument("-"),
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{},
+			Store:              store,
+			Snapshotter:        text.TextSnapshotter{},
+		}
+	}
+
+	if ok, err := newClient().Load(); err != nil || ok {
+		t.Fatalf("unexpected result loading an empty store: %t, %v", ok, err)
+	}
+
+	s := &cooperate.Server{
+		Document:           text.NewTextDocument("-"),
+		History:            &cooperate.DedupMemoryHistory{},
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+	if _, err := s.Submit(cooperate.Submission{ClientID: "bob", Seq: 1, Operation: cooperate.Operation{text.RetainAction(1), text.InsertAction("xy")}}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the client edits offline, and its process exits
+	client := newClient()
+	for _, op := range []cooperate.Operation{
+		{text.InsertAction("ab"), text.RetainAction(1)},
+		{text.RetainAction(2), text.InsertAction("c"), text.RetainAction(1)},
+		{text.RetainAction(3), text.InsertAction("d"), text.RetainAction(1)},
+	} {
+		if err := client.ApplyLocal(op); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	// after restarting, the client resumes where it left off
+	restored := newClient()
+	if ok, err := restored.Load(); err != nil || !ok {
+		t.Fatalf("unexpected result loading the store: %t, %v", ok, err)
+	}
+
+	if doc := restored.Document.(*text.TextDocument).String(); doc != "abcd-" {
+		t.Errorf("unexpected document: expected 'abcd-' but got '%s'", doc)
+	}
+	if restored.Revision != client.Revision || restored.Seq != client.Seq || !reflect.DeepEqual(restored.InFlight, client.InFlight) || !reflect.DeepEqual(restored.Buffer, client.Buffer) {
+		t.Errorf("unexpected state: expected %+v but got %+v", client, restored)
+	}
+
+	// and synchronizes once it is online
+	if err := s.Resync(restored.Revision, "alice", restored.Seq, restored.Catchup); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	for restored.InFlight != nil {
+		if _, err := s.Submit(cooperate.Submission{ClientID: "alice", Seq: restored.Seq, Root: restored.Revision, Operation: restored.InFlight}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if err := restored.Acknowledge(); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	server, local := s.Document.(*text.TextDocument).String(), restored.Document.(*text.TextDocument).String()
+	if server != "abcd-xy" || local != server {
+		t.Errorf("documents diverged: server has %q and client has %q", server, local)
+	}
+
+	// every change was saved
+	if state, _, _ := store.Load(); state.Revision != 3 || state.InFlight != nil {
+		t.Errorf("unexpected stored state: %+v", state)
+	}
+	if reloaded := newClient(); !reloadedEqual(t, reloaded, restored) {
+		t.Errorf("unexpected state: expected %+v but got %+v", restored, reloaded)
+	}
+
+}
+
+func TestClient_FileStore(t *testing.T) {
+
+	store := cooperate.FileClientStore{Path: filepath.Join(t.TempDir(), "client")}
+
+	newClient := func() *cooperate.Client {
+		return &cooperate.Client{
+			Document:           text.NewTextDocument("xy"),
+			ExpandReducer:      text.TextHandler{},
+			ComposeTransformer: text.TextHandler{Priority: text.FavorA},
+			Store:              store,
+			Snapshotter:        text.TextSnapshotter{},
+		}
+	}
+
+	if ok, err := newClient().Load(); err != nil || ok {
+		t.Fatalf("unexpected result loading an empty store: %t, %v", ok, err)
+	}
+
+	// the client and another delete the same text, which empties its
+	// in-flight operation but leaves it in flight
+	client := newClient()
+	if err := client.ApplyLocal(cooperate.Operation{text.DeleteAction("xy")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if err := client.ApplyReceived(cooperate.Operation{text.DeleteAction("xy")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// enough edits follow for the document to be snapshotted again
+	for i := 0; i < 150; i++ {
+		if err := client.ApplyLocal(cooperate.Operation{text.RetainAction(i), text.InsertAction("z")}); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+
+	restored := newClient()
+	if !reloadedEqual(t, restored, client) {
+		t.Fatalf("unexpected state: expected %+v but got %+v", client, restored)
+	}
+	if restored.InFlight == nil {
+		t.Fatalf("expected an empty operation in flight")
+	}
+
+	// the restored client can be acknowledged, and its buffer proposed
+	if err := restored.Acknowledge(); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if doc := restored.Document.(*text.TextDocument).String(); doc != strings.Repeat("z", 150) || len(restored.InFlight) == 0 {
+		t.Errorf("unexpected state: %q, %#v", doc, restored.InFlight)
+	}
+
+}
+
+func TestClient_StoreErrors(t *testing.T) {
+
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument(""),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+	}
+
+	if err := client.Save(); err != cooperate.ErrNoStore {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", cooperate.ErrNoStore, err)
+	}
+
+	client.Store = &cooperate.MemoryClientStore{}
+
+	if err := client.ApplyLocal(cooperate.Operation{text.InsertAction("a")}); err != cooperate.ErrNoSnapshotter {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", cooperate.ErrNoSnapshotter, err)
+	}
+	if _, err := client.Load(); err != cooperate.ErrNoSnapshotter {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", cooperate.ErrNoSnapshotter, err)
+	}
+
+	// the client is checked before it changes
+	if doc := client.Document.(*text.TextDocument); doc.String() != "" || client.InFlight != nil || client.Seq != 0 {
+		t.Errorf("unexpected change: document %q, in flight %#v, seq %d", doc.String(), client.InFlight, client.Seq)
+	}
+
+}
+
+// failingStore is a MemoryClientStore that fails to save while fail is set.
+type failingStore struct {
+	cooperate.MemoryClientStore
+	fail bool
+}
+
+var errSave = errors.New("save failed")
+
+func (fs *failingStore) Save(state cooperate.ClientState) error {
+	if fs.fail {
+		return errSave
+	}
+	return fs.MemoryClientStore.Save(state)
+}
+
+func TestClient_SaveError(t *testing.T) {
+
+	store := &failingStore{}
+	client := &cooperate.Client{
+		Document:           text.NewTextDocument(""),
+		ExpandReducer:      text.TextHandler{},
+		ComposeTransformer: text.TextHandler{},
+		Store:              store,
+		Snapshotter:        text.TextSnapshotter{},
+	}
+
+	if err := client.ApplyLocal(cooperate.Operation{text.InsertAction("a")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// a change that fails to save has been made nonetheless
+	store.fail = true
+	var saveErr *cooperate.SaveError
+	if err := client.ApplyLocal(cooperate.Operation{text.RetainAction(1), text.InsertAction("b")}); !errors.As(err, &saveErr) || saveErr.Err != errSave {
+		t.Fatalf("unexpected error: expected a SaveError but got '%v'", err)
+	}
+	if doc := client.Document.(*text.TextDocument); doc.String() != "ab" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+	// and is saved with the next change
+	store.fail = false
+	if err := client.ApplyLocal(cooperate.Operation{text.RetainAction(2), text.InsertAction("c")}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if !reloadedEqual(t, &cooperate.Client{Store: store, Snapshotter: text.TextSnapshotter{}}, client) {
+		t.Errorf("reloaded client differs from the original")
+	}
+
+}
+
+// reloadedEqual loads into client and reports whether it then matches
+// expected.
+func reloadedEqual(t *testing.T, client, expected *cooperate.Client) bool {
+	if ok, err := client.Load(); err != nil || !ok {
+		t.Fatalf("unexpected result loading the store: %t, %v", ok, err)
+	}
+	return client.Document.(*text.TextDocument).String() == expected.Document.(*text.TextDocument).String() &&
+		client.Revision == expected.Revision && client.Seq == expected.Seq &&
+		reflect.DeepEqual(client.InFlight, expected.InFlight) && reflect.DeepEqual(client.Buffer, expected.Buffer)
+}
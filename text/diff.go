@@ -0,0 +1,292 @@
+package text
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// Diff returns an operation that changes from into to with as few inserted
+// and deleted runes as possible, computed with Myers' diff algorithm.
+//
+// Bytes that are not valid UTF-8 are compared one at a time, as if each were
+// a rune of its own.
+//
+// Minimal operations are often hard for people to read, since they match
+// every coincidentally equal character; see DiffSemantic.
+func Diff(from, to string) cooperate.Operation {
+	return toOperation(diffRunes(runes(from), runes(to)))
+}
+
+// DiffSemantic is like Diff, but gives up minimality to remove short
+// stretches of unchanged text between larger changes, so that the operation
+// replaces whole words or phrases as a person would.
+func DiffSemantic(from, to string) cooperate.Operation {
+	return toOperation(cleanupSemantic(diffRunes(runes(from), runes(to))))
+}
+
+// runes splits s into the encodings of its runes. Unlike converting s to a
+// []rune, it keeps bytes that are not valid UTF-8 as they are, so that they
+// neither compare equal to each other nor change the length of s.
+func runes(s string) []string {
+	r := make([]string, 0, len(s))
+	for len(s) > 0 {
+		_, size := utf8.DecodeRuneInString(s)
+		r, s = append(r, s[:size]), s[size:]
+	}
+	return r
+}
+
+// A diffKind identifies a stretch of a diff as deleted, unchanged or
+// inserted.
+type diffKind int
+
+const (
+	diffDelete diffKind = iota - 1
+	diffEqual
+	diffInsert
+)
+
+type diff struct {
+	kind diffKind
+	text string
+}
+
+func toOperation(diffs []diff) cooperate.Operation {
+	var op cooperate.Operation
+	for _, d := range diffs {
+		switch d.kind {
+		case diffEqual:
+			op = append(op, RetainAction(len(d.text)))
+		case diffDelete:
+			op = append(op, DeleteAction(d.text))
+		case diffInsert:
+			op = append(op, InsertAction(d.text))
+		}
+	}
+	return op
+}
+
+// diffRunes returns the stretches of a minimal diff from a to b.
+func diffRunes(a, b []string) []diff {
+
+	var prefix int
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	var suffix int
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-suffix-1] == b[len(b)-suffix-1] {
+		suffix++
+	}
+
+	var diffs []diff
+	diffs = appendDiff(diffs, diffEqual, a[:prefix])
+
+	switch a, b := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]; {
+	case len(a) == 0:
+		diffs = appendDiff(diffs, diffInsert, b)
+	case len(b) == 0:
+		diffs = appendDiff(diffs, diffDelete, a)
+	default:
+		for _, d := range bisect(a, b) {
+			diffs = appendDiffString(diffs, d.kind, d.text)
+		}
+	}
+
+	return appendDiff(diffs, diffEqual, a[len(a)-suffix:])
+
+}
+
+// bisect finds the middle snake of a minimal diff from a to b, and diffs the
+// halves on either side of it separately, so that it needs only linear space.
+func bisect(a, b []string) []diff {
+
+	n, m := len(a), len(b)
+	maxD := (n + m + 1) / 2
+	offset := maxD
+
+	// v1 and v2 hold the furthest x reached on each diagonal k from the start
+	// and from the end respectively
+	v1, v2 := make([]int, 2*maxD+2), make([]int, 2*maxD+2)
+	for i := range v1 {
+		v1[i], v2[i] = -1, -1
+	}
+	v1[offset+1], v2[offset+1] = 0, 0
+
+	// if the total number of runes is odd, the front path will collide
+	// with the reverse path
+	delta := n - m
+	front := delta%2 != 0
+
+	// the diagonals that have run off the edges of the grid
+	var k1start, k1end, k2start, k2end int
+
+	for d := 0; d < maxD; d++ {
+
+		// walk the front path one step
+		for k1 := -d + k1start; k1 <= d-k1end; k1 += 2 {
+			k1Offset := offset + k1
+			var x1 int
+			if k1 == -d || (k1 != d && v1[k1Offset-1] < v1[k1Offset+1]) {
+				x1 = v1[k1Offset+1]
+			} else {
+				x1 = v1[k1Offset-1] + 1
+			}
+			y1 := x1 - k1
+			for x1 < n && y1 < m && a[x1] == b[y1] {
+				x1++
+				y1++
+			}
+			v1[k1Offset] = x1
+			switch {
+			case x1 > n:
+				k1end += 2
+			case y1 > m:
+				k1start += 2
+			case front:
+				if k2Offset := offset + delta - k1; k2Offset >= 0 && k2Offset < len(v2) && v2[k2Offset] != -1 {
+					if x1 >= n-v2[k2Offset] {
+						return split(a, b, x1, y1)
+					}
+				}
+			}
+		}
+
+		// walk the reverse path one step
+		for k2 := -d + k2start; k2 <= d-k2end; k2 += 2 {
+			k2Offset := offset + k2
+			var x2 int
+			if k2 == -d || (k2 != d && v2[k2Offset-1] < v2[k2Offset+1]) {
+				x2 = v2[k2Offset+1]
+			} else {
+				x2 = v2[k2Offset-1] + 1
+			}
+			y2 := x2 - k2
+			for x2 < n && y2 < m && a[n-x2-1] == b[m-y2-1] {
+				x2++
+				y2++
+			}
+			v2[k2Offset] = x2
+			switch {
+			case x2 > n:
+				k2end += 2
+			case y2 > m:
+				k2start += 2
+			case !front:
+				if k1Offset := offset + delta - k2; k1Offset >= 0 && k1Offset < len(v1) && v1[k1Offset] != -1 {
+					x1 := v1[k1Offset]
+					if y1 := offset + x1 - k1Offset; x1 >= n-x2 {
+						return split(a, b, x1, y1)
+					}
+				}
+			}
+		}
+
+	}
+
+	// the paths never met, which happens only if nothing is in common
+	return []diff{{diffDelete, strings.Join(a, "")}, {diffInsert, strings.Join(b, "")}}
+
+}
+
+// split diffs a and b separately on either side of the point (x, y).
+func split(a, b []string, x, y int) []diff {
+	return append(diffRunes(a[:x], b[:y]), diffRunes(a[x:], b[y:])...)
+}
+
+// appendDiff adds a stretch of kind to the end of diffs, merging it with the
+// last stretch if it is of the same kind. Deletions are placed before any
+// insertions that immediately precede them.
+func appendDiff(diffs []diff, kind diffKind, text []string) []diff {
+	return appendDiffString(diffs, kind, strings.Join(text, ""))
+}
+
+func appendDiffString(diffs []diff, kind diffKind, text string) []diff {
+
+	if text == "" {
+		return diffs
+	}
+
+	n := len(diffs)
+	switch {
+	case n > 0 && diffs[n-1].kind == kind:
+		diffs[n-1].text += text
+		return diffs
+
+	case kind == diffDelete && n > 0 && diffs[n-1].kind == diffInsert:
+		ins := diffs[n-1]
+		diffs = appendDiffString(diffs[:n-1], diffDelete, text)
+		return append(diffs, ins)
+	}
+
+	return append(diffs, diff{kind, text})
+
+}
+
+// cleanupSemantic removes each stretch of unchanged text that is no longer
+// than the changes on either side of it, by deleting and reinserting it.
+func cleanupSemantic(diffs []diff) []diff {
+
+	var (
+		equalities []int // indices of the candidate unchanged stretches
+		last       string
+
+		// lengths of the changes before and after the last equality
+		ins1, del1, ins2, del2 int
+	)
+
+	changed := false
+
+	for i := 0; i < len(diffs); i++ {
+
+		if diffs[i].kind == diffEqual {
+			equalities = append(equalities, i)
+			ins1, del1, ins2, del2 = ins2, del2, 0, 0
+			last = diffs[i].text
+			continue
+		}
+
+		if diffs[i].kind == diffInsert {
+			ins2 += utf8.RuneCountInString(diffs[i].text)
+		} else {
+			del2 += utf8.RuneCountInString(diffs[i].text)
+		}
+
+		l := utf8.RuneCountInString(last)
+		if last == "" || l > max(ins1, del1) || l > max(ins2, del2) {
+			continue
+		}
+
+		// replace the equality with a deletion and an insertion of it
+		j := equalities[len(equalities)-1]
+		diffs = append(diffs[:j], append([]diff{{diffDelete, last}, {diffInsert, last}}, diffs[j+1:]...)...)
+		changed = true
+
+		// throw away this equality and the one before it, which needs to be
+		// reevaluated now that the changes after it have grown
+		equalities = equalities[:len(equalities)-1]
+		if len(equalities) > 0 {
+			equalities = equalities[:len(equalities)-1]
+		}
+		i = -1
+		if len(equalities) > 0 {
+			i = equalities[len(equalities)-1]
+		}
+		ins1, del1, ins2, del2 = 0, 0, 0, 0
+		last = ""
+
+	}
+
+	if !changed {
+		return diffs
+	}
+
+	var merged []diff
+	for _, d := range diffs {
+		merged = appendDiffString(merged, d.kind, d.text)
+	}
+	return merged
+
+}
@@ -0,0 +1,159 @@
+package text
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestDiff(t *testing.T) {
+
+	cases := []struct {
+		From, To string
+		Expected cooperate.Operation
+		Semantic cooperate.Operation
+	}{
+		{
+			From:     "",
+			To:       "",
+			Expected: nil,
+			Semantic: nil,
+		},
+		{
+			From:     "abc",
+			To:       "abc",
+			Expected: cooperate.Operation{RetainAction(3)},
+			Semantic: cooperate.Operation{RetainAction(3)},
+		},
+		{
+			From:     "the cat",
+			To:       "the hat",
+			Expected: cooperate.Operation{RetainAction(4), DeleteAction("c"), InsertAction("h"), RetainAction(2)},
+			Semantic: cooperate.Operation{RetainAction(4), DeleteAction("c"), InsertAction("h"), RetainAction(2)},
+		},
+		{
+			From:     "héllo",
+			To:       "hällo wörld",
+			Expected: cooperate.Operation{RetainAction(1), DeleteAction("é"), InsertAction("ä"), RetainAction(3), InsertAction(" wörld")},
+			Semantic: cooperate.Operation{RetainAction(1), DeleteAction("é"), InsertAction("ä"), RetainAction(3), InsertAction(" wörld")},
+		},
+		{
+			From:     "\xffab",
+			To:       "\xffb",
+			Expected: cooperate.Operation{RetainAction(1), DeleteAction("a"), RetainAction(1)},
+			Semantic: cooperate.Operation{RetainAction(1), DeleteAction("a"), RetainAction(1)},
+		},
+		{
+			From:     "a\xfe",
+			To:       "b\xff",
+			Expected: cooperate.Operation{DeleteAction("a\xfe"), InsertAction("b\xff")},
+			Semantic: cooperate.Operation{DeleteAction("a\xfe"), InsertAction("b\xff")},
+		},
+		{
+			From:     "mouse",
+			To:       "sofas",
+			Expected: cooperate.Operation{DeleteAction("m"), InsertAction("s"), RetainAction(1), DeleteAction("u"), InsertAction("fa"), RetainAction(1), DeleteAction("e")},
+			Semantic: cooperate.Operation{DeleteAction("mouse"), InsertAction("sofas")},
+		},
+	}
+
+	for i, c := range cases {
+
+		if op := Diff(c.From, c.To); !reflect.DeepEqual(op, c.Expected) {
+			t.Errorf("[case %d] unexpected diff: expected '%#v' but got '%#v'", i, c.Expected, op)
+		}
+
+		if op := DiffSemantic(c.From, c.To); !reflect.DeepEqual(op, c.Semantic) {
+			t.Errorf("[case %d] unexpected semantic diff: expected '%#v' but got '%#v'", i, c.Semantic, op)
+		}
+	}
+
+}
+
+func TestDiff_Random(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+	alphabet := []rune("ab c€😀\n")
+
+	random := func() string {
+		s := make([]rune, r.Intn(40))
+		for i := range s {
+			s[i] = alphabet[r.Intn(len(alphabet))]
+		}
+		return string(s)
+	}
+
+	for i := 0; i < 500; i++ {
+
+		from, to := random(), random()
+
+		// a minimal diff changes every rune outside a longest common
+		// subsequence
+		var changed int
+		for _, a := range Diff(from, to) {
+			switch a := a.(type) {
+			case InsertAction:
+				changed += len([]rune(string(a)))
+			case DeleteAction:
+				changed += len([]rune(string(a)))
+			}
+		}
+		if expected := len([]rune(from)) + len([]rune(to)) - 2*lcs([]rune(from), []rune(to)); changed != expected {
+			t.Errorf("[case %d] diff of %q and %q is not minimal: changed %d runes instead of %d", i, from, to, changed, expected)
+		}
+
+		for _, diff := range []func(string, string) cooperate.Operation{Diff, DiffSemantic} {
+			doc := NewTextDocument(from)
+			if err := doc.Apply(diff(from, to)); err != nil {
+				t.Fatalf("[case %d] unexpected error diffing %q and %q: %s", i, from, to, err)
+			} else if doc.String() != to {
+				t.Fatalf("[case %d] unexpected result: expected %q but got %q", i, to, doc.String())
+			}
+		}
+	}
+
+}
+
+// lcs returns the length of the longest common subsequence of a and b.
+func lcs(a, b []rune) int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiff_Client(t *testing.T) {
+
+	from := strings.Repeat("lorem ipsum dolor sit amet\n", 3)
+	to := strings.Replace(from, "dolor", "dolores", 1)
+
+	c := &cooperate.Client{
+		Document:           NewTextDocument(from),
+		ExpandReducer:      TextHandler{},
+		ComposeTransformer: TextHandler{},
+	}
+
+	if err := c.ApplyLocal(Diff(from, to)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := cooperate.Operation{RetainAction(17), InsertAction("es"), RetainAction(64)}
+	if !reflect.DeepEqual(c.InFlight, expected) {
+		t.Errorf("unexpected operation: expected '%#v' but got '%#v'", expected, c.InFlight)
+	}
+
+}
@@ -25,6 +25,40 @@ func (td *TextDocument) String() string {
 
 // Apply performs op against the TextDocument.
 func (td *TextDocument) Apply(op cooperate.Operation) error {
+	return apply(td, op)
+}
+
+// TextSnapshotter is a cooperate.Snapshotter for TextDocuments. It also
+// snapshots PieceTableDocuments, but restores them as TextDocuments.
+type TextSnapshotter struct{}
+
+func (TextSnapshotter) Snapshot(doc cooperate.Document) ([]byte, error) {
+	s, ok := doc.(fmt.Stringer)
+	if !ok {
+		return nil, fmt.Errorf("cannot snapshot %T", doc)
+	}
+	return []byte(s.String()), nil
+}
+
+func (TextSnapshotter) Restore(data []byte) (cooperate.Document, error) {
+	return NewTextDocument(string(data)), nil
+}
+
+// Typed returns a view of the TextDocument that accepts operations made of
+// Actions, for use with TypedHandler.
+func (td *TextDocument) Typed() cooperate.DocumentOf[Action] {
+	return typedDocument{td}
+}
+
+type typedDocument struct {
+	*TextDocument
+}
+
+func (td typedDocument) Apply(op cooperate.OperationOf[Action]) error {
+	return apply(td.TextDocument, op)
+}
+
+func apply[A any](td *TextDocument, op cooperate.OperationOf[A]) error {
 
 	// verify that operation will apply cleanly to document
 	// TODO(tylerchr): Assert that the post length also matches.
@@ -38,22 +72,20 @@ func (td *TextDocument) Apply(op cooperate.Operation) error {
 
 	for iter.More() {
 
-		nextType, next := iter.PeekType(), iter.Peek()
-
-		switch nextType {
-		case Retain:
-			ret := int(next.(RetainAction))
+		switch next := any(iter.Peek()).(type) {
+		case RetainAction:
+			ret := int(next)
 			cursor += ret
 			iter.Consume()
 
-		case Insert:
-			text := string(next.(InsertAction))
+		case InsertAction:
+			text := string(next)
 			td.contents = td.contents[:cursor] + text + td.contents[cursor:]
 			cursor += len(text)
 			iter.Consume()
 
-		case Delete:
-			expectedText := string(next.(DeleteAction))
+		case DeleteAction:
+			expectedText := string(next)
 			actualText := td.contents[cursor : cursor+len(expectedText)]
 			if expectedText != actualText {
 				panic(fmt.Errorf("failed delete assertion: %s != %s", expectedText, actualText))
@@ -62,7 +94,7 @@ func (td *TextDocument) Apply(op cooperate.Operation) error {
 			iter.Consume()
 
 		default:
-			fmt.Printf("unknown action type: %T", nextType)
+			fmt.Printf("unknown action type: %T", next)
 			return cooperate.ErrUnknownAction
 		}
 
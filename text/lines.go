@@ -0,0 +1,201 @@
+package text
+
+import (
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrInvalidPosition indicates that an offset or Position does not address a
+// location within a document.
+var ErrInvalidPosition = errors.New("invalid position")
+
+// A Unit is the unit in which columns are measured.
+type Unit int
+
+const (
+	// Bytes measures columns in bytes of UTF-8, like the offsets of actions.
+	Bytes Unit = iota
+
+	// Runes measures columns in Unicode code points.
+	Runes
+
+	// UTF16 measures columns in UTF-16 code units, as the Language Server
+	// Protocol does by default.
+	UTF16
+)
+
+// A Position is a location in a document given by a line and column, both
+// counted from zero. Lines are separated by "\n" or "\r\n", and the "\r" of a
+// "\r\n" is not counted among the columns of its line.
+type Position struct {
+	Line, Column int
+}
+
+// LineCount returns the number of lines in the document. An empty document,
+// and a document ending in "\n", has an empty last line.
+func (td *TextDocument) LineCount() int {
+	return strings.Count(td.contents, "\n") + 1
+}
+
+// Line returns line n of the document, without its terminating "\n" or
+// "\r\n".
+func (td *TextDocument) Line(n int) (string, error) {
+	start, ok := lineStart(td.contents, n)
+	if !ok {
+		return "", ErrInvalidPosition
+	}
+	return td.contents[start : start+lineLength(td.contents[start:])], nil
+}
+
+// Position returns the line and column, in unit, of the byte offset.
+func (td *TextDocument) Position(offset int, unit Unit) (Position, error) {
+
+	if offset < 0 || offset > len(td.contents) {
+		return Position{}, ErrInvalidPosition
+	}
+
+	// columns in runes or UTF-16 can't address the middle of a rune
+	if unit != Bytes && offset < len(td.contents) && !utf8.RuneStart(td.contents[offset]) {
+		return Position{}, ErrInvalidPosition
+	}
+
+	// nor can any column address the middle of a "\r\n"
+	if offset > 0 && offset < len(td.contents) && td.contents[offset-1:offset+1] == "\r\n" {
+		return Position{}, ErrInvalidPosition
+	}
+
+	before := td.contents[:offset]
+	start := strings.LastIndexByte(before, '\n') + 1
+
+	return Position{
+		Line:   strings.Count(before, "\n"),
+		Column: columns(before[start:], unit),
+	}, nil
+
+}
+
+// Offset returns the byte offset of pos, whose column is measured in unit.
+func (td *TextDocument) Offset(pos Position, unit Unit) (int, error) {
+
+	start, ok := lineStart(td.contents, pos.Line)
+	if !ok || pos.Column < 0 {
+		return 0, ErrInvalidPosition
+	}
+	line := td.contents[start : start+lineLength(td.contents[start:])]
+
+	if unit == Bytes {
+		if pos.Column > len(line) {
+			return 0, ErrInvalidPosition
+		}
+		return start + pos.Column, nil
+	}
+
+	var col, i int
+	for col < pos.Column {
+		if i == len(line) {
+			return 0, ErrInvalidPosition
+		}
+		r, size := utf8.DecodeRuneInString(line[i:])
+		col += width(r, unit)
+		i += size
+	}
+
+	// the column falls within a surrogate pair
+	if col != pos.Column {
+		return 0, ErrInvalidPosition
+	}
+
+	return start + i, nil
+
+}
+
+// Replace returns an operation that replaces the text between from and to,
+// whose columns are measured in unit, with s.
+func (td *TextDocument) Replace(from, to Position, unit Unit, s string) (cooperate.Operation, error) {
+
+	start, err := td.Offset(from, unit)
+	if err != nil {
+		return nil, err
+	}
+	end, err := td.Offset(to, unit)
+	if err != nil {
+		return nil, err
+	}
+	if end < start {
+		return nil, ErrInvalidPosition
+	}
+
+	var op cooperate.Operation
+	if start > 0 {
+		op = append(op, RetainAction(start))
+	}
+	if end > start {
+		op = append(op, DeleteAction(td.contents[start:end]))
+	}
+	if s != "" {
+		op = append(op, InsertAction(s))
+	}
+	if end < len(td.contents) {
+		op = append(op, RetainAction(len(td.contents)-end))
+	}
+	return op, nil
+
+}
+
+// lineStart returns the byte offset at which line n of s begins.
+func lineStart(s string, n int) (int, bool) {
+	if n < 0 {
+		return 0, false
+	}
+	var start int
+	for ; n > 0; n-- {
+		i := strings.IndexByte(s[start:], '\n')
+		if i < 0 {
+			return 0, false
+		}
+		start += i + 1
+	}
+	return start, true
+}
+
+// lineLength returns the length in bytes of the first line of s, excluding
+// the "\r" of a terminating "\r\n".
+func lineLength(s string) int {
+	i := strings.IndexByte(s, '\n')
+	switch {
+	case i > 0 && s[i-1] == '\r':
+		return i - 1
+	case i >= 0:
+		return i
+	}
+	return len(s)
+}
+
+// columns returns the length of s in unit.
+func columns(s string, unit Unit) int {
+	switch unit {
+	case Runes:
+		return utf8.RuneCountInString(s)
+	case UTF16:
+		var n int
+		for _, r := range s {
+			n += width(r, UTF16)
+		}
+		return n
+	}
+	return len(s)
+}
+
+// width returns the length of r in unit.
+func width(r rune, unit Unit) int {
+	switch {
+	case unit == Bytes:
+		return utf8.RuneLen(r)
+	case unit == UTF16 && r >= 0x10000:
+		return 2
+	}
+	return 1
+}
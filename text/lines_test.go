@@ -0,0 +1,167 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// sample has a two-byte rune, a three-byte rune and a rune outside the Basic
+// Multilingual Plane, which takes four bytes and two UTF-16 code units.
+const sample = "first\nsé€😀x\n"
+
+func TestTextDocument_Line(t *testing.T) {
+
+	td := NewTextDocument(sample)
+
+	if n := td.LineCount(); n != 3 {
+		t.Errorf("unexpected line count: expected 3 but got %d", n)
+	}
+
+	for i, expected := range []string{"first", "sé€😀x", ""} {
+		if line, err := td.Line(i); err != nil {
+			t.Errorf("[line %d] unexpected error: %s", i, err)
+		} else if line != expected {
+			t.Errorf("[line %d] expected %q but got %q", i, expected, line)
+		}
+	}
+
+	if _, err := td.Line(3); err != ErrInvalidPosition {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", ErrInvalidPosition, err)
+	}
+
+}
+
+func TestTextDocument_Position(t *testing.T) {
+
+	cases := []struct {
+		Offset   int
+		Unit     Unit
+		Expected Position
+		Error    error
+	}{
+		{Offset: 0, Unit: Runes, Expected: Position{0, 0}},
+		{Offset: 5, Unit: Runes, Expected: Position{0, 5}},
+		{Offset: 6, Unit: Runes, Expected: Position{1, 0}},
+		{Offset: 12, Unit: Bytes, Expected: Position{1, 6}},
+		{Offset: 12, Unit: Runes, Expected: Position{1, 3}},
+		{Offset: 12, Unit: UTF16, Expected: Position{1, 3}},
+		{Offset: 16, Unit: Runes, Expected: Position{1, 4}},
+		{Offset: 16, Unit: UTF16, Expected: Position{1, 5}},
+		{Offset: 18, Unit: UTF16, Expected: Position{2, 0}},
+		{Offset: 8, Unit: Runes, Error: ErrInvalidPosition},
+		{Offset: 19, Unit: Bytes, Error: ErrInvalidPosition},
+	}
+
+	td := NewTextDocument(sample)
+
+	for i, c := range cases {
+
+		pos, err := td.Position(c.Offset, c.Unit)
+		if err != c.Error {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.Error, err)
+		} else if err == nil && pos != c.Expected {
+			t.Errorf("[case %d] unexpected position: expected %v but got %v", i, c.Expected, pos)
+		}
+
+		// positions must round-trip
+		if err == nil {
+			if offset, err := td.Offset(pos, c.Unit); err != nil || offset != c.Offset {
+				t.Errorf("[case %d] unexpected offset: expected %d but got %d (%v)", i, c.Offset, offset, err)
+			}
+		}
+	}
+
+}
+
+func TestTextDocument_Offset(t *testing.T) {
+
+	cases := []struct {
+		Position Position
+		Unit     Unit
+		Expected int
+		Error    error
+	}{
+		{Position: Position{1, 2}, Unit: UTF16, Expected: 9},
+		{Position: Position{1, 3}, Unit: UTF16, Expected: 12},
+		{Position: Position{1, 4}, Unit: UTF16, Error: ErrInvalidPosition},
+		{Position: Position{1, 5}, Unit: UTF16, Expected: 16},
+		{Position: Position{1, 6}, Unit: UTF16, Expected: 17},
+		{Position: Position{1, 7}, Unit: UTF16, Error: ErrInvalidPosition},
+		{Position: Position{0, 6}, Unit: Bytes, Error: ErrInvalidPosition},
+		{Position: Position{3, 0}, Unit: Bytes, Error: ErrInvalidPosition},
+	}
+
+	td := NewTextDocument(sample)
+
+	for i, c := range cases {
+		if offset, err := td.Offset(c.Position, c.Unit); err != c.Error {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.Error, err)
+		} else if err == nil && offset != c.Expected {
+			t.Errorf("[case %d] unexpected offset: expected %d but got %d", i, c.Expected, offset)
+		}
+	}
+
+}
+
+func TestTextDocument_CRLF(t *testing.T) {
+
+	td := NewTextDocument("ab\r\ncd\r\n")
+
+	for i, expected := range []string{"ab", "cd", ""} {
+		if line, err := td.Line(i); err != nil {
+			t.Errorf("[line %d] unexpected error: %s", i, err)
+		} else if line != expected {
+			t.Errorf("[line %d] expected %q but got %q", i, expected, line)
+		}
+	}
+
+	cases := []struct {
+		Offset   int
+		Expected Position
+		Error    error
+	}{
+		{Offset: 2, Expected: Position{0, 2}},
+		{Offset: 3, Error: ErrInvalidPosition},
+		{Offset: 4, Expected: Position{1, 0}},
+		{Offset: 6, Expected: Position{1, 2}},
+		{Offset: 8, Expected: Position{2, 0}},
+	}
+
+	for i, c := range cases {
+		if pos, err := td.Position(c.Offset, Runes); err != c.Error {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.Error, err)
+		} else if err == nil && pos != c.Expected {
+			t.Errorf("[case %d] unexpected position: expected %v but got %v", i, c.Expected, pos)
+		}
+	}
+
+	// the "\r" is not a column of its line
+	if _, err := td.Offset(Position{0, 3}, Bytes); err != ErrInvalidPosition {
+		t.Errorf("unexpected error: expected '%v' but got '%v'", ErrInvalidPosition, err)
+	}
+
+}
+
+func TestTextDocument_Replace(t *testing.T) {
+
+	td := NewTextDocument(sample)
+
+	op, err := td.Replace(Position{0, 3}, Position{1, 3}, UTF16, "!")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := cooperate.Operation{RetainAction(3), DeleteAction("st\nsé€"), InsertAction("!"), RetainAction(6)}
+	if !reflect.DeepEqual(op, expected) {
+		t.Errorf("unexpected operation: expected '%#v' but got '%#v'", expected, op)
+	}
+
+	if err := td.Apply(op); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	} else if expected := "fir!😀x\n"; td.String() != expected {
+		t.Errorf("unexpected contents: expected %q but got %q", expected, td.String())
+	}
+
+}
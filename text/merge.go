@@ -0,0 +1,190 @@
+package text
+
+import (
+	"github.com/tylerchr/cooperate"
+)
+
+// A Span is a range of bytes [Start, End) of a document.
+type Span struct {
+	Start, End int
+}
+
+// A Conflict is a region of the base document that both sides of a merge
+// changed, and that may need to be reviewed by a person.
+type Conflict struct {
+	// Base is the region in the base document.
+	Base Span
+
+	// Merged is the region in the merged document, including any text that
+	// either side inserted at its edges.
+	Merged Span
+}
+
+// Merge merges a branch of offline changes into h. The offline operations
+// were made one after another against the document at revision base, that
+// is, after the first base operations of h.
+//
+// The returned operation applies to the document at h.SequenceNumber(), and
+// can be submitted with that as its root. Conflicts lists the regions where
+// the offline changes overlap changes committed to h since base. As
+// everywhere in this package, the committed changes are favored.
+func Merge(h cooperate.History, base int, offline []cooperate.Operation) (op cooperate.Operation, conflicts []Conflict, err error) {
+
+	var th TextHandler
+
+	local, err := composeAll(th, offline)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var committed []cooperate.Operation
+	if err := h.Iterate(base, func(seqno int, op cooperate.Operation) error {
+		committed = append(committed, op)
+		return nil
+	}); err != nil {
+		return nil, nil, err
+	}
+
+	remote, err := composeAll(th, committed)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if local == nil || remote == nil {
+		return local, nil, nil
+	}
+
+	localPrime, _, err := th.Transform(
+		cooperate.NewOperationIterator(cooperate.Expand(th, local)),
+		cooperate.NewOperationIterator(cooperate.Expand(th, remote)),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	merged, err := th.Compose(
+		cooperate.NewOperationIterator(cooperate.Expand(th, remote)),
+		cooperate.NewOperationIterator(cooperate.Expand(th, localPrime)),
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, l := range regions(local) {
+		for _, r := range regions(remote) {
+			if !overlaps(l, r) {
+				continue
+			}
+			s := Span{Start: min(l.Start, r.Start), End: max(l.End, r.End)}
+			conflicts = appendConflict(conflicts, Conflict{
+				Base:   s,
+				Merged: Span{Start: mapIndex(th, merged, s.Start, false), End: mapIndex(th, merged, s.End, true)},
+			})
+		}
+	}
+
+	return localPrime, conflicts, nil
+
+}
+
+// composeAll composes a sequence of operations into one, or returns nil if
+// there are none.
+func composeAll(th TextHandler, ops []cooperate.Operation) (cooperate.Operation, error) {
+	var composed cooperate.Operation
+	for _, op := range ops {
+		if composed == nil {
+			composed = op
+			continue
+		}
+		var err error
+		composed, err = th.Compose(
+			cooperate.NewOperationIterator(cooperate.Expand(th, composed)),
+			cooperate.NewOperationIterator(cooperate.Expand(th, op)),
+		)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return cooperate.Reduce(th, composed), nil
+}
+
+// regions returns the spans of the document op applies to that op changes.
+// Adjacent insertions and deletions form a single region, and an insertion
+// alone is an empty span at its location.
+func regions(op cooperate.Operation) []Span {
+	var spans []Span
+	var pos int
+	open := false
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			pos += int(a)
+			open = false
+		case InsertAction, DeleteAction:
+			if !open {
+				spans = append(spans, Span{Start: pos, End: pos})
+				open = true
+			}
+			if d, ok := a.(DeleteAction); ok {
+				pos += len(d)
+				spans[len(spans)-1].End = pos
+			}
+		}
+	}
+	return spans
+}
+
+// overlaps reports whether changes to a and b touch the same text. An empty
+// span, which is an insertion, overlaps a span that strictly contains it or
+// another insertion at the same location.
+func overlaps(a, b Span) bool {
+	switch {
+	case a.Start == a.End && b.Start == b.End:
+		return a.Start == b.Start
+	case a.Start == a.End:
+		return b.Start < a.Start && a.Start < b.End
+	case b.Start == b.End:
+		return a.Start < b.Start && b.Start < a.End
+	}
+	return a.Start < b.End && b.Start < a.End
+}
+
+// appendConflict adds c to conflicts, merging it with the last conflict if
+// their base regions overlap.
+func appendConflict(conflicts []Conflict, c Conflict) []Conflict {
+	if n := len(conflicts); n > 0 && conflicts[n-1].Base.End >= c.Base.Start {
+		last := &conflicts[n-1]
+		last.Base.End = max(last.Base.End, c.Base.End)
+		last.Merged.End = max(last.Merged.End, c.Merged.End)
+		return conflicts
+	}
+	return append(conflicts, c)
+}
+
+// mapIndex returns the offset in the result of op of the offset i in the
+// document op applies to. If after is set, text inserted at i is placed
+// before the returned offset rather than after it.
+func mapIndex(th TextHandler, op cooperate.Operation, i int, after bool) int {
+	var src, dst int
+	for _, a := range cooperate.Expand(th, op) {
+		if src >= i && !after {
+			return dst
+		}
+		switch a := a.(type) {
+		case RetainAction:
+			if src >= i {
+				return dst
+			}
+			src += int(a)
+			dst += int(a)
+		case InsertAction:
+			dst += len(a)
+		case DeleteAction:
+			if src >= i {
+				return dst
+			}
+			src += len(a)
+		}
+	}
+	return dst
+}
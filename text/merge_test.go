@@ -0,0 +1,86 @@
+package text
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestMerge(t *testing.T) {
+
+	const base = "the quick fox jumps"
+
+	// everything committed since the base revision
+	committed := []cooperate.Operation{
+		{RetainAction(4), DeleteAction("quick"), InsertAction("slow"), RetainAction(10)},
+		{RetainAction(9), InsertAction("brown "), RetainAction(9)},
+	}
+
+	// changes made offline against the base revision
+	offline := []cooperate.Operation{
+		{RetainAction(4), DeleteAction("quick"), InsertAction("fast"), RetainAction(10)},
+		{RetainAction(18), InsertAction("!")},
+		{InsertAction("see "), RetainAction(19)},
+	}
+
+	// one operation before the base revision that both sides share
+	h := &cooperate.MemoryHistory{
+		{InsertAction(base)},
+	}
+
+	doc := NewTextDocument("")
+	for _, op := range append([]cooperate.Operation{(*h)[0]}, committed...) {
+		if err := doc.Apply(op); err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+	}
+	for _, op := range committed {
+		h.Store(op)
+	}
+
+	op, conflicts, err := Merge(h, 1, offline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if err := doc.Apply(op); err != nil {
+		t.Fatalf("unexpected error applying merge: %s", err)
+	} else if expected := "see the slowfast brown fox jumps!"; doc.String() != expected {
+		t.Errorf("unexpected document: expected %q but got %q", expected, doc.String())
+	}
+
+	expected := []Conflict{
+		{Base: Span{Start: 4, End: 9}, Merged: Span{Start: 8, End: 16}},
+	}
+	if !reflect.DeepEqual(conflicts, expected) {
+		t.Errorf("unexpected conflicts: expected %v but got %v", expected, conflicts)
+	}
+	if c := conflicts[0].Merged; doc.String()[c.Start:c.End] != "slowfast" {
+		t.Errorf("unexpected conflict region: got %q", doc.String()[c.Start:c.End])
+	}
+
+}
+
+func TestMerge_NothingCommitted(t *testing.T) {
+
+	h := &cooperate.MemoryHistory{{InsertAction("abc")}}
+
+	offline := []cooperate.Operation{
+		{RetainAction(3), InsertAction("d")},
+		{DeleteAction("a"), RetainAction(3)},
+	}
+
+	op, conflicts, err := Merge(h, 1, offline)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if expected := (cooperate.Operation{DeleteAction("a"), RetainAction(2), InsertAction("d")}); !reflect.DeepEqual(op, expected) {
+		t.Errorf("unexpected operation: expected '%#v' but got '%#v'", expected, op)
+	}
+	if len(conflicts) != 0 {
+		t.Errorf("unexpected conflicts: %v", conflicts)
+	}
+
+}
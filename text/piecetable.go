@@ -0,0 +1,190 @@
+package text
+
+import (
+	"io"
+	"strings"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// smallPiece is the size below which adjacent pieces are merged, so that
+// typing one character at a time doesn't fragment the table.
+const smallPiece = 64
+
+// A PieceTableDocument is a TextDocument for large texts. It accepts the same
+// operations, but it holds its contents as a list of pieces that share
+// memory with the initial text and with inserted text, so applying an
+// operation costs time proportional to the number of pieces and actions
+// rather than to the length of the text.
+//
+// Unlike TextDocument, a failed delete assertion is reported as
+// cooperate.ErrDeleteMismatch, and a failed Apply leaves the document
+// unchanged.
+type PieceTableDocument struct {
+	pieces []string
+	length int
+}
+
+// NewPieceTableDocument initializes a PieceTableDocument with a starting
+// value of initial. The initial text is not copied.
+func NewPieceTableDocument(initial string) *PieceTableDocument {
+	return &PieceTableDocument{
+		pieces: appendPiece(nil, initial),
+		length: len(initial),
+	}
+}
+
+// Len returns the length of the document in bytes.
+func (pd *PieceTableDocument) Len() int {
+	return pd.length
+}
+
+// String returns the current contents of the document. The pieces are joined
+// at most once between calls to Apply.
+func (pd *PieceTableDocument) String() string {
+	switch len(pd.pieces) {
+	case 0:
+		return ""
+	case 1:
+		return pd.pieces[0]
+	}
+
+	var sb strings.Builder
+	sb.Grow(pd.length)
+	for _, p := range pd.pieces {
+		sb.WriteString(p)
+	}
+
+	s := sb.String()
+	pd.pieces = []string{s}
+	return s
+}
+
+// Reader returns an io.Reader over the current contents of the document that
+// reads the pieces in place. Later changes to the document do not affect it.
+func (pd *PieceTableDocument) Reader() io.Reader {
+	return &pieceReader{pieces: pd.pieces}
+}
+
+// Apply performs op against the PieceTableDocument.
+//
+// Apply builds a new list of pieces, copying every piece it retains, so an
+// operation that changes a single character, such as a keystroke, still costs
+// time proportional to the number of pieces. Merging small pieces keeps that
+// number well below the number of operations applied, and String reduces it
+// to one.
+func (pd *PieceTableDocument) Apply(op cooperate.Operation) error {
+
+	// verify that operation will apply cleanly to document
+	pre, post := Lengths(op)
+	if pd.length != pre {
+		return cooperate.ErrDocumentSizeMismatch
+	}
+
+	pieces := make([]string, 0, len(pd.pieces)+len(op))
+	cur := &pieceCursor{pieces: pd.pieces}
+
+	for _, a := range op {
+		switch a := a.(type) {
+		case RetainAction:
+			if a < 0 {
+				return cooperate.ErrDocumentSizeMismatch
+			}
+			for _, p := range cur.take(int(a)) {
+				pieces = appendPiece(pieces, p)
+			}
+
+		case InsertAction:
+			pieces = appendPiece(pieces, string(a))
+
+		case DeleteAction:
+			expected := string(a)
+			for _, p := range cur.take(len(expected)) {
+				if !strings.HasPrefix(expected, p) {
+					return cooperate.ErrDeleteMismatch
+				}
+				expected = expected[len(p):]
+			}
+
+		default:
+			return cooperate.ErrUnknownAction
+		}
+	}
+
+	pd.pieces, pd.length = pieces, post
+	return nil
+
+}
+
+// appendPiece adds p to the end of pieces, merging it with the last piece if
+// both are small.
+func appendPiece(pieces []string, p string) []string {
+	if p == "" {
+		return pieces
+	}
+	if n := len(pieces); n > 0 && len(pieces[n-1])+len(p) <= smallPiece {
+		pieces[n-1] += p
+		return pieces
+	}
+	return append(pieces, p)
+}
+
+// pieceCursor reads successive spans of bytes from a list of pieces.
+type pieceCursor struct {
+	pieces []string
+	offset int // byte offset into pieces[0]
+}
+
+// take consumes the next n bytes, returning them as pieces that share memory
+// with the originals.
+func (pc *pieceCursor) take(n int) []string {
+	var taken []string
+	for n > 0 && len(pc.pieces) > 0 {
+		p := pc.pieces[0][pc.offset:]
+		if len(p) > n {
+			p = p[:n]
+			pc.offset += n
+		} else {
+			pc.pieces, pc.offset = pc.pieces[1:], 0
+		}
+		n -= len(p)
+		taken = append(taken, p)
+	}
+	return taken
+}
+
+// pieceReader implements io.Reader and io.WriterTo over a list of pieces.
+type pieceReader struct {
+	pieces []string
+	offset int // byte offset into pieces[0]
+}
+
+func (pr *pieceReader) Read(p []byte) (int, error) {
+	var n int
+	for n < len(p) && len(pr.pieces) > 0 {
+		c := copy(p[n:], pr.pieces[0][pr.offset:])
+		n += c
+		pr.offset += c
+		if pr.offset == len(pr.pieces[0]) {
+			pr.pieces, pr.offset = pr.pieces[1:], 0
+		}
+	}
+	if n == 0 && len(p) > 0 {
+		return 0, io.EOF
+	}
+	return n, nil
+}
+
+func (pr *pieceReader) WriteTo(w io.Writer) (int64, error) {
+	var total int64
+	for len(pr.pieces) > 0 {
+		n, err := io.WriteString(w, pr.pieces[0][pr.offset:])
+		total += int64(n)
+		pr.offset += n
+		if err != nil {
+			return total, err
+		}
+		pr.pieces, pr.offset = pr.pieces[1:], 0
+	}
+	return total, nil
+}
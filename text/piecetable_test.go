@@ -0,0 +1,170 @@
+package text
+
+import (
+	"io"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// randomOperation returns an operation against doc made of random retains,
+// inserts and deletes.
+func randomOperation(r *rand.Rand, doc string, actions int) cooperate.Operation {
+	var op cooperate.Operation
+	var cursor int
+	for i := 0; i < actions && cursor < len(doc); i++ {
+		n := 1 + r.Intn((len(doc)-cursor+actions-1)/actions+1)
+		if cursor+n > len(doc) {
+			n = len(doc) - cursor
+		}
+		switch r.Intn(3) {
+		case 0:
+			op = append(op, RetainAction(n))
+		case 1:
+			op = append(op, DeleteAction(doc[cursor:cursor+n]))
+		case 2:
+			op = append(op, InsertAction(randomText(r)), RetainAction(n))
+		}
+		cursor += n
+	}
+	if cursor < len(doc) {
+		op = append(op, RetainAction(len(doc)-cursor))
+	}
+	if len(op) == 0 || r.Intn(4) == 0 {
+		op = append(op, InsertAction(randomText(r)))
+	}
+	return op
+}
+
+// randomText returns up to eight copies of a random letter.
+func randomText(r *rand.Rand) string {
+	return strings.Repeat(string(rune('a'+r.Intn(26))), 1+r.Intn(8))
+}
+
+func TestPieceTableDocument(t *testing.T) {
+
+	r := rand.New(rand.NewSource(1))
+
+	td := NewTextDocument("the quick brown fox jumps over the lazy dog")
+	pd := NewPieceTableDocument(td.String())
+
+	for i := 0; i < 200; i++ {
+		op := randomOperation(r, td.String(), 1+r.Intn(10))
+		if len(td.String()) < 16 {
+			op = append(op, InsertAction(strings.Repeat("y", 32)))
+		}
+
+		if err := td.Apply(op); err != nil {
+			t.Fatalf("[op %d] unexpected error from TextDocument: %s", i, err)
+		}
+		if err := pd.Apply(op); err != nil {
+			t.Fatalf("[op %d] unexpected error from PieceTableDocument: %s", i, err)
+		}
+
+		// read through the Reader before String compacts the pieces
+		b, err := io.ReadAll(pd.Reader())
+		if err != nil {
+			t.Fatalf("[op %d] unexpected error reading: %s", i, err)
+		}
+		if string(b) != td.String() || pd.Len() != len(td.String()) {
+			t.Fatalf("[op %d] documents diverged: expected %q but read %q", i, td.String(), b)
+		}
+		if i%10 == 0 && pd.String() != td.String() {
+			t.Fatalf("[op %d] documents diverged: expected %q but got %q", i, td.String(), pd.String())
+		}
+	}
+
+}
+
+func TestPieceTableDocument_Errors(t *testing.T) {
+
+	cases := []struct {
+		Operation     cooperate.Operation
+		ExpectedError error
+	}{
+		{
+			Operation:     cooperate.Operation{RetainAction(4), InsertAction("!"), DeleteAction("bar")},
+			ExpectedError: cooperate.ErrDeleteMismatch,
+		},
+		{
+			Operation:     cooperate.Operation{RetainAction(4)},
+			ExpectedError: cooperate.ErrDocumentSizeMismatch,
+		},
+		{
+			Operation:     cooperate.Operation{RetainAction(7), "bogus"},
+			ExpectedError: cooperate.ErrUnknownAction,
+		},
+	}
+
+	for i, c := range cases {
+
+		pd := NewPieceTableDocument("foo baz")
+
+		if err := pd.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if pd.String() != "foo baz" {
+			t.Errorf("[case %d] document changed by failed operation: got %q", i, pd.String())
+		}
+	}
+
+}
+
+// benchmarkApply applies operations making many small edits to a 4MB
+// document.
+func benchmarkApply(b *testing.B, newDocument func(string) cooperate.Document) {
+
+	initial := strings.Repeat("lorem ipsum dolor sit amet\n", 4<<20/27)
+
+	r := rand.New(rand.NewSource(1))
+	var ops []cooperate.Operation
+	doc := NewPieceTableDocument(initial)
+	for i := 0; i < 10; i++ {
+		op := randomOperation(r, doc.String(), 100)
+		doc.Apply(op)
+		ops = append(ops, op)
+	}
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		doc := newDocument(initial)
+		for _, op := range ops {
+			if err := doc.Apply(op); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+}
+
+func BenchmarkApply_TextDocument(b *testing.B) {
+	benchmarkApply(b, func(s string) cooperate.Document { return NewTextDocument(s) })
+}
+
+func BenchmarkApply_PieceTableDocument(b *testing.B) {
+	benchmarkApply(b, func(s string) cooperate.Document { return NewPieceTableDocument(s) })
+}
+
+func BenchmarkString_PieceTableDocument(b *testing.B) {
+
+	initial := strings.Repeat("lorem ipsum dolor sit amet\n", 4<<20/27)
+	op := randomOperation(rand.New(rand.NewSource(1)), initial, 100)
+
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+
+		// String caches the joined pieces, so fragment them again first
+		b.StopTimer()
+		doc := NewPieceTableDocument(initial)
+		if err := doc.Apply(op); err != nil {
+			b.Fatal(err)
+		}
+		b.StartTimer()
+
+		_ = doc.String()
+	}
+
+}
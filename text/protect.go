@@ -0,0 +1,92 @@
+package text
+
+import (
+	"errors"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrProtected indicates that an operation changes a protected range of a
+// document.
+var ErrProtected = errors.New("protected range")
+
+// A ProtectedDocument is a text document with protected ranges, such as the
+// locked sections of a template. The ranges are transformed through every
+// operation applied to the document, so they continue to cover the same text
+// as it moves.
+//
+// Applying an operation does not check it against the protected ranges; use
+// Validate, or set the ProtectedDocument as the Authorizer of the
+// cooperate.Server that owns it.
+type ProtectedDocument struct {
+	cooperate.Document
+	spans []Span
+}
+
+// NewProtectedDocument wraps doc, protecting spans of it.
+func NewProtectedDocument(doc cooperate.Document, spans ...Span) *ProtectedDocument {
+	pd := &ProtectedDocument{Document: doc}
+	for _, s := range spans {
+		pd.Protect(s)
+	}
+	return pd
+}
+
+// Protected returns the protected spans of the current document, in order.
+func (pd *ProtectedDocument) Protected() []Span {
+	return append([]Span(nil), pd.spans...)
+}
+
+// Protect adds s to the protected spans. Empty spans protect nothing and are
+// ignored.
+func (pd *ProtectedDocument) Protect(s Span) {
+	if s.Start >= s.End {
+		return
+	}
+	i := 0
+	for i < len(pd.spans) && pd.spans[i].Start < s.Start {
+		i++
+	}
+	pd.spans = append(pd.spans, Span{})
+	copy(pd.spans[i+1:], pd.spans[i:])
+	pd.spans[i] = s
+}
+
+// Validate returns ErrProtected if op, which applies to the current
+// document, inserts text within a protected span or deletes text from one.
+// Text may be inserted at either end of a protected span.
+func (pd *ProtectedDocument) Validate(op cooperate.Operation) error {
+	if changes(op, pd.spans) {
+		return ErrProtected
+	}
+	return nil
+}
+
+// Authorize implements cooperate.Authorizer by validating op, regardless of
+// session and document.
+func (pd *ProtectedDocument) Authorize(session, document string, op cooperate.Operation) error {
+	return pd.Validate(op)
+}
+
+// Apply performs op against the document, and transforms the protected spans
+// through it. Text inserted at either end of a span is left outside it, and
+// spans whose text is deleted entirely are dropped.
+func (pd *ProtectedDocument) Apply(op cooperate.Operation) error {
+
+	if err := pd.Document.Apply(op); err != nil {
+		return err
+	}
+
+	var th TextHandler
+	spans := pd.spans[:0]
+	for _, s := range pd.spans {
+		s = Span{Start: mapIndex(th, op, s.Start, true), End: mapIndex(th, op, s.End, false)}
+		if s.Start < s.End {
+			spans = append(spans, s)
+		}
+	}
+	pd.spans = spans
+
+	return nil
+
+}
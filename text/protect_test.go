@@ -0,0 +1,113 @@
+package text
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestProtectedDocument(t *testing.T) {
+
+	// "[Title] body [Footer]" with both bracketed sections locked
+	pd := NewProtectedDocument(NewTextDocument("[Title] body [Footer]"), Span{Start: 13, End: 21}, Span{Start: 0, End: 7})
+
+	if expected := []Span{{0, 7}, {13, 21}}; !reflect.DeepEqual(pd.Protected(), expected) {
+		t.Fatalf("unexpected spans: expected %v but got %v", expected, pd.Protected())
+	}
+
+	cases := []struct {
+		Operation cooperate.Operation
+		Error     error
+		Spans     []Span
+	}{
+		{
+			// inserted at the ends of the spans, the text is outside them
+			Operation: cooperate.Operation{InsertAction("> "), RetainAction(7), InsertAction("!"), RetainAction(14)},
+			Spans:     []Span{{2, 9}, {16, 24}},
+		},
+		{
+			Operation: cooperate.Operation{RetainAction(11), DeleteAction("body"), InsertAction("text"), RetainAction(9)},
+			Spans:     []Span{{2, 9}, {16, 24}},
+		},
+		{
+			Operation: cooperate.Operation{RetainAction(9), DeleteAction("!"), RetainAction(14)},
+			Spans:     []Span{{2, 9}, {15, 23}},
+		},
+		{
+			Operation: cooperate.Operation{RetainAction(3), InsertAction("My "), RetainAction(20)},
+			Error:     ErrProtected,
+			Spans:     []Span{{2, 9}, {15, 23}},
+		},
+		{
+			Operation: cooperate.Operation{RetainAction(14), DeleteAction(" [Footer]")},
+			Error:     ErrProtected,
+			Spans:     []Span{{2, 9}, {15, 23}},
+		},
+	}
+
+	for i, c := range cases {
+		if err := pd.Validate(c.Operation); err != c.Error {
+			t.Errorf("[case %d] unexpected error state: expected '%v' but got '%v'", i, c.Error, err)
+		} else if err == nil {
+			if err := pd.Apply(c.Operation); err != nil {
+				t.Fatalf("[case %d] unexpected error: %s", i, err)
+			}
+		}
+		if !reflect.DeepEqual(pd.Protected(), c.Spans) {
+			t.Errorf("[case %d] unexpected spans: expected %v but got %v", i, c.Spans, pd.Protected())
+		}
+	}
+
+	if doc := pd.Document.(*TextDocument).String(); doc != "> [Title] text [Footer]" {
+		t.Errorf("unexpected document: %q", doc)
+	}
+
+	// deleting a protected span entirely, as an unvalidated operation may,
+	// removes its protection
+	if err := pd.Apply(cooperate.Operation{DeleteAction("> [Title]"), RetainAction(14)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if expected := []Span{{6, 14}}; !reflect.DeepEqual(pd.Protected(), expected) {
+		t.Errorf("unexpected spans: expected %v but got %v", expected, pd.Protected())
+	}
+
+}
+
+func TestProtectedDocument_Server(t *testing.T) {
+
+	pd := NewProtectedDocument(NewTextDocument("Hi NAME."), Span{Start: 0, End: 3})
+
+	s := &cooperate.Server{
+		Document:           pd,
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      TextHandler{},
+		ComposeTransformer: TextHandler{},
+		Authorizer:         pd,
+	}
+
+	if err := s.Apply(0, cooperate.Operation{InsertAction("Re: "), RetainAction(8)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// rooted before the first operation, this is checked against the
+	// protected span where it has since moved
+	err := s.Apply(0, cooperate.Operation{RetainAction(1), DeleteAction("i"), RetainAction(6)})
+	if !errors.Is(err, ErrProtected) {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", ErrProtected, err)
+	}
+
+	if err := s.Apply(1, cooperate.Operation{RetainAction(7), DeleteAction("NAME"), InsertAction("Ada"), RetainAction(1)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if doc := pd.Document.(*TextDocument).String(); doc != "Re: Hi Ada." {
+		t.Errorf("unexpected document: %q", doc)
+	}
+
+	if expected := []Span{{4, 7}}; !reflect.DeepEqual(pd.Protected(), expected) {
+		t.Errorf("unexpected spans: expected %v but got %v", expected, pd.Protected())
+	}
+
+}
@@ -0,0 +1,40 @@
+package text
+
+import (
+	"errors"
+
+	"github.com/tylerchr/cooperate"
+)
+
+// ErrReadOnly indicates that an operation changes a read-only range of a
+// document.
+var ErrReadOnly = errors.New("read-only range")
+
+// A RangeAuthorizer is a cooperate.Authorizer that prevents sessions from
+// changing read-only ranges of text. Text may be inserted at either end of a
+// read-only range, but not within it.
+type RangeAuthorizer struct {
+	// ReadOnly returns the spans of document that session may not change,
+	// in the document's current state.
+	ReadOnly func(session, document string) []Span
+}
+
+func (ra RangeAuthorizer) Authorize(session, document string, op cooperate.Operation) error {
+	if changes(op, ra.ReadOnly(session, document)) {
+		return ErrReadOnly
+	}
+	return nil
+}
+
+// changes reports whether op inserts text within any of spans, or deletes
+// text from one.
+func changes(op cooperate.Operation, spans []Span) bool {
+	for _, r := range regions(op) {
+		for _, s := range spans {
+			if s.Start < s.End && overlaps(r, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package text
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+)
+
+func TestRangeAuthorizer(t *testing.T) {
+
+	// "Dear NAME, thanks!" where "Dear " and ", thanks!" are read-only
+	ra := RangeAuthorizer{
+		ReadOnly: func(session, document string) []Span {
+			if session == "owner" {
+				return nil
+			}
+			return []Span{{Start: 0, End: 5}, {Start: 9, End: 18}}
+		},
+	}
+
+	cases := []struct {
+		Session   string
+		Operation cooperate.Operation
+		Error     error
+	}{
+		{
+			Session:   "guest",
+			Operation: cooperate.Operation{RetainAction(5), DeleteAction("NAME"), InsertAction("Ada"), RetainAction(9)},
+		},
+		{
+			// inserting at the edges of a read-only range is allowed
+			Session:   "guest",
+			Operation: cooperate.Operation{InsertAction(">"), RetainAction(18), InsertAction("<")},
+		},
+		{
+			Session:   "guest",
+			Operation: cooperate.Operation{RetainAction(2), InsertAction("a"), RetainAction(16)},
+			Error:     ErrReadOnly,
+		},
+		{
+			Session:   "guest",
+			Operation: cooperate.Operation{RetainAction(4), DeleteAction(" NAME"), RetainAction(9)},
+			Error:     ErrReadOnly,
+		},
+		{
+			Session:   "owner",
+			Operation: cooperate.Operation{DeleteAction("Dear"), InsertAction("Hi"), RetainAction(14)},
+		},
+	}
+
+	for i, c := range cases {
+		if err := ra.Authorize(c.Session, "letter", c.Operation); err != c.Error {
+			t.Errorf("[case %d] unexpected error state: expected '%v' but got '%v'", i, c.Error, err)
+		}
+	}
+
+}
+
+func TestRangeAuthorizer_Server(t *testing.T) {
+
+	s := &cooperate.Server{
+		Document:           NewTextDocument("Dear NAME, thanks!"),
+		History:            &cooperate.MemoryHistory{},
+		ExpandReducer:      TextHandler{},
+		ComposeTransformer: TextHandler{},
+		Authorizer: RangeAuthorizer{
+			ReadOnly: func(session, document string) []Span {
+				return []Span{{Start: 3, End: 8}}
+			},
+		},
+	}
+
+	if err := s.ApplyAs("ada", 0, cooperate.Operation{InsertAction(">> "), RetainAction(18)}); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	// the read-only range is now "Dear " at 3, and this edit, rooted before
+	// the first, would fall within it if it were not transformed
+	if err := s.ApplyAs("bob", 0, cooperate.Operation{RetainAction(5), InsertAction("my "), RetainAction(13)}); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+
+	err := s.ApplyAs("bob", 2, cooperate.Operation{RetainAction(3), DeleteAction("D"), InsertAction("d"), RetainAction(20)})
+	if !errors.Is(err, ErrReadOnly) {
+		t.Errorf("unexpected error: expected '%s' but got '%v'", ErrReadOnly, err)
+	}
+
+	if doc := s.Document.(*TextDocument); doc.String() != ">> Dear my NAME, thanks!" {
+		t.Errorf("unexpected document: %s", doc.String())
+	}
+
+}
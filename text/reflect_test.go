@@ -36,3 +36,25 @@ func BenchmarkType_Switch(b *testing.B) {
 		}
 	}
 }
+
+func BenchmarkTransform_TextHandler(b *testing.B) {
+	var th TextHandler
+	x := cooperate.Expand(th, cooperate.Operation{RetainAction(64), InsertAction("lorem ipsum"), RetainAction(64)})
+	y := cooperate.Expand(th, cooperate.Operation{RetainAction(32), DeleteAction("dolor sit amet"), RetainAction(82)})
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		th.Transform(cooperate.NewOperationIterator(x), cooperate.NewOperationIterator(y))
+	}
+}
+
+func BenchmarkTransform_TypedHandler(b *testing.B) {
+	var th TypedHandler
+	x := cooperate.Expand[Action](th, cooperate.OperationOf[Action]{RetainAction(64), InsertAction("lorem ipsum"), RetainAction(64)})
+	y := cooperate.Expand[Action](th, cooperate.OperationOf[Action]{RetainAction(32), DeleteAction("dolor sit amet"), RetainAction(82)})
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		th.Transform(cooperate.NewOperationIterator(x), cooperate.NewOperationIterator(y))
+	}
+}
@@ -5,9 +5,13 @@ import (
 	"reflect"
 
 	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/runlength"
 )
 
 var (
+	// Retain, Insert and Delete are the reflect.Types of the text actions.
+	//
+	// Deprecated: a type switch on the action is considerably faster.
 	Retain = reflect.TypeOf(RetainAction(0))
 	Insert = reflect.TypeOf(InsertAction(""))
 	Delete = reflect.TypeOf(DeleteAction(""))
@@ -16,7 +20,26 @@ var (
 type (
 	// A TextHandler implements cooperate.ComposeTransformer and cooperate.ExpandReducer
 	// for the standard text-based operations: retain, insert, and delete.
-	TextHandler struct{}
+	TextHandler struct {
+		// Priority decides the order of concurrent insertions at the same
+		// location. The zero value favors b.
+		Priority Priority
+	}
+
+	// A TypedHandler is the counterpart of TextHandler for operations made of
+	// Actions, so that only text actions can be used to construct them.
+	TypedHandler struct {
+		Priority Priority
+	}
+
+	// A Priority decides which of two concurrent insertions at the same
+	// location Transform places first.
+	Priority = cooperate.Priority
+
+	// An Action is a RetainAction, InsertAction or DeleteAction.
+	Action interface {
+		textAction()
+	}
 
 	// RetainAction moves the cursor forward a specified number of elements
 	RetainAction int
@@ -29,27 +52,36 @@ type (
 	DeleteAction string
 )
 
+const (
+	// FavorB places b's insertion first.
+	FavorB = cooperate.FavorB
+
+	// FavorA places a's insertion first. A Client whose Server uses FavorB
+	// should use FavorA so that they order concurrent insertions alike.
+	FavorA = cooperate.FavorA
+)
+
+// PriorityByID returns the Priority that places the insertion of the peer
+// with the lesser ID first. Peers that each transform their own operation, as
+// a, against another's, as b, using PriorityByID(ownID, otherID), order
+// concurrent insertions identically without a server to arbitrate.
+func PriorityByID(aID, bID string) Priority {
+	if aID < bID {
+		return FavorA
+	}
+	return FavorB
+}
+
 func (a RetainAction) GoString() string { return fmt.Sprintf("R(%d)", a) }
 func (a InsertAction) GoString() string { return fmt.Sprintf("I(%s)", a) }
 func (a DeleteAction) GoString() string { return fmt.Sprintf("D(%s)", a) }
 
+func (RetainAction) textAction() {}
+func (InsertAction) textAction() {}
+func (DeleteAction) textAction() {}
+
 func (th TextHandler) Expand(a cooperate.Action) []cooperate.Action {
-	var actions []cooperate.Action
-	switch a := a.(type) {
-	case RetainAction:
-		for i := 0; i < int(a); i++ {
-			actions = append(actions, RetainAction(1))
-		}
-	case InsertAction:
-		for _, x := range string(a) {
-			actions = append(actions, InsertAction(x))
-		}
-	case DeleteAction:
-		for _, x := range string(a) {
-			actions = append(actions, DeleteAction(x))
-		}
-	}
-	return actions
+	return handler[cooperate.Action]{}.Expand(a)
 }
 
 // Reduce combines two actions of identical type into a single action with the same
@@ -57,102 +89,13 @@ func (th TextHandler) Expand(a cooperate.Action) []cooperate.Action {
 //
 // Reduce will always fail if a and b are not of identical types.
 func (th TextHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
-
-	// if the actions aren't the same type, then they can't be reduced
-	if reflect.TypeOf(a) != reflect.TypeOf(b) {
-		// fmt.Printf("not same type: %s != %s\n", reflect.TypeOf(a), reflect.TypeOf(b))
-		return nil, false
-	}
-
-	// TODO(tylerchr): Check the type of b, because this solution will panic on bad input.
-
-	switch a.(type) {
-	case InsertAction:
-		return InsertAction(string(a.(InsertAction)) + string(b.(InsertAction))), true
-	case DeleteAction:
-		return DeleteAction(string(a.(DeleteAction)) + string(b.(DeleteAction))), true
-	case RetainAction:
-		return RetainAction(int(a.(RetainAction)) + int(b.(RetainAction))), true
-	}
-
-	return nil, false // we can't merge actions we can't identify
+	return handler[cooperate.Action]{}.Reduce(a, b)
 }
 
 // Compose merges a and b into a single operation c such that the effect of
 // applying c is equal to that of applying a then b.
 func (th TextHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
-
-	var composedActions []cooperate.Action // new list of actions
-
-ComposeLoop:
-	for {
-
-		switch {
-
-		// if we are out of actions from B, we are finished
-		case !b.More():
-			break ComposeLoop
-
-		case !a.More():
-			if b.PeekType() == Insert {
-				composedActions = append(composedActions, b.Consume())
-				continue ComposeLoop
-			}
-			break ComposeLoop
-
-		// // optimization case
-		// // anytime the first action is a delete, we can apply it immediately
-		// case a.PeekType() == Delete:
-		// 	composedActions = append(composedActions, a.Consume())
-
-		// // optimization case
-		// // anytime the second action is an insert, we can apply it immediately
-		// case b.PeekType() == Insert:
-		// 	composedActions = append(composedActions, b.Consume())
-
-		case a.PeekType() == Insert && b.PeekType() == Insert:
-			composedActions = append(composedActions, b.Consume(), a.Consume())
-
-		case a.PeekType() == Insert && b.PeekType() == Delete:
-			f, s := a.Peek(), b.Peek()
-			if string(f.(InsertAction)) != string(s.(DeleteAction)) {
-				panic(cooperate.ErrDeleteMismatch)
-			}
-			a.Consume()
-			b.Consume()
-
-		case a.PeekType() == Insert && b.PeekType() == Retain:
-			composedActions = append(composedActions, a.Consume())
-			b.Consume()
-
-		case a.PeekType() == Delete && b.PeekType() == Insert:
-			composedActions = append(composedActions, a.Consume(), b.Consume())
-
-		case a.PeekType() == Delete && b.PeekType() == Delete:
-			composedActions = append(composedActions, a.Consume(), b.Consume())
-
-		case a.PeekType() == Delete && b.PeekType() == Retain:
-			composedActions = append(composedActions, a.Consume())
-
-		case a.PeekType() == Retain && b.PeekType() == Insert:
-			composedActions = append(composedActions, b.Consume())
-
-		case a.PeekType() == Retain && b.PeekType() == Delete:
-			a.Consume()
-			composedActions = append(composedActions, b.Consume())
-
-		case a.PeekType() == Retain && b.PeekType() == Retain:
-			composedActions = append(composedActions, a.Consume())
-			b.Consume()
-		}
-	}
-
-	// a document size mismatch occurs if we didn't process everything
-	if a.More() || b.More() {
-		return nil, cooperate.ErrDocumentSizeMismatch
-	}
-
-	return cooperate.Reduce(th, cooperate.Operation(composedActions)), nil
+	return handler[cooperate.Action]{}.Compose(a, b)
 }
 
 // Transform implements cooperate.Transformer for the basic text operations
@@ -160,91 +103,161 @@ ComposeLoop:
 //
 // This implementation favors b; that is, if a and b both act on the
 // same element, the effect is as though b's intended change was applied first.
+// The order of concurrent insertions at the same location is instead decided
+// by th.Priority.
 func (th TextHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+	return handler[cooperate.Action]{priority: th.Priority}.Transform(a, b)
+}
 
-	var aPrime, bPrime []cooperate.Action // new list of actions
-
-TransformLoop:
-	for {
+// OperationSize implements cooperate.Sizer, measuring the bytes of text op
+// inserts and deletes.
+func (th TextHandler) OperationSize(op cooperate.Operation) int {
+	return size(op)
+}
 
-		switch {
+// DocumentSize implements cooperate.Sizer. The length of the document op
+// produces is known from op alone, unless op is empty.
+func (th TextHandler) DocumentSize(doc cooperate.Document, op cooperate.Operation) int {
+	if len(op) == 0 {
+		return length(doc)
+	}
+	_, post := Lengths(op)
+	return post
+}
 
-		// if we reach the ends at the same time, we are done
-		case !a.More() && !b.More():
-			break TransformLoop
+func (th TypedHandler) Expand(a Action) []Action {
+	return handler[Action]{}.Expand(a)
+}
 
-		// if we are at the end of b but not a, then a must contain only inserts
-		case a.More() && !b.More():
-			if a.PeekType() == Insert {
-				aPrime = append(aPrime, a.Consume())
-				bPrime = append(bPrime, RetainAction(1))
-				continue
-			}
-			break TransformLoop
+// Reduce behaves like TextHandler.Reduce.
+func (th TypedHandler) Reduce(a, b Action) (Action, bool) {
+	return handler[Action]{}.Reduce(a, b)
+}
 
-		// if we are at the end of a but not b, then b must contain only inserts
-		case !a.More() && b.More():
-			if b.PeekType() == Insert {
-				aPrime = append(aPrime, RetainAction(1))
-				bPrime = append(bPrime, b.Consume())
-				continue
-			}
-			break TransformLoop
+// Compose behaves like TextHandler.Compose.
+func (th TypedHandler) Compose(a, b *cooperate.OperationIteratorOf[Action]) (cooperate.OperationOf[Action], error) {
+	return handler[Action]{}.Compose(a, b)
+}
 
-		case a.PeekType() == Insert && b.PeekType() == Insert:
-			aPrime = append(aPrime, RetainAction(1))
-			bPrime = append(bPrime, b.Consume())
+// Transform behaves like TextHandler.Transform.
+func (th TypedHandler) Transform(a, b *cooperate.OperationIteratorOf[Action]) (aa, bb cooperate.OperationOf[Action], err error) {
+	return handler[Action]{priority: th.Priority}.Transform(a, b)
+}
 
-		case a.PeekType() == Insert && b.PeekType() == Delete:
-			aPrime = append(aPrime, a.Consume())
-			bPrime = append(bPrime, RetainAction(1))
+// OperationSize behaves like TextHandler.OperationSize.
+func (th TypedHandler) OperationSize(op cooperate.OperationOf[Action]) int {
+	return size(op)
+}
 
-		case a.PeekType() == Insert && b.PeekType() == Retain:
-			aPrime = append(aPrime, a.Consume())
-			bPrime = append(bPrime, RetainAction(1))
+// DocumentSize behaves like TextHandler.DocumentSize.
+func (th TypedHandler) DocumentSize(doc cooperate.DocumentOf[Action], op cooperate.OperationOf[Action]) int {
+	if len(op) == 0 {
+		return length(doc)
+	}
+	_, post := Lengths(op)
+	return post
+}
 
-		case a.PeekType() == Delete && b.PeekType() == Insert:
-			aPrime = append(aPrime, RetainAction(1))
-			bPrime = append(bPrime, b.Consume())
+// length returns the length of doc, a document of this package.
+func length(doc interface{}) int {
+	switch doc := doc.(type) {
+	case interface{ Len() int }:
+		return doc.Len()
+	case fmt.Stringer:
+		return len(doc.String())
+	}
+	return 0
+}
 
-		case a.PeekType() == Delete && b.PeekType() == Delete:
-			a.Consume()
-			b.Consume()
+// handler implements the text operations for any action type A able to hold
+// the text actions, which lets TextHandler and TypedHandler share it.
+type handler[A any] struct {
+	priority Priority
+}
 
-		case a.PeekType() == Delete && b.PeekType() == Retain:
-			aPrime = append(aPrime, a.Consume())
-			b.Consume()
+// action converts one of the text actions to an A.
+func (handler[A]) action(a any) A {
+	return a.(A)
+}
 
-		case a.PeekType() == Retain && b.PeekType() == Insert:
-			aPrime = append(aPrime, RetainAction(1))
-			bPrime = append(bPrime, b.Consume())
+func (h handler[A]) Expand(a A) []A {
+	var actions []A
+	switch a := any(a).(type) {
+	case RetainAction:
+		for i := 0; i < int(a); i++ {
+			actions = append(actions, h.action(RetainAction(1)))
+		}
+	case InsertAction:
+		for _, x := range string(a) {
+			actions = append(actions, h.action(InsertAction(x)))
+		}
+	case DeleteAction:
+		for _, x := range string(a) {
+			actions = append(actions, h.action(DeleteAction(x)))
+		}
+	}
+	return actions
+}
 
-		case a.PeekType() == Retain && b.PeekType() == Delete:
-			a.Consume()
-			bPrime = append(bPrime, b.Consume())
+func (h handler[A]) Reduce(a, b A) (A, bool) {
 
-		case a.PeekType() == Retain && b.PeekType() == Retain:
-			aPrime = append(aPrime, a.Consume())
-			bPrime = append(bPrime, b.Consume())
+	var zero A
 
+	switch a := any(a).(type) {
+	case InsertAction:
+		if b, ok := any(b).(InsertAction); ok {
+			return h.action(a + b), true
+		}
+	case DeleteAction:
+		if b, ok := any(b).(DeleteAction); ok {
+			return h.action(a + b), true
+		}
+	case RetainAction:
+		if b, ok := any(b).(RetainAction); ok {
+			return h.action(a + b), true
 		}
-
 	}
 
-	// a document size mismatch occurs if we didn't process everything
-	if a.More() || b.More() {
-		return nil, nil, cooperate.ErrDocumentSizeMismatch
+	// if the actions aren't the same type, then they can't be reduced
+	return zero, false
+}
+
+// Kind implements runlength.Alphabet.
+func (handler[A]) Kind(a A) runlength.Kind {
+	switch any(a).(type) {
+	case RetainAction:
+		return runlength.Retain
+	case InsertAction:
+		return runlength.Insert
+	case DeleteAction:
+		return runlength.Delete
 	}
+	return runlength.Unknown
+}
+
+// Retain implements runlength.Alphabet.
+func (h handler[A]) Retain(n int) A {
+	return h.action(RetainAction(n))
+}
 
-	return cooperate.Reduce(th, cooperate.Operation(aPrime)), cooperate.Reduce(th, cooperate.Operation(bPrime)), nil
+// Matches implements runlength.Alphabet.
+func (handler[A]) Matches(insert, del A) bool {
+	return string(any(insert).(InsertAction)) == string(any(del).(DeleteAction))
+}
 
+func (h handler[A]) Compose(a, b *cooperate.OperationIteratorOf[A]) (cooperate.OperationOf[A], error) {
+	return runlength.Compose[A](h, a, b)
+}
+
+func (h handler[A]) Transform(a, b *cooperate.OperationIteratorOf[A]) (aa, bb cooperate.OperationOf[A], err error) {
+	return runlength.Transform[A](h, a, b, h.priority == FavorA)
 }
 
 // Lengths calculates the lengths of the document op expects to be applied to
 // and the length of that document after applying op.
-func Lengths(op cooperate.Operation) (pre, post int) {
-	for _, a := range []cooperate.Action(op) {
-		switch a := a.(type) {
+func Lengths[A any](op cooperate.OperationOf[A]) (pre, post int) {
+	for _, a := range []A(op) {
+		switch a := any(a).(type) {
 		case RetainAction:
 			pre += int(a)
 			post += int(a)
@@ -256,3 +269,16 @@ func Lengths(op cooperate.Operation) (pre, post int) {
 	}
 	return
 }
+
+// size returns the bytes of text op inserts and deletes.
+func size[A any](op cooperate.OperationOf[A]) (n int) {
+	for _, a := range []A(op) {
+		switch a := any(a).(type) {
+		case InsertAction:
+			n += len(a)
+		case DeleteAction:
+			n += len(a)
+		}
+	}
+	return
+}
@@ -1,10 +1,12 @@
 package text
 
 import (
+	"math/rand"
 	"reflect"
 	"testing"
 
 	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
 )
 
 func TestReduce(t *testing.T) {
@@ -139,6 +141,43 @@ func TestCompose(t *testing.T) {
 				RetainAction(2),
 			}),
 		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				InsertAction("a"),
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				InsertAction("b"),
+				RetainAction(1),
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				InsertAction("ba"),
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				DeleteAction("a"),
+				RetainAction(1),
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				DeleteAction("b"),
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				DeleteAction("ab"),
+			}),
+		},
+		{
+			First: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				DeleteAction("b"),
+			}),
+			Second: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+			}),
+			Composition: cooperate.Operation([]cooperate.Action{
+				RetainAction(1),
+				DeleteAction("b"),
+			}),
+		},
 	}
 
 	var th TextHandler
@@ -218,3 +257,74 @@ func TestTransform(t *testing.T) {
 	}
 
 }
+
+func TestTypedHandler_Transform(t *testing.T) {
+
+	var th TypedHandler
+
+	a := cooperate.NewOperationIterator(cooperate.Expand[Action](th, cooperate.OperationOf[Action]{RetainAction(2), InsertAction("t")}))
+	b := cooperate.NewOperationIterator(cooperate.Expand[Action](th, cooperate.OperationOf[Action]{RetainAction(2), InsertAction("a")}))
+
+	aPrime, bPrime, err := th.Transform(a, b)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if expected := (cooperate.OperationOf[Action]{RetainAction(3), InsertAction("t")}); !reflect.DeepEqual(aPrime, expected) {
+		t.Errorf("unexpected a': expected '%#v' but got '%#v'", expected, aPrime)
+	}
+
+	if expected := (cooperate.OperationOf[Action]{RetainAction(2), InsertAction("a"), RetainAction(1)}); !reflect.DeepEqual(bPrime, expected) {
+		t.Errorf("unexpected b': expected '%#v' but got '%#v'", expected, bPrime)
+	}
+
+}
+
+func TestTransform_Priority(t *testing.T) {
+
+	ops := map[string]cooperate.Operation{
+		"alice": {RetainAction(1), InsertAction("a"), RetainAction(1)},
+		"bob":   {RetainAction(1), InsertAction("b"), RetainAction(1)},
+	}
+
+	// each peer transforms the other's operation against its own, and both
+	// must arrive at the same document
+	for _, peers := range [][2]string{{"alice", "bob"}, {"bob", "alice"}} {
+
+		self, other := peers[0], peers[1]
+		th := TextHandler{Priority: PriorityByID(self, other)}
+
+		_, otherPrime, err := th.Transform(
+			cooperate.NewOperationIterator(cooperate.Expand(th, ops[self])),
+			cooperate.NewOperationIterator(cooperate.Expand(th, ops[other])),
+		)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %s", self, err)
+		}
+
+		doc := NewTextDocument("xy")
+		doc.Apply(ops[self])
+		if err := doc.Apply(otherPrime); err != nil {
+			t.Fatalf("[%s] unexpected error: %s", self, err)
+		}
+
+		if expected := "xaby"; doc.String() != expected {
+			t.Errorf("[%s] unexpected document: expected %q but got %q", self, expected, doc.String())
+		}
+	}
+
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, ottest.Config[cooperate.Action]{
+		NewDocument: func(r *rand.Rand) cooperate.Document {
+			return NewTextDocument("hello"[:r.Intn(6)])
+		},
+		Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+			return TextHandler{Priority: p}
+		},
+		RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+			return randomOperation(r, doc.(*TextDocument).String(), 3)
+		},
+	}, 2000)
+}
@@ -0,0 +1,191 @@
+package tree
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/text"
+)
+
+// ErrInvalidPath indicates that an action's path does not address a node
+// that exists in the document.
+var ErrInvalidPath = errors.New("invalid path")
+
+// A Node is an element of a tree, such as an item of an outline or an XML
+// element.
+type Node struct {
+	Text       string
+	Attributes map[string]string
+	Children   []*Node
+}
+
+// Clone returns a deep copy of n.
+func (n *Node) Clone() *Node {
+	if n == nil {
+		return nil
+	}
+	c := &Node{Text: n.Text}
+	if len(n.Attributes) > 0 {
+		c.Attributes = make(map[string]string, len(n.Attributes))
+		for k, v := range n.Attributes {
+			c.Attributes[k] = v
+		}
+	}
+	for _, child := range n.Children {
+		c.Children = append(c.Children, child.Clone())
+	}
+	return c
+}
+
+// A TreeDocument is a tree of Nodes that implements the cooperate.Document
+// interface. Its root node always exists; actions address its descendants.
+type TreeDocument struct {
+	root *Node
+}
+
+// NewTreeDocument initializes a TreeDocument with a copy of root, which may
+// be nil for an empty tree.
+func NewTreeDocument(root *Node) *TreeDocument {
+	if root == nil {
+		root = &Node{}
+	}
+	return &TreeDocument{root: root.Clone()}
+}
+
+// Root returns the root node of the document. It must not be modified.
+func (td *TreeDocument) Root() *Node {
+	return td.root
+}
+
+// Get returns the node at p, or nil if none exists.
+func (td *TreeDocument) Get(p Path) *Node {
+	return find(td.root, p)
+}
+
+func find(root *Node, p Path) *Node {
+	n := root
+	for _, i := range p {
+		if i < 0 || i >= len(n.Children) {
+			return nil
+		}
+		n = n.Children[i]
+	}
+	return n
+}
+
+// Apply performs op against the TreeDocument. The actions of op are applied
+// in order; if any of them fails, the document is left unchanged.
+func (td *TreeDocument) Apply(op cooperate.Operation) error {
+
+	root := td.root.Clone()
+	for _, a := range op {
+		if err := applyAction(root, a); err != nil {
+			return err
+		}
+	}
+
+	td.root = root
+	return nil
+
+}
+
+func applyAction(root *Node, a cooperate.Action) error {
+
+	switch a := a.(type) {
+
+	case InsertAction:
+		parent, i, err := locate(root, a.Path)
+		if err != nil || i > len(parent.Children) {
+			return ErrInvalidPath
+		}
+		insert(parent, i, a.Node.Clone())
+
+	case DeleteAction:
+		if _, err := remove(root, a.Path, a.Node); err != nil {
+			return err
+		}
+
+	case MoveAction:
+		n, err := remove(root, a.From, nil)
+		if err != nil {
+			return err
+		}
+		parent, i, err := locate(root, a.To)
+		if err != nil || i > len(parent.Children) {
+			return ErrInvalidPath
+		}
+		insert(parent, i, n)
+
+	case EditTextAction:
+		n := find(root, a.Path)
+		if n == nil {
+			return ErrInvalidPath
+		}
+		doc := text.NewTextDocument(n.Text)
+		if err := doc.Apply(a.Op); err != nil {
+			return err
+		}
+		n.Text = doc.String()
+
+	case SetAttributeAction:
+		n := find(root, a.Path)
+		if n == nil {
+			return ErrInvalidPath
+		}
+		if a.Value == "" {
+			delete(n.Attributes, a.Key)
+			if len(n.Attributes) == 0 {
+				n.Attributes = nil
+			}
+		} else {
+			if n.Attributes == nil {
+				n.Attributes = make(map[string]string)
+			}
+			n.Attributes[a.Key] = a.Value
+		}
+
+	default:
+		return cooperate.ErrUnknownAction
+
+	}
+
+	return nil
+
+}
+
+// locate returns the parent of the node at p and the index of p within it.
+func locate(root *Node, p Path) (*Node, int, error) {
+	if len(p) == 0 {
+		return nil, 0, ErrInvalidPath
+	}
+	parent := find(root, p[:len(p)-1])
+	if parent == nil || p[len(p)-1] < 0 {
+		return nil, 0, ErrInvalidPath
+	}
+	return parent, p[len(p)-1], nil
+}
+
+func insert(parent *Node, i int, n *Node) {
+	parent.Children = append(parent.Children, nil)
+	copy(parent.Children[i+1:], parent.Children[i:])
+	parent.Children[i] = n
+}
+
+// remove detaches and returns the node at p. If expected is not nil, the
+// node must be equal to it.
+func remove(root *Node, p Path, expected *Node) (*Node, error) {
+	parent, i, err := locate(root, p)
+	if err != nil || i >= len(parent.Children) {
+		return nil, ErrInvalidPath
+	}
+	n := parent.Children[i]
+	if expected != nil && !reflect.DeepEqual(n.Clone(), expected.Clone()) {
+		return nil, cooperate.ErrDeleteMismatch
+	}
+	parent.Children = append(parent.Children[:i], parent.Children[i+1:]...)
+	if len(parent.Children) == 0 {
+		parent.Children = nil
+	}
+	return n, nil
+}
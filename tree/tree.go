@@ -0,0 +1,432 @@
+// Package tree implements collaborative editing of hierarchical documents,
+// such as outlines or XML, in which nodes have text, attributes and
+// children.
+//
+// Every action addresses a node by its Path from the root, and an operation
+// is a list of actions applied one after another.
+//
+// # Concurrent moves
+//
+// Two concurrent moves can each be valid on their own and yet together
+// place a node inside its own subtree: for example, one user moves A under
+// B while another moves B under A. Transform resolves such conflicts in
+// favor of the operation it favors for every other conflict as well, which
+// is b unless the handler's Priority favors a: b's move takes effect and
+// a's move is discarded. a' is then empty, while b' first reverts a's move
+// and then performs its own, so that both orders of application converge.
+//
+// For example, in a document whose top-level nodes are A and B, suppose a
+// moves A under B and b moves B under A:
+//
+//	a  = M([0], [0 0])
+//	b  = M([1], [0 0])
+//	a' = nothing
+//	b' = M([0 0], [0]), M([1], [0 0])
+//
+// Applying a leaves B with the child A; b' moves A back to the top level and
+// then moves B under it. Applying b and then the empty a' gives the same
+// result: A with the child B.
+//
+// Likewise, when a node is concurrently deleted and moved, the delete wins:
+// a node moved out of a deleted subtree is deleted along with it, and a
+// node moved into a deleted subtree is deleted too.
+package tree
+
+import (
+	"fmt"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/json0"
+)
+
+type (
+	// A TreeHandler implements cooperate.ComposeTransformer and
+	// cooperate.ExpandReducer for the tree actions defined in this package.
+	TreeHandler struct {
+		// Priority decides whose change wins when a and b conflict.
+		Priority cooperate.Priority
+	}
+
+	// A Path addresses a node by the indices of its ancestors among their
+	// siblings, starting from the children of the root.
+	Path []int
+
+	// InsertAction inserts Node so that it ends up at Path.
+	InsertAction struct {
+		Path Path
+		Node *Node
+	}
+
+	// DeleteAction removes the node at Path and its subtree. If Node is set,
+	// the subtree must be equal to it; Transform clears Node if a concurrent
+	// operation changed the subtree.
+	DeleteAction struct {
+		Path Path
+		Node *Node
+	}
+
+	// MoveAction moves the node at From and its subtree so that it ends up
+	// at To. To is interpreted after the node has been removed from From.
+	MoveAction struct {
+		From, To Path
+	}
+
+	// EditTextAction applies Op, an operation made of text actions, to the
+	// text of the node at Path.
+	EditTextAction struct {
+		Path Path
+		Op   cooperate.Operation
+	}
+
+	// SetAttributeAction sets the attribute Key of the node at Path to Value,
+	// or removes it if Value is empty.
+	SetAttributeAction struct {
+		Path       Path
+		Key, Value string
+	}
+)
+
+func (a InsertAction) GoString() string   { return fmt.Sprintf("I(%v, %+v)", a.Path, a.Node) }
+func (a DeleteAction) GoString() string   { return fmt.Sprintf("D(%v)", a.Path) }
+func (a MoveAction) GoString() string     { return fmt.Sprintf("M(%v, %v)", a.From, a.To) }
+func (a EditTextAction) GoString() string { return fmt.Sprintf("T(%v, %#v)", a.Path, a.Op) }
+
+func (a SetAttributeAction) GoString() string {
+	return fmt.Sprintf("A(%v, %s=%s)", a.Path, a.Key, a.Value)
+}
+
+// Expand returns a unchanged, since tree actions are already atomic.
+func (th TreeHandler) Expand(a cooperate.Action) []cooperate.Action {
+	return []cooperate.Action{a}
+}
+
+// Reduce merges consecutive text edits of the same node, and consecutive
+// sets of the same attribute.
+func (th TreeHandler) Reduce(a, b cooperate.Action) (cooperate.Action, bool) {
+	switch a := a.(type) {
+	case EditTextAction:
+		if b, ok := b.(EditTextAction); ok && equal(a.Path, b.Path) {
+			if op, err := json0.ComposeText(a.Op, b.Op); err == nil {
+				return EditTextAction{Path: a.Path, Op: op}, true
+			}
+		}
+	case SetAttributeAction:
+		if b, ok := b.(SetAttributeAction); ok && equal(a.Path, b.Path) && a.Key == b.Key {
+			return b, true
+		}
+	}
+	return nil, false
+}
+
+// Compose merges a and b into a single operation c such that the effect of
+// applying c is equal to that of applying a then b.
+func (th TreeHandler) Compose(a, b *cooperate.OperationIterator) (cooperate.Operation, error) {
+
+	var composed []cooperate.Action
+
+	for _, it := range []*cooperate.OperationIterator{a, b} {
+		for it.More() {
+			next := it.Consume()
+			if !known(next) {
+				return nil, cooperate.ErrUnknownAction
+			}
+			composed = appendAction(th, composed, next)
+		}
+	}
+
+	return cooperate.Operation(composed), nil
+
+}
+
+// Transform implements cooperate.Transformer for the tree actions defined in
+// this package.
+//
+// By default this implementation favors b: concurrent inserts at the same
+// location place b's node first, b's move wins if both move the same node or
+// their moves would form a cycle, and b's value wins if both set the same
+// attribute. th.Priority may favor a instead.
+func (th TreeHandler) Transform(a, b *cooperate.OperationIterator) (aa, bb cooperate.Operation, err error) {
+
+	// appendAction drops moves of a node to where it already is, which
+	// must not shift anything else
+	var aActions, bActions []cooperate.Action
+	for a.More() {
+		aActions = appendAction(th, aActions, a.Consume())
+	}
+	for b.More() {
+		bActions = appendAction(th, bActions, b.Consume())
+	}
+
+	// the left side wins conflicts
+	left, right := bActions, aActions
+	if th.Priority == cooperate.FavorA {
+		left, right = aActions, bActions
+	}
+
+	for _, x := range append(append([]cooperate.Action(nil), left...), right...) {
+		if !known(x) {
+			return nil, nil, cooperate.ErrUnknownAction
+		}
+	}
+
+	merge := func(actions []cooperate.Action, a cooperate.Action) ([]cooperate.Action, error) {
+		return appendAction(th, actions, a), nil
+	}
+
+	leftPrime, rightPrime, err := json0.TransformSequences(left, right, transformAction, merge)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if th.Priority == cooperate.FavorA {
+		return cooperate.Operation(leftPrime), cooperate.Operation(rightPrime), nil
+	}
+	return cooperate.Operation(rightPrime), cooperate.Operation(leftPrime), nil
+
+}
+
+// transformAction transforms c so that it applies after other. If c and
+// other conflict, c wins when isLeft.
+func transformAction(c, other cooperate.Action, isLeft bool) ([]cooperate.Action, error) {
+
+	switch c := c.(type) {
+
+	case InsertAction:
+		p, ok := mapInsertion(c.Path, other, isLeft)
+		if !ok {
+			return nil, nil
+		}
+		return []cooperate.Action{InsertAction{Path: p, Node: c.Node}}, nil
+
+	case DeleteAction:
+		p, ok := mapNode(c.Path, other)
+		if !ok {
+			return nil, nil
+		}
+
+		node := c.Node
+		if touches(other, c.Path) {
+			node = nil
+		}
+
+		// a node moved out of the deleted subtree is deleted as well
+		if m, ok := other.(MoveAction); ok && isPrefix(c.Path, m.From) && len(m.From) > len(c.Path) && !isPrefix(p, m.To) {
+			p, _ = mapNode(p, DeleteAction{Path: m.To})
+			return []cooperate.Action{DeleteAction{Path: m.To}, DeleteAction{Path: p}}, nil
+		}
+
+		return []cooperate.Action{DeleteAction{Path: p, Node: node}}, nil
+
+	case MoveAction:
+		if m, ok := other.(MoveAction); ok && equal(c.From, m.From) {
+			// we both moved the same node; the left move wins
+			if !isLeft {
+				return nil, nil
+			}
+			return []cooperate.Action{MoveAction{From: clonePath(m.To), To: clonePath(c.To)}}, nil
+		}
+
+		from, ok := mapNode(c.From, other)
+		if !ok {
+			// the node was deleted
+			return nil, nil
+		}
+
+		to, ok := mapInsertion(beforeRemoval(c.To, c.From), other, isLeft)
+		if !ok {
+			// the destination was deleted, so the node is deleted with it
+			return []cooperate.Action{DeleteAction{Path: from}}, nil
+		}
+
+		if m, ok := other.(MoveAction); ok && isPrefix(from, to) && len(to) > len(from) {
+			// the moves would place the node inside its own subtree
+			if !isLeft {
+				return nil, nil
+			}
+			return []cooperate.Action{MoveAction{From: clonePath(m.To), To: clonePath(m.From)}, c}, nil
+		}
+
+		return []cooperate.Action{MoveAction{From: from, To: afterRemoval(to, from)}}, nil
+
+	case EditTextAction:
+		p, ok := mapNode(c.Path, other)
+		if !ok {
+			return nil, nil
+		}
+		op := c.Op
+		if o, ok := other.(EditTextAction); ok && equal(c.Path, o.Path) {
+			var err error
+			if isLeft {
+				_, op, err = json0.TransformText(o.Op, c.Op)
+			} else {
+				op, _, err = json0.TransformText(c.Op, o.Op)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		return []cooperate.Action{EditTextAction{Path: p, Op: op}}, nil
+
+	case SetAttributeAction:
+		p, ok := mapNode(c.Path, other)
+		if !ok {
+			return nil, nil
+		}
+		if o, ok := other.(SetAttributeAction); ok && !isLeft && equal(c.Path, o.Path) && c.Key == o.Key {
+			return nil, nil
+		}
+		return []cooperate.Action{SetAttributeAction{Path: p, Key: c.Key, Value: c.Value}}, nil
+
+	}
+
+	return nil, cooperate.ErrUnknownAction
+
+}
+
+// mapNode returns the path at which the node at p can be found after other
+// is applied, or false if other deletes it.
+func mapNode(p Path, other cooperate.Action) (Path, bool) {
+
+	switch o := other.(type) {
+
+	case InsertAction:
+		return shift(p, o.Path, 1, func(op, pp int) bool { return op <= pp }), true
+
+	case DeleteAction:
+		if isPrefix(o.Path, p) {
+			return nil, false
+		}
+		return shift(p, o.Path, -1, func(op, pp int) bool { return op < pp }), true
+
+	case MoveAction:
+		if isPrefix(o.From, p) {
+			return append(clonePath(o.To), p[len(o.From):]...), true
+		}
+		p, _ = mapNode(p, DeleteAction{Path: o.From})
+		return mapNode(p, InsertAction{Path: o.To})
+
+	}
+
+	return clonePath(p), true
+
+}
+
+// mapInsertion returns the path at which a node should be inserted after
+// other is applied to have the effect of inserting it at p before, or false
+// if other deletes its parent. Insertions at the same location as other
+// are placed first if isLeft.
+func mapInsertion(p Path, other cooperate.Action, isLeft bool) (Path, bool) {
+
+	switch o := other.(type) {
+
+	case InsertAction:
+		return shift(p, o.Path, 1, func(op, pp int) bool {
+			return op < pp || (op == pp && (len(o.Path) < len(p) || !isLeft))
+		}), true
+
+	case DeleteAction:
+		if isPrefix(o.Path, p) && len(o.Path) < len(p) {
+			return nil, false
+		}
+		return shift(p, o.Path, -1, func(op, pp int) bool { return op < pp }), true
+
+	case MoveAction:
+		if isPrefix(o.From, p) && len(o.From) < len(p) {
+			return append(clonePath(o.To), p[len(o.From):]...), true
+		}
+		p, _ = mapInsertion(p, DeleteAction{Path: o.From}, isLeft)
+		return mapInsertion(p, InsertAction{Path: o.To}, isLeft)
+
+	}
+
+	return clonePath(p), true
+
+}
+
+// shift adjusts p for the insertion (by = 1) or removal (by = -1) of a
+// sibling of p or of one of its ancestors at o, if cond holds for their
+// indices.
+func shift(p, o Path, by int, cond func(op, pp int) bool) Path {
+	p = clonePath(p)
+	if len(o) == 0 || len(o) > len(p) || !isPrefix(o[:len(o)-1], p) {
+		return p
+	}
+	if d := len(o) - 1; cond(o[d], p[d]) {
+		p[d] += by
+	}
+	return p
+}
+
+// beforeRemoval converts to, an insertion point after the node at from has
+// been removed, to one before.
+func beforeRemoval(to, from Path) Path {
+	return shift(to, from, 1, func(op, pp int) bool { return op <= pp })
+}
+
+// afterRemoval converts to, an insertion point before the node at from has
+// been removed, to one after.
+func afterRemoval(to, from Path) Path {
+	return shift(to, from, -1, func(op, pp int) bool { return op < pp })
+}
+
+// touches reports whether other changes anything within the subtree at p.
+func touches(other cooperate.Action, p Path) bool {
+	switch o := other.(type) {
+	case InsertAction:
+		return isPrefix(p, o.Path) && len(o.Path) > len(p)
+	case DeleteAction:
+		return isPrefix(p, o.Path) && len(o.Path) > len(p)
+	case MoveAction:
+		to := beforeRemoval(o.To, o.From)
+		return (isPrefix(p, o.From) && len(o.From) > len(p)) || (isPrefix(p, to) && len(to) > len(p))
+	case EditTextAction:
+		return isPrefix(p, o.Path)
+	case SetAttributeAction:
+		return isPrefix(p, o.Path)
+	}
+	return false
+}
+
+// appendAction adds a to the end of actions, merging it with the last action
+// where possible.
+func appendAction(th TreeHandler, actions []cooperate.Action, a cooperate.Action) []cooperate.Action {
+	if m, ok := a.(MoveAction); ok && equal(m.From, m.To) {
+		return actions
+	}
+	if n := len(actions); n > 0 {
+		if reduced, ok := th.Reduce(actions[n-1], a); ok {
+			actions[n-1] = reduced
+			return actions
+		}
+	}
+	return append(actions, a)
+}
+
+func known(a cooperate.Action) bool {
+	switch a.(type) {
+	case InsertAction, DeleteAction, MoveAction, EditTextAction, SetAttributeAction:
+		return true
+	}
+	return false
+}
+
+// isPrefix reports whether p is a prefix of q, including q itself.
+func isPrefix(p, q Path) bool {
+	return len(p) <= len(q) && equal(p, q[:len(p)])
+}
+
+func equal(p, q Path) bool {
+	if len(p) != len(q) {
+		return false
+	}
+	for i := range p {
+		if p[i] != q[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func clonePath(p Path) Path {
+	return append(Path(nil), p...)
+}
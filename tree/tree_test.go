@@ -0,0 +1,323 @@
+package tree
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/tylerchr/cooperate"
+	"github.com/tylerchr/cooperate/internal/ottest"
+	"github.com/tylerchr/cooperate/text"
+)
+
+// outline returns a tree with the given top-level items, each of which may
+// list the text of its own children.
+func outline(items ...[]string) *Node {
+	root := &Node{}
+	for _, item := range items {
+		n := &Node{Text: item[0]}
+		for _, child := range item[1:] {
+			n.Children = append(n.Children, &Node{Text: child})
+		}
+		root.Children = append(root.Children, n)
+	}
+	return root
+}
+
+func TestTreeDocument(t *testing.T) {
+
+	cases := []struct {
+		Existing      *Node
+		Operation     cooperate.Operation
+		ExpectedError error
+		Expected      *Node
+	}{
+		{
+			Existing: outline([]string{"a"}),
+			Operation: cooperate.Operation{
+				InsertAction{Path: Path{1}, Node: &Node{Text: "b"}},
+				InsertAction{Path: Path{0, 0}, Node: &Node{Text: "a1"}},
+			},
+			Expected: outline([]string{"a", "a1"}, []string{"b"}),
+		},
+		{
+			Existing: outline([]string{"a", "a1"}, []string{"b"}),
+			Operation: cooperate.Operation{
+				MoveAction{From: Path{1}, To: Path{0, 1}},
+				EditTextAction{Path: Path{0, 1}, Op: cooperate.Operation{text.InsertAction("x"), text.RetainAction(1)}},
+				SetAttributeAction{Path: Path{0}, Key: "collapsed", Value: "true"},
+			},
+			Expected: &Node{Children: []*Node{
+				{Text: "a", Attributes: map[string]string{"collapsed": "true"}, Children: []*Node{{Text: "a1"}, {Text: "xb"}}},
+			}},
+		},
+		{
+			Existing: outline([]string{"a", "a1"}, []string{"b"}),
+			Operation: cooperate.Operation{
+				DeleteAction{Path: Path{0}, Node: &Node{Text: "a"}},
+			},
+			ExpectedError: cooperate.ErrDeleteMismatch,
+			Expected:      outline([]string{"a", "a1"}, []string{"b"}),
+		},
+		{
+			Existing: outline([]string{"a"}),
+			Operation: cooperate.Operation{
+				DeleteAction{Path: Path{0}},
+				DeleteAction{Path: Path{0}},
+			},
+			ExpectedError: ErrInvalidPath,
+			Expected:      outline([]string{"a"}),
+		},
+	}
+
+	for i, c := range cases {
+
+		doc := NewTreeDocument(c.Existing)
+
+		if err := doc.Apply(c.Operation); err != c.ExpectedError {
+			t.Errorf("[case %d] unexpected error: expected '%v' but got '%v'", i, c.ExpectedError, err)
+		} else if !reflect.DeepEqual(doc.Root(), c.Expected) {
+			t.Errorf("[case %d] unexpected document: expected '%s' but got '%s'", i, format(c.Expected), format(doc.Root()))
+		}
+	}
+
+}
+
+func TestTransform(t *testing.T) {
+
+	cases := []struct {
+		Name     string
+		Existing *Node
+		A, B     cooperate.Operation
+		Expected *Node
+	}{
+		{
+			Name:     "concurrent inserts at the same location",
+			Existing: outline([]string{"x"}),
+			A:        cooperate.Operation{InsertAction{Path: Path{0}, Node: &Node{Text: "a"}}},
+			B:        cooperate.Operation{InsertAction{Path: Path{0}, Node: &Node{Text: "b"}}},
+			Expected: outline([]string{"b"}, []string{"a"}, []string{"x"}),
+		},
+		{
+			Name:     "edit inside a concurrently moved subtree",
+			Existing: outline([]string{"a", "a1"}, []string{"b"}),
+			A:        cooperate.Operation{EditTextAction{Path: Path{0, 0}, Op: cooperate.Operation{text.RetainAction(2), text.InsertAction("!")}}},
+			B:        cooperate.Operation{MoveAction{From: Path{0}, To: Path{1}}},
+			Expected: outline([]string{"b"}, []string{"a", "a1!"}),
+		},
+		{
+			Name:     "insert into a concurrently deleted subtree",
+			Existing: outline([]string{"a", "a1"}, []string{"b"}),
+			A:        cooperate.Operation{InsertAction{Path: Path{0, 1}, Node: &Node{Text: "a2"}}},
+			B:        cooperate.Operation{DeleteAction{Path: Path{0}, Node: &Node{Text: "a", Children: []*Node{{Text: "a1"}}}}},
+			Expected: outline([]string{"b"}),
+		},
+		{
+			Name:     "move out of a concurrently deleted subtree",
+			Existing: outline([]string{"a", "a1"}, []string{"b"}),
+			A:        cooperate.Operation{MoveAction{From: Path{0, 0}, To: Path{2}}},
+			B:        cooperate.Operation{DeleteAction{Path: Path{0}}},
+			Expected: outline([]string{"b"}),
+		},
+		{
+			Name:     "move into a concurrently deleted subtree",
+			Existing: outline([]string{"a", "a1"}, []string{"b"}, []string{"c"}),
+			A:        cooperate.Operation{DeleteAction{Path: Path{0}}},
+			B:        cooperate.Operation{MoveAction{From: Path{2}, To: Path{0, 0}}},
+			Expected: outline([]string{"b"}),
+		},
+		{
+			Name:     "concurrent moves of the same node",
+			Existing: outline([]string{"a"}, []string{"b"}, []string{"c"}),
+			A:        cooperate.Operation{MoveAction{From: Path{0}, To: Path{2}}},
+			B:        cooperate.Operation{MoveAction{From: Path{0}, To: Path{1}}},
+			Expected: outline([]string{"b"}, []string{"a"}, []string{"c"}),
+		},
+		{
+			Name:     "concurrent moves that would form a cycle",
+			Existing: outline([]string{"a"}, []string{"b"}),
+			A:        cooperate.Operation{MoveAction{From: Path{0}, To: Path{0, 0}}},
+			B:        cooperate.Operation{MoveAction{From: Path{1}, To: Path{0, 0}}},
+			Expected: outline([]string{"a", "b"}),
+		},
+		{
+			Name:     "concurrent moves that would form a cycle, b reversed",
+			Existing: outline([]string{"a"}, []string{"b"}),
+			A:        cooperate.Operation{MoveAction{From: Path{1}, To: Path{0, 0}}},
+			B:        cooperate.Operation{MoveAction{From: Path{0}, To: Path{0, 0}}},
+			Expected: outline([]string{"b", "a"}),
+		},
+		{
+			Name:     "move against a move of a node to where it already is",
+			Existing: outline([]string{"a"}, []string{"b"}, []string{"c"}),
+			A:        cooperate.Operation{MoveAction{From: Path{2}, To: Path{1}}},
+			B:        cooperate.Operation{MoveAction{From: Path{1}, To: Path{1}}},
+			Expected: outline([]string{"a"}, []string{"c"}, []string{"b"}),
+		},
+		{
+			Name:     "concurrent sets of the same attribute",
+			Existing: outline([]string{"a"}),
+			A:        cooperate.Operation{SetAttributeAction{Path: Path{0}, Key: "k", Value: "a"}},
+			B:        cooperate.Operation{SetAttributeAction{Path: Path{0}, Key: "k", Value: "b"}},
+			Expected: &Node{Children: []*Node{{Text: "a", Attributes: map[string]string{"k": "b"}}}},
+		},
+	}
+
+	var th TreeHandler
+
+	for _, c := range cases {
+
+		aPrime, bPrime, err := th.Transform(cooperate.NewOperationIterator(c.A), cooperate.NewOperationIterator(c.B))
+		if err != nil {
+			t.Errorf("[%s] unexpected error: %s", c.Name, err)
+			continue
+		}
+
+		// both orders of application must converge
+		ab, ba := NewTreeDocument(c.Existing), NewTreeDocument(c.Existing)
+		for _, step := range []struct {
+			Doc *TreeDocument
+			Op  cooperate.Operation
+		}{{ab, c.A}, {ab, bPrime}, {ba, c.B}, {ba, aPrime}} {
+			if err := step.Doc.Apply(step.Op); err != nil {
+				t.Errorf("[%s] apply error: %s", c.Name, err)
+			}
+		}
+		if !reflect.DeepEqual(ab.Root(), c.Expected) || !reflect.DeepEqual(ba.Root(), c.Expected) {
+			t.Errorf("[%s] documents diverged: expected '%s' but got '%s' and '%s'", c.Name, format(c.Expected), format(ab.Root()), format(ba.Root()))
+		}
+	}
+
+}
+
+// format renders n compactly for test failure messages.
+func format(n *Node) string {
+	s := n.Text
+	if len(n.Attributes) > 0 {
+		s += "{" + func() string {
+			var attrs string
+			for k, v := range n.Attributes {
+				attrs += k + "=" + v
+			}
+			return attrs
+		}() + "}"
+	}
+	if len(n.Children) > 0 {
+		s += "("
+		for i, c := range n.Children {
+			if i > 0 {
+				s += " "
+			}
+			s += format(c)
+		}
+		s += ")"
+	}
+	return s
+}
+
+var treeConfig = ottest.Config[cooperate.Action]{
+	NewDocument: func(r *rand.Rand) cooperate.Document {
+		root := &Node{}
+		for i := r.Intn(3); i > 0; i-- {
+			n := &Node{Text: randomText(r)}
+			for j := r.Intn(3); j > 0; j-- {
+				n.Children = append(n.Children, &Node{Text: randomText(r)})
+			}
+			root.Children = append(root.Children, n)
+		}
+		return NewTreeDocument(root)
+	},
+	Handler: func(p cooperate.Priority) ottest.Handler[cooperate.Action] {
+		return TreeHandler{Priority: p}
+	},
+	RandomOperation: func(r *rand.Rand, doc cooperate.Document) cooperate.Operation {
+		td := NewTreeDocument(doc.(*TreeDocument).Root())
+		var op cooperate.Operation
+		for n := 1 + r.Intn(3); len(op) < n; {
+			if a := randomAction(r, td.Root()); a != nil {
+				if err := td.Apply(cooperate.Operation{a}); err != nil {
+					panic(err)
+				}
+				op = append(op, a)
+			}
+		}
+		return op
+	},
+	Equal: func(a, b cooperate.Document) bool {
+		return reflect.DeepEqual(a.(*TreeDocument).Root().Clone(), b.(*TreeDocument).Root().Clone())
+	},
+}
+
+// randomAction returns a random action that applies to the tree at root, or
+// nil.
+func randomAction(r *rand.Rand, root *Node) cooperate.Action {
+
+	nodes := paths(root, nil)
+	p := nodes[r.Intn(len(nodes))]
+	n := find(root, p)
+
+	switch r.Intn(5) {
+	case 0:
+		return InsertAction{Path: append(p, r.Intn(len(n.Children)+1)), Node: &Node{Text: randomText(r)}}
+	case 1:
+		if len(p) > 0 {
+			if r.Intn(2) == 0 {
+				return DeleteAction{Path: p}
+			}
+			return DeleteAction{Path: p, Node: n.Clone()}
+		}
+	case 2:
+		if len(p) > 0 {
+			// pick a destination in the tree as it is without the node
+			rest := root.Clone()
+			remove(rest, p, nil)
+			parents := paths(rest, nil)
+			to := parents[r.Intn(len(parents))]
+			to = append(to, r.Intn(len(find(rest, to).Children)+1))
+			return MoveAction{From: p, To: to}
+		}
+	case 3:
+		if len(p) > 0 {
+			i, j := r.Intn(len(n.Text)+1), r.Intn(len(n.Text)+1)
+			if i > j {
+				i, j = j, i
+			}
+			op := cooperate.Operation{text.RetainAction(i)}
+			if i == j {
+				op = append(op, text.InsertAction(randomText(r)))
+			} else {
+				op = append(op, text.DeleteAction(n.Text[i:j]))
+			}
+			return EditTextAction{Path: p, Op: append(op, text.RetainAction(len(n.Text)-j))}
+		}
+	case 4:
+		if len(p) > 0 {
+			return SetAttributeAction{Path: p, Key: []string{"x", "y"}[r.Intn(2)], Value: []string{"", "1", "2"}[r.Intn(3)]}
+		}
+	}
+	return nil
+
+}
+
+// paths returns the paths of n, at p, and of all its descendants.
+func paths(n *Node, p Path) []Path {
+	all := []Path{p}
+	for i, c := range n.Children {
+		all = append(all, paths(c, append(clonePath(p), i))...)
+	}
+	return all
+}
+
+// randomText returns up to three copies of a random letter.
+func randomText(r *rand.Rand) string {
+	return strings.Repeat(string(rune('a'+r.Intn(26))), 1+r.Intn(3))
+}
+
+func TestTransform_Random(t *testing.T) {
+	ottest.Transform(t, treeConfig, 2000)
+}
+
+func TestClient_Random(t *testing.T) {
+	ottest.Converge(t, treeConfig, 200)
+}